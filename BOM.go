@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/olekukonko/tablewriter"
+)
+
+//**************************************************************************************************
+//**************************************************************************************************
+// Bill of materials
+//
+// For a survival world the blocks of a build have to be gathered first. With BOMReport the
+// blocks placed by every function are counted and listed at the end of the run, as stacks
+// and shulker boxes full, e.g.
+//    Block                        Count   Stacks       Shulker Boxes
+//    minecraft:polished_diorite   1000    15 x 64 + 40 0.58
+// The functions are run into a block model, so a block that is filled over by a later command
+// of the same function is only counted once, for the block that is left there. Air is not
+// counted and the _rm functions are not listed.
+//
+// Some blocks are placed from another item, water and lava from buckets for example, and are
+// listed by that item. Items that do not stack to 64 are counted in their own stack size.
+// Doors, beds and tall plants are two blocks from one item, they are counted once, by their
+// lower half or foot.
+// With BOMFormats the lists are also written to ExportDir/<generator>/<function>.bom.csv and
+// .bom.json for a spreadsheet or another tool.
+//**************************************************************************************************
+//**************************************************************************************************
+
+// Structure for using TOML to extract input from the user.
+//    BOMReport    List the blocks placed by every function at the end of the run
+//    BOMFormats   Files to write the lists to, "csv" and "json", in ExportDir
+type mcfdBOMInputStruct struct {
+	BOMReport  bool     `toml:"BOMReport"`
+	BOMFormats []string `toml:"BOMFormats"`
+}
+
+// Options for the bill of materials, read from the user input file by ReadBOMOptions.
+var bomOptions mcfdBOMInputStruct
+
+// The bills of materials of the functions written in this run, in the order written.
+var bomReports []*bomReport
+
+// Items per shulker box, in stacks
+const shulkerBoxStacks = 27
+
+// Blocks that are placed from an item with another name.
+var bomItems = map[string]string{
+	"minecraft:water":               "minecraft:water_bucket",
+	"minecraft:lava":                "minecraft:lava_bucket",
+	"minecraft:wall_torch":          "minecraft:torch",
+	"minecraft:soul_wall_torch":     "minecraft:soul_torch",
+	"minecraft:redstone_wire":       "minecraft:redstone",
+	"minecraft:redstone_wall_torch": "minecraft:redstone_torch",
+	"minecraft:fire":                "minecraft:flint_and_steel",
+	"minecraft:kelp_plant":          "minecraft:kelp",
+	"minecraft:tall_seagrass":       "minecraft:seagrass",
+}
+
+// Stack sizes of the items that do not stack to 64, by the end of their name. The longest end
+// of a name that matches is used, see bomStackSize.
+var bomStackSizes = []struct {
+	suffix string
+	size   int
+}{
+	{"_bucket", 1},
+	{"flint_and_steel", 1},
+	{"_bed", 1},
+	{"shulker_box", 1},
+	{"cake", 1},
+	{"_sign", 16},
+	{"_banner", 16},
+}
+
+// bomReport is the bill of materials of one function.
+type bomReport struct {
+	id    string
+	items []bomItem
+}
+
+// bomItem is one line of a bill of materials. Stacks are full stacks of StackSize, Remainder
+// is the items left over.
+type bomItem struct {
+	Block        string  `json:"block"`
+	Item         string  `json:"item"`
+	Count        int     `json:"count"`
+	StackSize    int     `json:"stack_size"`
+	Stacks       int     `json:"stacks"`
+	Remainder    int     `json:"remainder"`
+	ShulkerBoxes float64 `json:"shulker_boxes"`
+}
+
+// ReadBOMOptions reads the options for the bill of materials from the user input file.
+func ReadBOMOptions(inputFile string) error {
+	var mcfdInput mcfdBOMInputStruct
+	if _, err := toml.DecodeFile(inputFile, &mcfdInput); err != nil {
+		return err
+	}
+	for _, format := range mcfdInput.BOMFormats {
+		if format != "csv" && format != "json" {
+			return fmt.Errorf("BOMFormats: unknown format %q", format)
+		}
+	}
+	bomOptions = mcfdInput
+	return nil
+}
+
+// BOMFunction counts the blocks placed by the commands of a function and writes them in the
+// BOMFormats. The list is shown at the end of the run by CreateBOMDriver.
+func BOMFunction(f *mcfdFunctionFile) error {
+	dir := f.dir
+	base := strings.TrimSuffix(f.filename, ".mcfunction")
+	if !bomOptions.BOMReport || strings.HasSuffix(base, "_rm") {
+		return nil
+	}
+	m, err := f.model()
+	if err != nil {
+		return fmt.Errorf("bill of materials %v", err)
+	} else if m == nil {
+		return nil
+	}
+	r := &bomReport{id: FunctionID(f.basepath, dir, f.filename), items: BOMItems(m.Counts())}
+	bomReports = append(bomReports, r)
+
+	for _, format := range bomOptions.BOMFormats {
+		f, err := createExportFile(dir, base+".bom."+format)
+		if err != nil {
+			return fmt.Errorf("bill of materials: %v", err)
+		}
+		switch format {
+		case "csv":
+			err = r.writeCSV(f)
+		case "json":
+			enc := json.NewEncoder(f)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(struct {
+				Function string    `json:"function"`
+				Items    []bomItem `json:"items"`
+			}{r.id, r.items})
+		}
+		if err != nil {
+			return fmt.Errorf("bill of materials write %v: %v", f.Name(), err)
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BOMItems turns block counts, see mcshapes.Model.Counts, into the items to gather, most
+// first.
+func BOMItems(counts map[string]int) []bomItem {
+	totals := make(map[string]int)
+	blocks := make(map[string][]string)
+	for block, n := range counts {
+		item := block
+		if i, ok := bomItems[block]; ok {
+			item = i
+		}
+		totals[item] += n
+		blocks[item] = append(blocks[item], block)
+	}
+
+	items := make([]bomItem, 0, len(totals))
+	for item, n := range totals {
+		size := bomStackSize(item)
+		sort.Strings(blocks[item])
+		items = append(items, bomItem{
+			Block:        strings.Join(blocks[item], " "),
+			Item:         item,
+			Count:        n,
+			StackSize:    size,
+			Stacks:       n / size,
+			Remainder:    n % size,
+			ShulkerBoxes: float64(n) / float64(size*shulkerBoxStacks),
+		})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Item < items[j].Item
+	})
+	return items
+}
+
+// bomStackSize returns the stack size of an item, by the longest end of its name in
+// bomStackSizes, or 64.
+func bomStackSize(item string) int {
+	size, n := 64, 0
+	for _, s := range bomStackSizes {
+		if strings.HasSuffix(item, s.suffix) && len(s.suffix) > n {
+			size, n = s.size, len(s.suffix)
+		}
+	}
+	return size
+}
+
+// writeCSV writes a bill of materials as CSV with a header line.
+func (r *bomReport) writeCSV(f io.Writer) error {
+	w := csv.NewWriter(f)
+	w.Write([]string{"item", "block", "count", "stack_size", "stacks", "remainder",
+		"shulker_boxes"})
+	for _, it := range r.items {
+		w.Write([]string{it.Item, it.Block, fmt.Sprint(it.Count), fmt.Sprint(it.StackSize),
+			fmt.Sprint(it.Stacks), fmt.Sprint(it.Remainder),
+			fmt.Sprintf("%.2f", it.ShulkerBoxes)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// stacks returns the count of an item as full stacks and the rest, e.g. "15 x 64 + 40"
+func (it bomItem) stacks() string {
+	switch {
+	case it.Stacks == 0:
+		return fmt.Sprintf("%d", it.Remainder)
+	case it.Remainder == 0:
+		return fmt.Sprintf("%d x %d", it.Stacks, it.StackSize)
+	}
+	return fmt.Sprintf("%d x %d + %d", it.Stacks, it.StackSize, it.Remainder)
+}
+
+// CreateBOMDriver
+// Driver for showing the bills of materials of the functions written by the other drivers,
+// so this must be run after them.
+func CreateBOMDriver() {
+	if len(bomReports) == 0 {
+		return
+	}
+
+	fmt.Println("\nBill of Materials")
+	for _, r := range bomReports {
+		fmt.Printf("\n%v\n", r.id)
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Item", "Count", "Stacks", "Shulker Boxes"})
+		total, boxes := 0, 0.0
+		for _, it := range r.items {
+			table.Append([]string{it.Item, fmt.Sprintf("%d", it.Count), it.stacks(),
+				fmt.Sprintf("%.2f", it.ShulkerBoxes)})
+			total += it.Count
+			boxes += it.ShulkerBoxes
+		}
+		table.SetFooter([]string{"Total", fmt.Sprintf("%d", total), "-",
+			fmt.Sprintf("%.2f", boxes)})
+		table.Render()
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	mcview "github.com/GreenSeaTurtle/mcFunctionDev/mcView"
+)
+
+//**************************************************************************************************
+//**************************************************************************************************
+// Blueprints
+//
+//    mcFunctionDev blueprint [-out <dir>] [-cell <pixels>] [-views <views>] <function file> ...
+//
+// Builds are planned in shared documents, and a drawing of a wall or a walkway with the
+// player start marked is more use there than an STL file. A blueprint is an SVG drawing of
+// the blocks of a function from one side, the plan from above, the elevations from the
+// north, south, east and west, or a section through the blocks at one relative x, y or z.
+// See mcview.Blueprint. For each function file and view this is written,
+//    <out>/<function>/<function>_<view>.svg
+// e.g. blueprints/mw_ENS_15_10/mw_ENS_15_10_south.svg and, for the section y=0,
+// blueprints/mw_ENS_15_10/mw_ENS_15_10_y0.svg. The blocks are in the default preview colors.
+// Any function can be drawn, the blocks are read the same as for diff, see ReadFunctionModel.
+//**************************************************************************************************
+//**************************************************************************************************
+
+// BlueprintCommand runs the blueprint command with the arguments after "blueprint".
+func BlueprintCommand(args []string) error {
+	flags := flag.NewFlagSet("blueprint", flag.ContinueOnError)
+	out := flags.String("out", "blueprints", "directory for the drawings")
+	cell := flags.Int("cell", 16, "size of one block in pixels")
+	views := flags.String("views", "plan,south,east",
+		"views to draw, "+strings.Join(mcview.BlueprintViews, ",")+" and sections x=N,y=N,z=N")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(),
+			"usage: mcFunctionDev blueprint [-out <dir>] [-cell <pixels>] [-views <views>] <function file> ...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("blueprint needs function files")
+	}
+	if *cell < 4 {
+		return fmt.Errorf("blueprint: -cell must be at least 4 pixels")
+	}
+
+	for _, fname := range flags.Args() {
+		m, err := ReadFunctionModel(fname)
+		if err != nil {
+			return err
+		}
+		base := strings.TrimSuffix(path.Base(fname), ".mcfunction")
+		dir := path.Join(*out, base)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("blueprint mkdir %v: %v", dir, err)
+		}
+		b := mcview.NewBlueprint(m, mcview.WithBlueprintCellSize(*cell),
+			mcview.WithBlueprintPalette(previewPalette))
+		for _, view := range strings.Split(*views, ",") {
+			view = strings.TrimSpace(view)
+			suffix := strings.NewReplacer("=", "", "~", "").Replace(view)
+			if err := writeSVG(path.Join(dir, base+"_"+suffix+".svg"), b, base, view); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("%v: %v\n", fname, dir)
+	}
+	return nil
+}
+
+// writeSVG writes one view of a blueprint to the SVG file fname, see writeFile
+func writeSVG(fname string, b *mcview.Blueprint, name string, view string) error {
+	var buf bytes.Buffer
+	if err := b.WriteSVG(&buf, name, view); err != nil {
+		return fmt.Errorf("blueprint %v: %v", name, err)
+	}
+	return writeFile(fname, buf.Bytes())
+}
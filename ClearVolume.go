@@ -3,7 +3,7 @@ package main
 import (
 	//"bytes"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	//"strings"
 
@@ -21,50 +21,97 @@ import (
 //
 // Clears width*depth*height in front of the player
 // This capability can be used to create a rectangular volume of whatever is desired.
+//
+// The volumes are in ClearVol tables, see clearVolEntry, or in the old input form with one
+// array for each key, ClearVolHeight, ...
 type mcfdClearVolInputStruct struct {
-	ClearVolHeight     []int    `toml:"ClearVolHeight"`
-	ClearVolWidth      []int    `toml:"ClearVolWidth"`
-	ClearVolDepth      []int    `toml:"ClearVolDepth"`
-	ClearVolBlockType  []string `toml:"ClearVolBlockType"`
+	ClearVol           []clearVolEntry `toml:"clearvol"`
+	ClearVolHeight     []int           `toml:"ClearVolHeight"`
+	ClearVolWidth      []int           `toml:"ClearVolWidth"`
+	ClearVolDepth      []int           `toml:"ClearVolDepth"`
+	ClearVolBlockType  []string        `toml:"ClearVolBlockType"`
+}
+
+// clearVolEntry is one volume in the input file, see Input.go. The keys are the same as
+// above, with the same defaults,
+//    name     Name of the functions instead of the sizes and block, optional
+//    width, depth, height, block
+type clearVolEntry struct {
+	Name   string `toml:"name"`
+	Width  int    `toml:"width"`
+	Depth  int    `toml:"depth"`
+	Height int    `toml:"height"`
+	Block  string `toml:"block"`
+}
+
+// entries returns the volumes in the input, the old arrays first, with the defaults set.
+func (in mcfdClearVolInputStruct) entries() ([]clearVolEntry, error) {
+	n, err := parallelArrays("clearvol",
+		[]string{"ClearVolHeight", "ClearVolWidth", "ClearVolDepth", "ClearVolBlockType"},
+		[]int{len(in.ClearVolHeight), len(in.ClearVolWidth), len(in.ClearVolDepth),
+			len(in.ClearVolBlockType)})
+	if err != nil {
+		return nil, err
+	}
+	var entries []clearVolEntry
+	for i := 0; i < n; i++ {
+		entries = append(entries, clearVolEntry{Height: in.ClearVolHeight[i],
+			Width: in.ClearVolWidth[i], Depth: in.ClearVolDepth[i],
+			Block: in.ClearVolBlockType[i]})
+	}
+	for _, e := range in.ClearVol {
+		defaultInt(&e.Height, 100)
+		defaultInt(&e.Width, 50)
+		defaultInt(&e.Depth, 50)
+		defaultString(&e.Block, "air")
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// checkClearVolInput checks the volumes in the input file, see ValidateInput.
+func checkClearVolInput(c *inputCheck) {
+	var in mcfdClearVolInputStruct
+	if !c.decode(&in) {
+		return
+	}
+	volumes, err := in.entries()
+	if err != nil {
+		c.add(topKey("ClearVolHeight"), err.Error(), "give every array the same number of values")
+		return
+	}
+	keys := entryKeys{"clearvol", len(in.ClearVolHeight), map[string]string{
+		"height": "ClearVolHeight", "width": "ClearVolWidth", "depth": "ClearVolDepth",
+		"block": "ClearVolBlockType"}}
+	for i, e := range volumes {
+		c.name("clearvol", keys.key(i, "name"), e.Name)
+		c.atLeast(keys.key(i, "width"), "width", e.Width, 1, "e.g. width = 50")
+		c.atLeast(keys.key(i, "depth"), "depth", e.Depth, 1, "e.g. depth = 50")
+		c.atLeast(keys.key(i, "height"), "height", e.Height, 1, "e.g. height = 100")
+		c.block(keys.key(i, "block"), e.Block, false)
+	}
 }
 
 // CreateClearVolDriver
 // Driver for creating the Minecraft function files for clearing volumes
-func CreateClearVolDriver(inputFile string, basepath string) {
+func CreateClearVolDriver(inputFile string, basepath string) error {
 	// Extract pertinent input, using TOML, from the user input file
 	var mcfdInput mcfdClearVolInputStruct
 	if _, err := toml.DecodeFile(inputFile, &mcfdInput); err != nil {
-		fmt.Println(err)
-		return
+		return fmt.Errorf("CreateClearVol: %v", err)
 	}
 
 	// Consistency check on the user input
-	dim := [4]int{0, 0, 0, 0}
-	dim[0] = len(mcfdInput.ClearVolHeight)
-	dim[1] = len(mcfdInput.ClearVolWidth)
-	dim[2] = len(mcfdInput.ClearVolDepth)
-	dim[3] = len(mcfdInput.ClearVolBlockType)
-	maxdim := dim[0]
-	mindim := dim[0]
-	for _, v := range dim {
-		if v < mindim {
-			mindim = v
-		}
-		if v > maxdim {
-			maxdim = v
-		}
-	}
-	if maxdim != mindim {
-		fmt.Println("CreateClearVol user input FATAL ERROR")
-		fmt.Println("You must specify the same number of array values for all the")
-		fmt.Println("CreateClearVol arrays - ClearVolHeight, ClearVolBlockType, ...")
-		return
+	volumes, err := mcfdInput.entries()
+	if err != nil {
+		return fmt.Errorf("CreateClearVol user input: %v", err)
 	}
+	maxdim := len(volumes)
 
 	// If the user has not specified anything then there is nothing left
 	// to do.
 	if maxdim <= 0 {
-		return
+		return nil
 	}
 
 	// Create the clear volumes requested by the user in the user input file.
@@ -84,22 +131,25 @@ func CreateClearVolDriver(inputFile string, basepath string) {
 			dname := directionNames[j]
 			k := j + i*ndirvals
 			// Minecraft functions must have a suffix of ".mcfunction"
-			height := mcfdInput.ClearVolHeight[i]
-			height_str := fmt.Sprintf("%d", mcfdInput.ClearVolHeight[i])
+			height := volumes[i].Height
+			height_str := fmt.Sprintf("%d", volumes[i].Height)
 			sheight := ""
 			if height != 100 {
 				sheight = "_" + height_str
 			}
-			width_str := fmt.Sprintf("%d", mcfdInput.ClearVolWidth[i])
+			width_str := fmt.Sprintf("%d", volumes[i].Width)
 			swidth := "_" + width_str
-			depth_str  := fmt.Sprintf("%d", mcfdInput.ClearVolDepth[i])
+			depth_str  := fmt.Sprintf("%d", volumes[i].Depth)
 			sdepth := "_" + depth_str
-			bname := mcfdInput.ClearVolBlockType[i]
+			bname := volumes[i].Block
 			sbname := ""
 			if bname != "air" {
 				sbname = "_" + bname
 			}
 			filename[k] = "cv_" + dname + swidth + sdepth + sheight + sbname + ".mcfunction"
+			if volumes[i].Name != "" {
+				filename[k] = "cv_" + dname + "_" + volumes[i].Name + ".mcfunction"
+			}
 			table.Append([]string{filename[k], width_str, depth_str, height_str, bname})
 		}
 	}
@@ -111,15 +161,16 @@ func CreateClearVolDriver(inputFile string, basepath string) {
 			direction := directionValues[j]
 			k := j + i*ndirvals
 			err := CreateClearVol(basepath, filename[k], direction,
-				mcfdInput.ClearVolHeight[i],
-				mcfdInput.ClearVolWidth[i],
-				mcfdInput.ClearVolDepth[i],
-				mcfdInput.ClearVolBlockType[i])
+				volumes[i].Height,
+				volumes[i].Width,
+				volumes[i].Depth,
+				volumes[i].Block)
 			if err != nil {
-				log.Fatalln(err)
+				return err
 			}
 		}
 	}
+	return nil
 }
 
 // CreateClearVol
@@ -128,11 +179,10 @@ func CreateClearVol(basepath string, filename string, direction string,
 	height int, width int, depth int, btype string) error {
 
 	fname := basepath + "/ClearVol/" + filename
-	f, err := os.OpenFile(fname, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
+	f, err := CreateFunctionFile(basepath, "ClearVol", filename)
 	if err != nil {
 		return fmt.Errorf("CreateClearVol open %v: %v", fname, err)
 	}
-	defer f.Close()
 
 	// When facing north, the depth is the negative Z coordinate and the width is positive X
 	// height is the Y coordinate
@@ -146,15 +196,18 @@ func CreateClearVol(basepath string, filename string, direction string,
 	z2 := z1 - depth + 1
 
 	for y := 0; y < height; y++ {
-		WriteClearVolBox(x1, y, z1, x2, y, z2, btype, direction, f)
+		err = WriteClearVolBox(x1, y, z1, x2, y, z2, btype, direction, f)
+		if err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return f.Close()
 }
 
 // WriteClearVolBox writes out a low level box for the wall.
 func WriteClearVolBox(x1 int, y1 int, z1 int, x2 int, y2 int, z2 int,
-	block_type string, direction string, f *os.File) error {
+	block_type string, direction string, f io.Writer) error {
 
 	corner1 := mcshapes.XYZ{X: x1, Y: y1, Z: z1}
 	corner2 := mcshapes.XYZ{X: x2, Y: y2, Z: z2}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+	"github.com/olekukonko/tablewriter"
+)
+
+//**************************************************************************************************
+//**************************************************************************************************
+// Running the generators
+//
+//    mcFunctionDev build    [flags] [generator ...]
+//    mcFunctionDev list     [flags] [generator ...]
+//    mcFunctionDev validate [flags] [generator ...]
+//    mcFunctionDev clean    [flags] [-n] [generator ...]
+//
+// build writes the functions of the generators into the world, list prints the functions
+// the generators make, validate checks the input file and the functions it makes, and clean
+// removes the functions of the generators from the world. Without a command mcFunctionDev
+// builds, the same as always. The flags are the same for all four commands,
+//    -input   the input file, default all.input
+//    -output  the functions directory to write to, instead of the one in the init file
+//    -init    the init file, default the first one found, see FindInitFile
+//    -profile the world in the init file, see ReadInitFile
+//    -target  the Minecraft version to check the functions for, see LintCommand
+//    -only    the generators to run, e.g. -only falls,sphere. The names can also be given
+//             after the flags. The default is all generators.
+// list and validate run the generators into a temporary directory, so nothing in the world
+// changes and the init file is not needed, -init and -profile are not used. build only
+// checks the functions when -target is given, validate checks them for the FunctionVersion of
+// the input file by default.
+// All of them check the input file first, see ValidateInput, and stop when it has problems.
+//**************************************************************************************************
+//**************************************************************************************************
+
+// generator is one of the generators, name is the name it is selected by and dir is the
+// directory in the functions directory its functions are written to. check checks its
+// entries in the input file before anything is run, see ValidateInput.
+type generator struct {
+	name  string
+	dir   string
+	run   func(inputFile string, basepath string) error
+	check func(c *inputCheck)
+}
+
+// The generators in the order they are run.
+var generators = []generator{
+	{"falls", "Falls", BuildFalls, checkFallsInput},
+	{"clearvol", "ClearVol", CreateClearVolDriver, checkClearVolInput},
+	{"mwall", "MWall", CreateMWallDriver, checkMWallInput},
+	{"sign7", "Sign7", CreateSign7Driver, checkSign7Input},
+	{"sphere", "Sphere", CreateSphereDriver, checkSphereInput},
+	{"walkway", "Walkway", CreateWalkwayDriver, checkWalkwayInput},
+	{"import", "Import", CreateImportDriver, checkImportInput},
+}
+
+// selectGenerators returns the generators named in the comma separated lists in names, all
+// of them if there are none. Names are the generator names or directories in any case.
+func selectGenerators(names ...string) ([]generator, error) {
+	want := make(map[string]bool)
+	for _, list := range names {
+		for _, name := range strings.Split(list, ",") {
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				want[name] = true
+			}
+		}
+	}
+	if len(want) == 0 {
+		return generators, nil
+	}
+	var selected []generator
+	for _, g := range generators {
+		if want[g.name] {
+			selected = append(selected, g)
+			delete(want, g.name)
+		}
+	}
+	if len(want) > 0 {
+		var unknown, known []string
+		for name := range want {
+			unknown = append(unknown, name)
+		}
+		for _, g := range generators {
+			known = append(known, g.name)
+		}
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown generator %v, the generators are %v",
+			strings.Join(unknown, ", "), strings.Join(known, ", "))
+	}
+	return selected, nil
+}
+
+// cliOptions are the flags of the commands that run the generators.
+type cliOptions struct {
+	input   string
+	output  string
+	init    string
+	profile string
+	target  string
+	only    string
+}
+
+// flagSet returns the flags of the command cmd, target is the default target version.
+func (o *cliOptions) flagSet(cmd string, target string, usage string) *flag.FlagSet {
+	flags := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	flags.StringVar(&o.input, "input", "all.input", "input file")
+	flags.StringVar(&o.output, "output", "", "functions directory, instead of the one in the init file")
+	flags.StringVar(&o.init, "init", "", "init file (default the first one found, see mcFunctionDev init)")
+	flags.StringVar(&o.profile, "profile", "", "world profile in the init file")
+	flags.StringVar(&o.target, "target", target, "Minecraft version to check the functions for")
+	flags.StringVar(&o.only, "only", "", "generators to run, e.g. falls,sphere (default all)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mcFunctionDev "+cmd+" "+usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parse parses the arguments of the command and returns the selected generators.
+func (o *cliOptions) parse(flags *flag.FlagSet, args []string) ([]generator, error) {
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	return selectGenerators(append([]string{o.only}, flags.Args()...)...)
+}
+
+// functionsDir reads the init file and returns the functions directory to write to.
+//
+// The init file sets things that do not change often, the input file controls what the
+// generators make. Right now the init file has the path to the Minecraft functions
+// directory on this system, so the function files are written directly to the game directory
+// which saves time and hassle of copying files. The path is split into two strings just
+// because it is typically a long path. See FindInitFile for where the init file is. With
+// -output the init file is not needed, but its namespace is used when there is one.
+func (o *cliOptions) functionsDir() (string, error) {
+	fname, _, err := FindInitFile(o.init)
+	if err != nil {
+		if o.output != "" && errors.Is(err, errNoInitFile) {
+			mcNamespace = ""
+			return o.output, nil
+		}
+		return "", err
+	}
+	mcwpath, err := ReadInitFile(fname, o.profile)
+	if err != nil {
+		return "", err
+	}
+	mcNamespace = mcwpath.MCNamespace
+	if o.output != "" {
+		return o.output, nil
+	}
+
+	// A wrong path in the init file should not make directories somewhere
+	basepath := path.Join(mcwpath.MCSavesDir, mcwpath.MCFunctionsDir)
+	if info, err := os.Stat(basepath); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("init file %v: the functions directory %v is not there, "+
+			"check mc_saves_dir and mc_world_functions_dir", fname, basepath)
+	}
+	return basepath, nil
+}
+
+// BuildCommand runs the build command with the arguments after "build".
+func BuildCommand(args []string) error {
+	var o cliOptions
+	flags := o.flagSet("build", "", "[flags] [generator ...]")
+	selected, err := o.parse(flags, args)
+	if err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	basepath, err := o.functionsDir()
+	if err != nil {
+		return err
+	}
+	if err := runDrivers(o.input, basepath, selected); err != nil {
+		return err
+	}
+	if o.target == "" {
+		return nil
+	}
+	return lintGenerators(basepath, selected, o.target)
+}
+
+// ListCommand runs the list command with the arguments after "list".
+func ListCommand(args []string) error {
+	var o cliOptions
+	flags := o.flagSet("list", "", "[flags] [generator ...]")
+	selected, err := o.parse(flags, args)
+	if err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	_, cleanup, err := generateFunctions(o.input, selected)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Generator", "Function"})
+	n := 0
+	for _, g := range selected {
+		for _, id := range generatedFunctions[strings.ToLower(g.dir)] {
+			table.Append([]string{g.name, id})
+			n++
+		}
+	}
+	table.Render()
+	fmt.Printf("%d functions\n", n)
+	return nil
+}
+
+// ValidateCommand runs the validate command with the arguments after "validate".
+func ValidateCommand(args []string) error {
+	var o cliOptions
+	flags := o.flagSet("validate", "", "[flags] [generator ...]")
+	selected, err := o.parse(flags, args)
+	if err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if o.target != "" {
+		if _, err := mcshapes.ParseVersion(o.target); err != nil {
+			return err
+		}
+	}
+	basepath, cleanup, err := generateFunctions(o.input, selected)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if o.target == "" {
+		o.target = functionOptions.FunctionVersion
+	}
+	if err := lintGenerators(basepath, selected, o.target); err != nil {
+		return err
+	}
+	fmt.Printf("%v is valid for Minecraft %v\n", o.input, o.target)
+	return nil
+}
+
+// lintGenerators checks the functions of the selected generators in basepath for the target
+// version, printing the problems.
+func lintGenerators(basepath string, selected []generator, target string) error {
+	version, err := mcshapes.ParseVersion(target)
+	if err != nil {
+		return err
+	}
+	nfiles, nbad, nproblems := 0, 0, 0
+	for _, g := range selected {
+		n, bad, problems, err := LintPath(path.Join(basepath, resourceName(g.dir)), version)
+		if err != nil {
+			return err
+		}
+		nfiles, nbad, nproblems = nfiles+n, nbad+bad, nproblems+problems
+	}
+	if nproblems > 0 {
+		return fmt.Errorf("lint: %d problems in %d of %d functions for Minecraft %v",
+			nproblems, nbad, nfiles, target)
+	}
+	return nil
+}
+
+// CleanCommand runs the clean command with the arguments after "clean".
+func CleanCommand(args []string) error {
+	var o cliOptions
+	flags := o.flagSet("clean", "", "[flags] [-n] [generator ...]")
+	dryRun := flags.Bool("n", false, "only print what would be removed")
+	selected, err := o.parse(flags, args)
+	if err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	basepath, err := o.functionsDir()
+	if err != nil {
+		return err
+	}
+
+	// The functions of a generator are all in its directory, the sub-functions and undo
+	// functions too. Its tag is removed with them. The directory is lower case for Minecraft
+	// 1.13 and later, see resourceName, both are cleaned.
+	tagdir := path.Join(path.Dir(basepath), "tags", path.Base(basepath))
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Generator", "Directory", "Files"})
+	for _, g := range selected {
+		files, err := filepath.Glob(path.Join(basepath, g.dir, "*.mcfunction"))
+		if err != nil {
+			return err
+		}
+		if lower := strings.ToLower(g.dir); lower != g.dir {
+			more, err := filepath.Glob(path.Join(basepath, lower, "*.mcfunction"))
+			if err != nil {
+				return err
+			}
+			files = append(files, more...)
+		}
+		if tag := path.Join(tagdir, strings.ToLower(g.dir)+".json"); fileExists(tag) {
+			files = append(files, tag)
+		}
+		for _, fname := range files {
+			if *dryRun {
+				fmt.Println("rm " + fname)
+				continue
+			}
+			// On a file system that ignores case both globs find the same files
+			if err := os.Remove(fname); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		table.Append([]string{g.name, path.Join(basepath, g.dir), fmt.Sprintf("%d", len(files))})
+	}
+	if *dryRun {
+		fmt.Println("Nothing was removed, the following table summarizes what would be:")
+	} else {
+		fmt.Println("The following table summarizes the files removed:")
+	}
+	table.Render()
+	return nil
+}
+
+// fileExists reports whether fname is a file
+func fileExists(fname string) bool {
+	info, err := os.Stat(fname)
+	return err == nil && !info.IsDir()
+}
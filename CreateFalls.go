@@ -2,8 +2,8 @@ package main
 
 import (
 	"bytes"
-	"log"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"strings"
@@ -11,15 +11,75 @@ import (
 	"github.com/BurntSushi/toml"
 	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
 	"github.com/olekukonko/tablewriter"
-	"github.com/benmcclelland/mcrender"
 )
 
 
 // Structure for using TOML to extract input from the user.
+//    Falls                  The falls, see fallsEntry
+//    FallWidth, ...         The falls in the old input form, one array for each key
 type mcfdFallsInputStruct struct {
-	FallWidth            []int    `toml:"FallWidth"`
-	FallHeight           []int    `toml:"FallHeight"`
-	FallFlowBlock        []string `toml:"FallFlowBlock"`
+	Falls                []fallsEntry `toml:"falls"`
+	FallWidth            []int        `toml:"FallWidth"`
+	FallHeight           []int        `toml:"FallHeight"`
+	FallFlowBlock        []string     `toml:"FallFlowBlock"`
+}
+
+// fallsEntry is one falls in the input file, see Input.go.
+//    name     Name of the functions instead of the width and height, optional
+//    width    Width of the falls, default 10
+//    height   Height of the falls, default 7
+//    flow     "water" (default) or "lava"
+type fallsEntry struct {
+	Name   string `toml:"name"`
+	Width  int    `toml:"width"`
+	Height int    `toml:"height"`
+	Flow   string `toml:"flow"`
+}
+
+// entries returns the falls in the input, the old arrays first, with the defaults set.
+func (in mcfdFallsInputStruct) entries() ([]fallsEntry, error) {
+	n, err := parallelArrays("falls", []string{"FallWidth", "FallHeight", "FallFlowBlock"},
+		[]int{len(in.FallWidth), len(in.FallHeight), len(in.FallFlowBlock)})
+	if err != nil {
+		return nil, err
+	}
+	var entries []fallsEntry
+	for i := 0; i < n; i++ {
+		entries = append(entries, fallsEntry{Width: in.FallWidth[i], Height: in.FallHeight[i],
+			Flow: in.FallFlowBlock[i]})
+	}
+	for _, e := range in.Falls {
+		defaultInt(&e.Width, 10)
+		defaultInt(&e.Height, 7)
+		defaultString(&e.Flow, "water")
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// checkFallsInput checks the falls in the input file, see ValidateInput.
+func checkFallsInput(c *inputCheck) {
+	var in mcfdFallsInputStruct
+	if !c.decode(&in) {
+		return
+	}
+	falls, err := in.entries()
+	if err != nil {
+		c.add(topKey("FallWidth"), err.Error(), "give every array the same number of values")
+		return
+	}
+	keys := entryKeys{"falls", len(in.FallWidth),
+		map[string]string{"width": "FallWidth", "height": "FallHeight", "flow": "FallFlowBlock"}}
+	for i, e := range falls {
+		c.name("falls", keys.key(i, "name"), e.Name)
+		c.atLeast(keys.key(i, "width"), "width", e.Width, 3, "the falls need a wall on each side, "+
+			"e.g. width = 10")
+		c.atLeast(keys.key(i, "height"), "height", e.Height, 1, "e.g. height = 7")
+		if e.Flow != "water" && e.Flow != "lava" {
+			c.add(keys.key(i, "flow"), fmt.Sprintf("the flow is %q, it must be water or lava",
+				e.Flow), "flow = \"water\" or flow = \"lava\"")
+		}
+	}
 }
 
 //BuildWaterFalls builds n, s, e, w waterfalls
@@ -27,31 +87,15 @@ func BuildFalls(inputFile string, basepath string) error {
 	// Extract pertinent input, using TOML, from the user input file
 	var mcfdInput mcfdFallsInputStruct
 	if _, err := toml.DecodeFile(inputFile, &mcfdInput); err != nil {
-		fmt.Println(err)
-		return nil
+		return fmt.Errorf("BuildFalls: %v", err)
 	}
 
-	// Consistency check on the user input
-	dim := [3]int{0, 0, 0}
-	dim[0] = len(mcfdInput.FallWidth)
-	dim[1] = len(mcfdInput.FallHeight)
-	dim[2] = len(mcfdInput.FallFlowBlock)
-	maxdim := dim[0]
-	mindim := dim[0]
-	for _, v := range dim {
-		if v < mindim {
-			mindim = v
-		}
-		if v > maxdim {
-			maxdim = v
-		}
-	}
-	if maxdim != mindim {
-		fmt.Println("BuildFalls user input FATAL ERROR")
-		fmt.Println("You must specify the same number of array values for all the")
-		fmt.Println("BuildFalls arrays - FallWidth, FallHeight, ...")
-		return nil
+	// Consistency check on the user input, see checkFallsInput for the rest
+	falls, err := mcfdInput.entries()
+	if err != nil {
+		return fmt.Errorf("BuildFalls user input: %v", err)
 	}
+	maxdim := len(falls)
 
 	// Create the falls requested by the user in the user input file.
 	if maxdim > 0 {
@@ -73,21 +117,19 @@ func BuildFalls(inputFile string, basepath string) error {
 				dname := directionNames[j]
 				k := j + i*ndirvals
 				// Minecraft functions must have a suffix of ".mcfunction"
-				swidth := fmt.Sprintf("%d", mcfdInput.FallWidth[i])
-				sheight := fmt.Sprintf("%d", mcfdInput.FallHeight[i])
-				blkname := mcfdInput.FallFlowBlock[i] + "fall"
-				filename[k] = blkname + "_" + dname + "_" + swidth + "_" +
-					sheight + ".mcfunction"
-				filename_rm[k] = blkname + "_" + dname + "_" + swidth + "_" +
-					sheight + "_rm.mcfunction"
-				filename_cfw[k] = blkname + "_" + dname + "_" + swidth + "_" +
-					sheight + "_cfw.mcfunction"
-				table.Append([]string{filename[k], swidth, sheight,
-					mcfdInput.FallFlowBlock[i]})
-				table.Append([]string{filename_rm[k], swidth, sheight,
-					mcfdInput.FallFlowBlock[i]})
-				table.Append([]string{filename_cfw[k], swidth, sheight,
-					mcfdInput.FallFlowBlock[i]})
+				swidth := fmt.Sprintf("%d", falls[i].Width)
+				sheight := fmt.Sprintf("%d", falls[i].Height)
+				blkname := falls[i].Flow + "fall"
+				base := blkname + "_" + dname + "_" + swidth + "_" + sheight
+				if falls[i].Name != "" {
+					base = blkname + "_" + dname + "_" + falls[i].Name
+				}
+				filename[k] = base + ".mcfunction"
+				filename_rm[k] = base + "_rm.mcfunction"
+				filename_cfw[k] = base + "_cfw.mcfunction"
+				table.Append([]string{filename[k], swidth, sheight, falls[i].Flow})
+				table.Append([]string{filename_rm[k], swidth, sheight, falls[i].Flow})
+				table.Append([]string{filename_cfw[k], swidth, sheight, falls[i].Flow})
 			}
 		}
 		table.Render()
@@ -99,21 +141,23 @@ func BuildFalls(inputFile string, basepath string) error {
 				k := j + i*ndirvals
 				// Create the falls functions
 				fname := basepath + "/Falls/" + filename[k]
-				f, err := os.OpenFile(fname, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
+				f, err := CreateFunctionFile(basepath, "Falls", filename[k])
 				if err != nil {
 					return fmt.Errorf("open FallsBuild %v: %v", fname, err)
 				}
 
-				falltype := mcfdInput.FallFlowBlock[i] + "fall"   // lavafall or waterfall
+				falltype := falls[i].Flow + "fall"   // lavafall or waterfall
 				obj := mcshapes.NewMCObject(mcshapes.WithOrientation(direction),
-					mcshapes.WithType(falltype), mcshapes.WithWidth(mcfdInput.FallWidth[i]),
-					mcshapes.WithHeight(mcfdInput.FallHeight[i]))
+					mcshapes.WithType(falltype), mcshapes.WithWidth(falls[i].Width),
+					mcshapes.WithHeight(falls[i].Height))
 				wf := CreateWaterfall(origin, obj)
 				err = mcshapes.WriteShapes(f, wf)
 				if err != nil {
 					return fmt.Errorf("BuildFalls: %v", err)
 				}
-				f.Close()
+				if err = f.Close(); err != nil {
+					return err
+				}
 
 				var buf bytes.Buffer
 				if err = mcshapes.WriteShapes(&buf, wf); err != nil {
@@ -121,40 +165,44 @@ func BuildFalls(inputFile string, basepath string) error {
 				}
 
 				// Create an stl file so we can look at the falls before going into the game.
+				// The obj and gltf ExportFormats write colored meshes.
 				stlname := "stlFiles/" + strings.Replace(filename[k], "mcfunction", "stl", 1)
-				err = mcrender.CreateSTLFromInput(&buf, stlname)
+				err = writeSTL(stlname, &buf)
 				if err != nil {
 					return fmt.Errorf("CreateSphere render to stl file: %v", err)
 				}
 
 				// Clear out a buffer area for the falls
 				fname = basepath + "/Falls/" + filename_cfw[k]
-				f, err = os.OpenFile(fname, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
+				f, err = CreateFunctionFile(basepath, "Falls", filename_cfw[k])
 				if err != nil {
 					return fmt.Errorf("open falls ClearForWall %v: %v", fname, err)
 				}
-				ClearForWall(mcfdInput.FallWidth[i], direction, f)
-				f.Close()
+				if err = ClearForWall(falls[i].Width, direction, f); err != nil {
+					return err
+				}
+				if err = f.Close(); err != nil {
+					return err
+				}
 
 				// Remove falls
 				fname = basepath + "/Falls/" + filename_rm[k]
-				f, err = os.OpenFile(fname, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
+				f, err = CreateFunctionFile(basepath, "Falls", filename_rm[k])
 				if err != nil {
 					return fmt.Errorf("open rmFalls %v: %v", fname, err)
 				}
-				rmFalls(mcfdInput.FallWidth[i], mcfdInput.FallHeight[i], direction, f)
-				f.Close()
+				if err = rmFalls(falls[i].Width, falls[i].Height, direction, f); err != nil {
+					return err
+				}
+				if err = f.Close(); err != nil {
+					return err
+				}
 			}
 		}
 	}
 
 	// Still under development
-	err := BuildRollerCoasterFalls(basepath)
-	if err != nil {
-		log.Fatalln(err)
-	}
-
-	return nil
+	return BuildRollerCoasterFalls(basepath)
 }
 
 
@@ -315,7 +363,7 @@ func CreateFalls(origin mcshapes.XYZ, o *mcshapes.MCObject) []mcshapes.ObjectWri
 // The ~ refers to the players current position in the game.
 // Yes, a fall could be removed by hand inside the game, but this is very tedious, thus
 // the need for this function.
-func rmFalls(width int, height int, direction string, f *os.File) error {
+func rmFalls(width int, height int, direction string, f io.Writer) error {
 	origin := mcshapes.XYZ{X: 0, Y: 0, Z: -2}
 	// Use a loop because Minecraft has a limit on total number of blocks per fill command.
 	for h := 0; h <= height; h++ {
@@ -348,7 +396,7 @@ func rmFalls(width int, height int, direction string, f *os.File) error {
 // crawling up and over the wall.
 // This function clears space for the wall. The width, height, and depth parameters specify the
 // extent of the cleared area. The wall is put in the middle of the cleared area.
-func ClearForWall(width int, direction string, f *os.File) error {
+func ClearForWall(width int, direction string, f io.Writer) error {
 	origin := mcshapes.XYZ{X: 0, Y: 0, Z: -2}
 
 	// Minecraft will not accept a width that is too large. 150 is too large, 100 works.
@@ -390,11 +438,10 @@ func ClearForWall(width int, direction string, f *os.File) error {
 func BuildRollerCoasterFalls(basepath string) error {
 	// Create the file that will contain both the north and south waterfalls.
 	fname := path.Join(basepath, "Falls/waterfall_rc_north_south.mcfunction")
-	f, err := os.OpenFile(fname, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
+	f, err := CreateFunctionFile(basepath, "Falls", "waterfall_rc_north_south.mcfunction")
 	if err != nil {
 		return fmt.Errorf("open %v: %v", fname, err)
 	}
-	defer f.Close()
 
 	// Build the north fall - faces south, runs west to east.
 	origin := mcshapes.XYZ{X: 2, Y: 0, Z: -2}
@@ -445,6 +492,6 @@ func BuildRollerCoasterFalls(basepath string) error {
 		return fmt.Errorf("build waterfall rc track: %v", err)
 	}
 
-	return nil
+	return f.Close()
 }
 
@@ -3,7 +3,7 @@ package main
 import (
 	//"bytes"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	//"strings"
 
@@ -14,12 +14,84 @@ import (
 )
 
 // Structure for using TOML to extract input from the user.
+//    MWall                  The walls, see mwallEntry
+//    MWallHeight, ...       The walls in the old input form, one array for each key
 type mcfdMWallInputStruct struct {
-	MWallHeight         []int    `toml:"MWallHeight"`
-	MWallWidth          []int    `toml:"MWallWidth"`
-	MWallDepth          []int    `toml:"MWallDepth"`
-	MWallWoodBlockType  []string `toml:"MWallWoodBlockType"`
-	MWallBrickBlockType []string `toml:"MWallBrickBlockType"`
+	MWall               []mwallEntry `toml:"mwall"`
+	MWallHeight         []int        `toml:"MWallHeight"`
+	MWallWidth          []int        `toml:"MWallWidth"`
+	MWallDepth          []int        `toml:"MWallDepth"`
+	MWallWoodBlockType  []string     `toml:"MWallWoodBlockType"`
+	MWallBrickBlockType []string     `toml:"MWallBrickBlockType"`
+}
+
+// mwallEntry is one wall in the input file, see Input.go.
+//    name     Name of the functions instead of the height and width, optional
+//    height   Height of the wall, default 15
+//    width    Width of the wall, must be even and at least 2, default 10
+//    depth    Depth of the wall, default 1
+//    wood     Block for the wood, default "log 1"
+//    brick    Block for the bricks, default "monster_egg 2"
+type mwallEntry struct {
+	Name   string `toml:"name"`
+	Height int    `toml:"height"`
+	Width  int    `toml:"width"`
+	Depth  int    `toml:"depth"`
+	Wood   string `toml:"wood"`
+	Brick  string `toml:"brick"`
+}
+
+// entries returns the walls in the input, the old arrays first, with the defaults set.
+func (in mcfdMWallInputStruct) entries() ([]mwallEntry, error) {
+	n, err := parallelArrays("mwall", []string{"MWallHeight", "MWallWidth", "MWallDepth",
+		"MWallWoodBlockType", "MWallBrickBlockType"},
+		[]int{len(in.MWallHeight), len(in.MWallWidth), len(in.MWallDepth),
+			len(in.MWallWoodBlockType), len(in.MWallBrickBlockType)})
+	if err != nil {
+		return nil, err
+	}
+	var entries []mwallEntry
+	for i := 0; i < n; i++ {
+		entries = append(entries, mwallEntry{Height: in.MWallHeight[i], Width: in.MWallWidth[i],
+			Depth: in.MWallDepth[i], Wood: in.MWallWoodBlockType[i],
+			Brick: in.MWallBrickBlockType[i]})
+	}
+	for _, e := range in.MWall {
+		defaultInt(&e.Height, 15)
+		defaultInt(&e.Width, 10)
+		defaultInt(&e.Depth, 1)
+		defaultString(&e.Wood, "log 1")
+		defaultString(&e.Brick, "monster_egg 2")
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// checkMWallInput checks the walls in the input file, see ValidateInput.
+func checkMWallInput(c *inputCheck) {
+	var in mcfdMWallInputStruct
+	if !c.decode(&in) {
+		return
+	}
+	walls, err := in.entries()
+	if err != nil {
+		c.add(topKey("MWallHeight"), err.Error(), "give every array the same number of values")
+		return
+	}
+	keys := entryKeys{"mwall", len(in.MWallHeight), map[string]string{
+		"height": "MWallHeight", "width": "MWallWidth", "depth": "MWallDepth",
+		"wood": "MWallWoodBlockType", "brick": "MWallBrickBlockType"}}
+	for i, e := range walls {
+		c.name("mwall", keys.key(i, "name"), e.Name)
+		c.atLeast(keys.key(i, "height"), "height", e.Height, 3, "e.g. height = 15")
+		if e.Width < conun_width || e.Width%conun_width != 0 {
+			c.add(keys.key(i, "width"), fmt.Sprintf("the width is %d, it must be even and at "+
+				"least %d", e.Width, conun_width), "e.g. width = 10")
+		}
+		c.atLeast(keys.key(i, "depth"), "depth", e.Depth, 1, "e.g. depth = 1")
+		c.block(keys.key(i, "wood"), e.Wood, false)
+		c.block(keys.key(i, "brick"), e.Brick, false)
+	}
 }
 
 // The construction unit for MWall is 2 blocks wide. This unit is duplicated
@@ -29,43 +101,24 @@ var conun_width int = 2
 // CreateMWallDriver
 // Driver for creating the Minecraft function files for this type of
 // castle wall.
-func CreateMWallDriver(inputFile string, basepath string) {
+func CreateMWallDriver(inputFile string, basepath string) error {
 	// Extract pertinent input, using TOML, from the user input file
 	var mcfdInput mcfdMWallInputStruct
 	if _, err := toml.DecodeFile(inputFile, &mcfdInput); err != nil {
-		fmt.Println(err)
-		return
+		return fmt.Errorf("CreateMWall: %v", err)
 	}
 
 	// Consistency check on the user input
-	dim := [5]int{0, 0, 0, 0, 0}
-	dim[0] = len(mcfdInput.MWallHeight)
-	dim[1] = len(mcfdInput.MWallWidth)
-	dim[2] = len(mcfdInput.MWallDepth)
-	dim[3] = len(mcfdInput.MWallWoodBlockType)
-	dim[4] = len(mcfdInput.MWallBrickBlockType)
-	maxdim := dim[0]
-	mindim := dim[0]
-	for _, v := range dim {
-		if v < mindim {
-			mindim = v
-		}
-		if v > maxdim {
-			maxdim = v
-		}
-	}
-	if maxdim != mindim {
-		fmt.Println("CreateMWall user input FATAL ERROR")
-		fmt.Println("You must specify the same number of array values for all the")
-		fmt.Println("CreateMWall arrays - MWallHeight, MWallWoodBlockType, ...")
-		return
+	walls, err := mcfdInput.entries()
+	if err != nil {
+		return fmt.Errorf("CreateMWall user input: %v", err)
 	}
-
+	maxdim := len(walls)
 
 	// If the user has not specified anything then there is nothing left
 	// to do.
 	if maxdim <= 0 {
-		return
+		return nil
 	}
 
 	// Create the walls requested by the user in the user input file.
@@ -87,15 +140,19 @@ func CreateMWallDriver(inputFile string, basepath string) {
 			dname := directionNames[j]
 			k := j + i*ndirvals
 			// Minecraft functions must have a suffix of ".mcfunction"
-			sheight := fmt.Sprintf("%d", mcfdInput.MWallHeight[i])
-			swidth := fmt.Sprintf("%d", mcfdInput.MWallWidth[i])
-			sdepth := fmt.Sprintf("%d", mcfdInput.MWallDepth[i])
-			wood_blkname := mcfdInput.MWallWoodBlockType[i]
-			brick_blkname := mcfdInput.MWallBrickBlockType[i]
+			sheight := fmt.Sprintf("%d", walls[i].Height)
+			swidth := fmt.Sprintf("%d", walls[i].Width)
+			sdepth := fmt.Sprintf("%d", walls[i].Depth)
+			wood_blkname := walls[i].Wood
+			brick_blkname := walls[i].Brick
 			//filename[k] = "MWall_" + direction + "_" + sheight + "_" + swidth + "_" +
 			//	sdepth + "_" + wood_blkname + "_" + brick_blkname + ".mcfunction"
 			filename[k] = "mw_" + dname + "_" + sheight + "_" + swidth + ".mcfunction"
 			filename_rm[k] = "mw_" + dname + "_" + sheight + "_" + swidth + "_rm.mcfunction"
+			if walls[i].Name != "" {
+				filename[k] = "mw_" + dname + "_" + walls[i].Name + ".mcfunction"
+				filename_rm[k] = "mw_" + dname + "_" + walls[i].Name + "_rm.mcfunction"
+			}
 
 			table.Append([]string{filename[k], sheight, swidth, sdepth,
 				wood_blkname, brick_blkname})
@@ -111,24 +168,25 @@ func CreateMWallDriver(inputFile string, basepath string) {
 			direction := directionValues[j]
 			k := j + i*ndirvals
 			err := CreateMWall(basepath, filename[k], direction,
-				mcfdInput.MWallHeight[i],
-				mcfdInput.MWallWidth[i],
-				mcfdInput.MWallDepth[i],
-				mcfdInput.MWallWoodBlockType[i],
-				mcfdInput.MWallBrickBlockType[i])
+				walls[i].Height,
+				walls[i].Width,
+				walls[i].Depth,
+				walls[i].Wood,
+				walls[i].Brick)
 			if err != nil {
-				log.Fatalln(err)
+				return err
 			}
 
 			err = RmMWall(basepath, filename_rm[k], direction,
-				mcfdInput.MWallHeight[i],
-				mcfdInput.MWallWidth[i],
-				mcfdInput.MWallDepth[i])
+				walls[i].Height,
+				walls[i].Width,
+				walls[i].Depth)
 			if err != nil {
-				log.Fatalln(err)
+				return err
 			}
 		}
 	}
+	return nil
 }
 
 // CreateMWall
@@ -138,11 +196,10 @@ func CreateMWall(basepath string, filename string, direction string,
 	brick_btype string) error {
 
 	fname := basepath + "/MWall/" + filename
-	f, err := os.OpenFile(fname, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
+	f, err := CreateFunctionFile(basepath, "MWall", filename)
 	if err != nil {
 		return fmt.Errorf("CreateMWall open %v: %v", fname, err)
 	}
-	defer f.Close()
 
 	// When facing north, the depth is the negative Z coordinate and the width is positive X
 	// height is the Y coordinate
@@ -165,41 +222,56 @@ func CreateMWall(basepath string, filename string, direction string,
 	// The following is done facing north, i.e. a north wall. The low level function
 	// takes care of rotating it for a east, south, and west wall.
 
-	// Clear out the space first
-	WriteMWallBox(0, 0, near_bf, 1, total_height-1, far_bf, nc, "air", direction, f)
+	boxes := []mwallBox{
+		// Clear out the space first
+		{0, 0, near_bf, 1, total_height-1, far_bf, "air"},
 
-	// The lower two wood pieces
-	WriteMWallBox(0, 0, near_wf, 1, 0, near_wf, nc, wood_btype, direction, f)
-	WriteMWallBox(0, 0, far_wf,  1, 0, far_wf,  nc, wood_btype, direction, f)
+		// The lower two wood pieces
+		{0, 0, near_wf, 1, 0, near_wf, wood_btype},
+		{0, 0, far_wf,  1, 0, far_wf,  wood_btype},
 
-	// The lower bricks going from near to far
-	WriteMWallBox(0, 1, near_bf,   0, 1, far_bf,    nc, brick_btype, direction, f)
-	WriteMWallBox(1, 1, near_bf-2, 1, 1, far_bf+2,  nc, brick_btype, direction, f)
+		// The lower bricks going from near to far
+		{0, 1, near_bf,   0, 1, far_bf,    brick_btype},
+		{1, 1, near_bf-2, 1, 1, far_bf+2,  brick_btype},
 
-	// The wood and brick vertical columns
-	WriteMWallBox(1, 1, near_wf, 1, height-2, near_wf, nc, wood_btype, direction, f)
-	WriteMWallBox(1, 1, far_wf,  1, height-2, far_wf,  nc, wood_btype, direction, f)
+		// The wood and brick vertical columns
+		{1, 1, near_wf, 1, height-2, near_wf, wood_btype},
+		{1, 1, far_wf,  1, height-2, far_wf,  wood_btype},
 
-	WriteMWallBox(0, 2, near_bf, 0, height-3, near_bf, nc, brick_btype, direction, f)
-	WriteMWallBox(0, 2, far_bf,  0, height-3, far_bf,  nc, brick_btype, direction, f)
+		{0, 2, near_bf, 0, height-3, near_bf, brick_btype},
+		{0, 2, far_bf,  0, height-3, far_bf,  brick_btype},
 
-	WriteMWallBox(0, 2, near_bf-2, 1, height-3, near_bf-2, nc, brick_btype, direction, f)
-	WriteMWallBox(0, 2, far_bf+2, 1, height-3, far_bf+2, nc, brick_btype, direction, f)
+		{0, 2, near_bf-2, 1, height-3, near_bf-2, brick_btype},
+		{0, 2, far_bf+2, 1, height-3, far_bf+2, brick_btype},
 
-	// The upper bricks going from near to far
-	WriteMWallBox(0, height-2, near_bf,   0, height-2, far_bf,    nc, brick_btype, direction, f)
-	WriteMWallBox(1, height-2, near_bf-2, 1, height-2, far_bf+2,  nc, brick_btype, direction, f)
+		// The upper bricks going from near to far
+		{0, height-2, near_bf,   0, height-2, far_bf,    brick_btype},
+		{1, height-2, near_bf-2, 1, height-2, far_bf+2,  brick_btype},
 
-	// The top two wood peices
-	WriteMWallBox(0, height-1, near_wf, 1, height-1, near_wf, nc, wood_btype, direction, f)
-	WriteMWallBox(0, height-1, far_wf,  1, height-1, far_wf,  nc, wood_btype, direction, f)
+		// The top two wood peices
+		{0, height-1, near_wf, 1, height-1, near_wf, wood_btype},
+		{0, height-1, far_wf,  1, height-1, far_wf,  wood_btype},
 
-	WriteMWallBox(0, height, near_wf, 0, height, near_wf, nc, "gold_block", direction, f)
-	WriteMWallBox(0, height, far_wf,  0, height, far_wf,  nc, "gold_block", direction, f)
-	WriteMWallBox(0, height+1, near_wf, 0, height+1, near_wf, nc, "torch", direction, f)
-	WriteMWallBox(0, height+1, far_wf,  0, height+1, far_wf,  nc, "torch", direction, f)
+		{0, height, near_wf, 0, height, near_wf, "gold_block"},
+		{0, height, far_wf,  0, height, far_wf,  "gold_block"},
+		{0, height+1, near_wf, 0, height+1, near_wf, "torch"},
+		{0, height+1, far_wf,  0, height+1, far_wf,  "torch"},
+	}
+	for _, b := range boxes {
+		err := WriteMWallBox(b.x1, b.y1, b.z1, b.x2, b.y2, b.z2, nc, b.block, direction, f)
+		if err != nil {
+			return err
+		}
+	}
 
-	return nil
+	return f.Close()
+}
+
+// mwallBox is one box of a construction unit, see WriteMWallBox
+type mwallBox struct {
+	x1, y1, z1 int
+	x2, y2, z2 int
+	block      string
 }
 
 // RmMWall
@@ -208,11 +280,10 @@ func RmMWall(basepath string, filename string, direction string,
 	total_height int, width int, depth int) error {
 
 	fname := basepath + "/MWall/" + filename
-	f, err := os.OpenFile(fname, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
+	f, err := CreateFunctionFile(basepath, "MWall", filename)
 	if err != nil {
 		return fmt.Errorf("CreateMWall open %v: %v", fname, err)
 	}
-	defer f.Close()
 
 	nc := width/conun_width
 	total_depth := depth + 3 + 3
@@ -221,16 +292,19 @@ func RmMWall(basepath string, filename string, direction string,
 	far_bf  := near_bf - total_depth + 1  // Far brick face
 
 	// Clear out the space first
-	WriteMWallBox(0, 0, near_bf, 1, total_height-1, far_bf, nc, "air", direction, f)
+	err = WriteMWallBox(0, 0, near_bf, 1, total_height-1, far_bf, nc, "air", direction, f)
+	if err != nil {
+		return err
+	}
 
-	return nil
+	return f.Close()
 }
 
 
 // WriteMWallBox writes out a low level box for the wall.
 // Duplicate for all the contruction units (nconun)
 func WriteMWallBox(x1 int, y1 int, z1 int, x2 int, y2 int, z2 int,
-	nconun int, block_type string, direction string, f *os.File) error {
+	nconun int, block_type string, direction string, f io.Writer) error {
 
 	xt := 0
 	for n:=0; n<nconun; n++ {
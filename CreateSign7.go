@@ -3,7 +3,7 @@ package main
 import (
 	//"bytes"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	//"strings"
 
@@ -37,14 +37,102 @@ import (
 //                           Can also be "none"
 //    Sign7TextBlockType  Block for the text.
 //
+// These are the signs in the old input form, with one array for each key. The signs can
+// also be Sign7 tables, see sign7Entry.
 type mcfdSign7InputStruct struct {
-	Sign7Index         []int    `toml:"Sign7Index"`
-	Sign7Text1         []string `toml:"Sign7Text1"`
-	Sign7Text2         []string `toml:"Sign7Text2"`
-	Sign7Text3         []string `toml:"Sign7Text3"`
-	Sign7BackBlockType []string `toml:"Sign7BackBlockType"`
-	Sign7EdgeBlockType []string `toml:"Sign7EdgeBlockType"`
-	Sign7TextBlockType []string `toml:"Sign7TextBlockType"`
+	Sign7              []sign7Entry `toml:"sign7"`
+	Sign7Index         []int        `toml:"Sign7Index"`
+	Sign7Text1         []string     `toml:"Sign7Text1"`
+	Sign7Text2         []string     `toml:"Sign7Text2"`
+	Sign7Text3         []string     `toml:"Sign7Text3"`
+	Sign7BackBlockType []string     `toml:"Sign7BackBlockType"`
+	Sign7EdgeBlockType []string     `toml:"Sign7EdgeBlockType"`
+	Sign7TextBlockType []string     `toml:"Sign7TextBlockType"`
+}
+
+// sign7Entry is one sign in the input file, see Input.go. The keys are the same as above,
+//    name          Name of the functions instead of the index of the sign, optional
+//    text1         Text for the first line
+//    text2, text3  Text for the second and third lines, default "none"
+//    back_block    Block for the backing, default "lapis_block"
+//    edge_block    Block for the edge, default "sea_lantern"
+//    text_block    Block for the text, default "gold_block"
+type sign7Entry struct {
+	Name      string `toml:"name"`
+	Text1     string `toml:"text1"`
+	Text2     string `toml:"text2"`
+	Text3     string `toml:"text3"`
+	BackBlock string `toml:"back_block"`
+	EdgeBlock string `toml:"edge_block"`
+	TextBlock string `toml:"text_block"`
+}
+
+// entries returns the signs in the input, the old arrays first, with the defaults set.
+func (in mcfdSign7InputStruct) entries() ([]sign7Entry, error) {
+	n, err := parallelArrays("sign7", []string{"Sign7Index", "Sign7Text1", "Sign7Text2",
+		"Sign7Text3", "Sign7BackBlockType", "Sign7EdgeBlockType", "Sign7TextBlockType"},
+		[]int{len(in.Sign7Index), len(in.Sign7Text1), len(in.Sign7Text2), len(in.Sign7Text3),
+			len(in.Sign7BackBlockType), len(in.Sign7EdgeBlockType), len(in.Sign7TextBlockType)})
+	if err != nil {
+		return nil, err
+	}
+	var entries []sign7Entry
+	for i := 0; i < n; i++ {
+		entries = append(entries, sign7Entry{Text1: in.Sign7Text1[i], Text2: in.Sign7Text2[i],
+			Text3: in.Sign7Text3[i], BackBlock: in.Sign7BackBlockType[i],
+			EdgeBlock: in.Sign7EdgeBlockType[i], TextBlock: in.Sign7TextBlockType[i]})
+	}
+	for _, e := range in.Sign7 {
+		defaultString(&e.Text1, "none")
+		defaultString(&e.Text2, "none")
+		defaultString(&e.Text3, "none")
+		defaultString(&e.BackBlock, "lapis_block")
+		defaultString(&e.EdgeBlock, "sea_lantern")
+		defaultString(&e.TextBlock, "gold_block")
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// checkSign7Input checks the signs in the input file, see ValidateInput.
+func checkSign7Input(c *inputCheck) {
+	var in mcfdSign7InputStruct
+	if !c.decode(&in) {
+		return
+	}
+	signs, err := in.entries()
+	if err != nil {
+		c.add(topKey("Sign7Index"), err.Error(), "give every array the same number of values")
+		return
+	}
+	keys := entryKeys{"sign7", len(in.Sign7Index), map[string]string{
+		"text1": "Sign7Text1", "text2": "Sign7Text2", "text3": "Sign7Text3",
+		"back_block": "Sign7BackBlockType", "edge_block": "Sign7EdgeBlockType",
+		"text_block": "Sign7TextBlockType"}}
+	for i, e := range signs {
+		c.name("sign7", keys.key(i, "name"), e.Name)
+		if e.Text1 == "none" {
+			c.add(keys.key(i, "text1"), "the sign has no text",
+				"give the text of the first line, text2 and text3 can be left out")
+		}
+		for j, text := range []string{e.Text1, e.Text2, e.Text3} {
+			if text == "none" {
+				continue
+			}
+			// The other characters would be drawn as A
+			for _, r := range text {
+				if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+					c.add(keys.key(i, fmt.Sprintf("text%d", j+1)),
+						fmt.Sprintf("%q has %q, the signs only have A to Z and 0 to 9", text, r),
+						"use capital letters and digits, without spaces")
+					break
+				}
+			}
+		}
+		c.block(keys.key(i, "back_block"), e.BackBlock, false)
+		c.block(keys.key(i, "edge_block"), e.EdgeBlock, false)
+		c.block(keys.key(i, "text_block"), e.TextBlock, false)
+	}
 }
 
 // Number of text lines the user can input.
@@ -54,55 +142,31 @@ const nlines_inp = 3
 
 // CreateSign7Driver
 // Driver for creating the Minecraft function files for 7 block tall letter signs
-func CreateSign7Driver(inputFile string, basepath string) {
+func CreateSign7Driver(inputFile string, basepath string) error {
 	// Extract pertinent input, using TOML, from the user input file
 	var mcfdInput mcfdSign7InputStruct
 	if _, err := toml.DecodeFile(inputFile, &mcfdInput); err != nil {
-		fmt.Println(err)
-		return
+		return fmt.Errorf("CreateSign7: %v", err)
 	}
 
 	// Consistency check on the user input
-	dim := [7]int{0, 0, 0, 0, 0, 0, 0}
-	dim[0] = len(mcfdInput.Sign7Index)
-	dim[1] = len(mcfdInput.Sign7Text1)
-	dim[2] = len(mcfdInput.Sign7Text2)
-	dim[3] = len(mcfdInput.Sign7Text3)
-	dim[4] = len(mcfdInput.Sign7BackBlockType)
-	dim[5] = len(mcfdInput.Sign7EdgeBlockType)
-	dim[6] = len(mcfdInput.Sign7TextBlockType)
-	maxdim := dim[0]
-	mindim := dim[0]
-	for _, v := range dim {
-		if v < mindim {
-			mindim = v
-		}
-		if v > maxdim {
-			maxdim = v
-		}
-	}
-	if maxdim != mindim {
-		fmt.Println("CreateSign7 user input FATAL ERROR")
-		fmt.Println("You must specify the same number of array values for all the")
-		fmt.Println("CreateSign7 arrays - Sign7Text1, Sign7EdgeBlockType, ...")
-		fmt.Println("maxdim = ", maxdim)
-		fmt.Println("mindim = ", mindim)
-		return
+	signs, err := mcfdInput.entries()
+	if err != nil {
+		return fmt.Errorf("CreateSign7 user input: %v", err)
 	}
+	maxdim := len(signs)
 
 	for i := 0; i < maxdim; i++ {
-		if mcfdInput.Sign7Text1[i] == "none" {
-			fmt.Println("CreateSign7 user input FATAL ERROR")
-			fmt.Println("You must specify something other than none for Sign7Text1")
-			fmt.Println("Sign7Text2 and Sign7Text3 can be none")
-			return
+		if signs[i].Text1 == "none" {
+			return fmt.Errorf("CreateSign7 user input: sign %d has no text, Sign7Text1 (text1) "+
+				"must be something other than none", i)
 		}
 	}
 
 	// If the user has not specified anything then there is nothing left
 	// to do.
 	if maxdim <= 0 {
-		return
+		return nil
 	}
 
 	// Create the signs requested by the user in the user input file.
@@ -120,6 +184,9 @@ func CreateSign7Driver(inputFile string, basepath string) {
 	directionNames := []string{"N", "E", "S", "W"}
 	for i := 0; i < maxdim; i++ {
 		index_str := fmt.Sprintf("%d", i)
+		if signs[i].Name != "" {
+			index_str = signs[i].Name
+		}
 		for j := 0; j < ndirvals; j++ {
 			dname := directionNames[j]
 			k := j + i*ndirvals
@@ -128,11 +195,11 @@ func CreateSign7Driver(inputFile string, basepath string) {
 			//	"_" + mcfdInput.Sign7TextBlockType[i] + "_" + index_str + ".mcfunction"
 			filename[k] = "s_" + dname + "_" + index_str + ".mcfunction"
 			filename_rm[k] = "s_" + dname + "_" + index_str + "_rm.mcfunction"
-			table.Append([]string{filename[k], mcfdInput.Sign7BackBlockType[i],
-				mcfdInput.Sign7EdgeBlockType[i], mcfdInput.Sign7TextBlockType[i],
+			table.Append([]string{filename[k], signs[i].BackBlock,
+				signs[i].EdgeBlock, signs[i].TextBlock,
 				index_str})
-			table.Append([]string{filename_rm[k], mcfdInput.Sign7BackBlockType[i],
-				mcfdInput.Sign7EdgeBlockType[i], mcfdInput.Sign7TextBlockType[i],
+			table.Append([]string{filename_rm[k], signs[i].BackBlock,
+				signs[i].EdgeBlock, signs[i].TextBlock,
 				index_str})
 		}
 	}
@@ -141,20 +208,21 @@ func CreateSign7Driver(inputFile string, basepath string) {
 	// Now actually write the Sign7 functions, both create and remove.
 	for i := 0; i < maxdim; i++ {
 		text_inp_arr := [nlines_inp]string{}
-		text_inp_arr[0] = mcfdInput.Sign7Text1[i]
-		text_inp_arr[1] = mcfdInput.Sign7Text2[i]
-		text_inp_arr[2] = mcfdInput.Sign7Text3[i]
+		text_inp_arr[0] = signs[i].Text1
+		text_inp_arr[1] = signs[i].Text2
+		text_inp_arr[2] = signs[i].Text3
 		for j := 0; j < ndirvals; j++ {
 			direction := directionValues[j]
 			k := j + i*ndirvals
 			err := CreateSign7(basepath, filename[k], filename_rm[k], direction,
-				text_inp_arr[:], mcfdInput.Sign7BackBlockType[i],
-				mcfdInput.Sign7EdgeBlockType[i], mcfdInput.Sign7TextBlockType[i])
+				text_inp_arr[:], signs[i].BackBlock,
+				signs[i].EdgeBlock, signs[i].TextBlock)
 			if err != nil {
-				log.Fatalln(err)
+				return err
 			}
 		}
 	}
+	return nil
 }
 
 // CreateSign7
@@ -163,11 +231,10 @@ func CreateSign7(basepath string, filename string, filename_rm string, direction
 	text_inp_arr []string, blk_back string, blk_edge string, blk_text string) error {
 
 	fname := basepath + "/Sign7/" + filename
-	f, err := os.OpenFile(fname, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
+	f, err := CreateFunctionFile(basepath, "Sign7", filename)
 	if err != nil {
 		return fmt.Errorf("CreateSign7 open %v: %v", fname, err)
 	}
-	defer f.Close()
 
 	// Every character is in a box 5 blocks wide and 7 blocks tall (except for "I" and "1" which are 3 blocks
 	// wide). This is taken to be a 5x7, X,Y grid with the 1,1 point being in the lower left corner. X goes
@@ -308,11 +375,21 @@ func CreateSign7(basepath string, filename string, filename_rm string, direction
 	bh := 7*nlines_text + (nlines_text - 1) + (2 + 2) - 1
 
 	// Render the back of the sign and the edges.
-	WriteSign7Box(0,  0,  -2,   bw, bh, -2,  blk_back, direction, f)    // Back of the sign
-	WriteSign7Box(0,  0,  -2,   bw,  0, -2,  blk_edge, direction, f)    // Lower edge
-	WriteSign7Box(0,  bh, -2,   bw, bh, -2,  blk_edge, direction, f)    // Upper edge
-	WriteSign7Box(0,  0,  -2,   0,  bh, -2,  blk_edge, direction, f)    // Left edge
-	WriteSign7Box(bw, 0,  -2,   bw, bh, -2,  blk_edge, direction, f)    // Right edge
+	boxes := []struct {
+		x1, y1, x2, y2 int
+		blk            string
+	}{
+		{0,  0,    bw, bh,  blk_back},    // Back of the sign
+		{0,  0,    bw,  0,  blk_edge},    // Lower edge
+		{0,  bh,   bw, bh,  blk_edge},    // Upper edge
+		{0,  0,    0,  bh,  blk_edge},    // Left edge
+		{bw, 0,    bw, bh,  blk_edge},    // Right edge
+	}
+	for _, b := range boxes {
+		if err := WriteSign7Box(b.x1, b.y1, -2, b.x2, b.y2, -2, b.blk, direction, f); err != nil {
+			return err
+		}
+	}
 
 	// Render the text.
 	xs := 2
@@ -343,7 +420,10 @@ func CreateSign7(basepath string, filename string, filename_rm string, direction
 			for i := 0; i < np; i++ {
 				x := xs + coords[ic][i*2] - 1
 				y := ys + coords[ic][i*2+1] - 1
-				WriteSign7Box(x, y, -2,  x, y, -2, blk_text, direction, f)
+				err := WriteSign7Box(x, y, -2,  x, y, -2, blk_text, direction, f)
+				if err != nil {
+					return err
+				}
 			}
 
 			// Go on to the next character
@@ -353,18 +433,24 @@ func CreateSign7(basepath string, filename string, filename_rm string, direction
 		ys -= 7 + 1
 	}
 
+	if err := f.Close(); err != nil {
+		return err
+	}
+
 	// Write the file to remove a sign.
 	fname_rm := basepath + "/Sign7/" + filename_rm
-	f_rm, err_rm := os.OpenFile(fname_rm, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
+	f_rm, err_rm := CreateFunctionFile(basepath, "Sign7", filename_rm)
 	if err_rm != nil {
 		return fmt.Errorf("CreateSign7 open rm file %v: %v", fname_rm, err_rm)
 	}
-	defer f_rm.Close()
 
 	// Remove the sign
-	WriteSign7Box(0,  0,  -2,   bw, bh, -2,  "air",  direction, f_rm)    // Back of the sign
+	err = WriteSign7Box(0,  0,  -2,   bw, bh, -2,  "air",  direction, f_rm)    // Back of the sign
+	if err != nil {
+		return err
+	}
 
-	return nil
+	return f_rm.Close()
 }
 
 
@@ -391,7 +477,7 @@ func GetNblocksInString(text string) int {
 
 // WriteSign7Box writes out a low level box for the sign.
 func WriteSign7Box(x1 int, y1 int, z1 int, x2 int, y2 int, z2 int,
-	block_type string, direction string, f *os.File) error {
+	block_type string, direction string, f io.Writer) error {
 
 	corner1 := mcshapes.XYZ{X: x1, Y: y1, Z: z1}
 	corner2 := mcshapes.XYZ{X: x2, Y: y2, Z: z2}
@@ -3,54 +3,95 @@ package main
 import (
 	"bytes"
 	"fmt"
-	"log"
 	"os"
 	"strings"
 
 	"github.com/BurntSushi/toml"
 	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
 	"github.com/olekukonko/tablewriter"
-	"github.com/benmcclelland/mcrender"
 )
 
 // Structure for using TOML to extract input from the user.
+//    Sphere                   The spheres, see sphereEntry
+//    SphereRadius, ...        The spheres in the old input form, one array for each key
 type mcfdControlStruct struct {
-	SphereRadius            []int    `toml:"SphereRadius"`
-	SphereExteriorBlockType []string `toml:"SphereExteriorBlockType"`
-	SphereInteriorBlockType []string `toml:"SphereInteriorBlockType"`
+	Sphere                  []sphereEntry `toml:"sphere"`
+	SphereRadius            []int         `toml:"SphereRadius"`
+	SphereExteriorBlockType []string      `toml:"SphereExteriorBlockType"`
+	SphereInteriorBlockType []string      `toml:"SphereInteriorBlockType"`
+}
+
+// sphereEntry is one sphere in the input file, see Input.go.
+//    name       Name of the function instead of the blocks and radius, optional
+//    radius     Radius in blocks
+//    exterior   Block for the shell of the sphere, default "glass"
+//    interior   Block to fill the sphere with, default "none" for a hollow sphere
+type sphereEntry struct {
+	Name     string `toml:"name"`
+	Radius   int    `toml:"radius"`
+	Exterior string `toml:"exterior"`
+	Interior string `toml:"interior"`
+}
+
+// entries returns the spheres in the input, the old arrays first, with the defaults set.
+func (in mcfdControlStruct) entries() ([]sphereEntry, error) {
+	n, err := parallelArrays("sphere",
+		[]string{"SphereRadius", "SphereExteriorBlockType", "SphereInteriorBlockType"},
+		[]int{len(in.SphereRadius), len(in.SphereExteriorBlockType),
+			len(in.SphereInteriorBlockType)})
+	if err != nil {
+		return nil, err
+	}
+	var entries []sphereEntry
+	for i := 0; i < n; i++ {
+		entries = append(entries, sphereEntry{Radius: in.SphereRadius[i],
+			Exterior: in.SphereExteriorBlockType[i], Interior: in.SphereInteriorBlockType[i]})
+	}
+	for _, e := range in.Sphere {
+		defaultString(&e.Exterior, "glass")
+		defaultString(&e.Interior, "none")
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// checkSphereInput checks the spheres in the input file, see ValidateInput.
+func checkSphereInput(c *inputCheck) {
+	var in mcfdControlStruct
+	if !c.decode(&in) {
+		return
+	}
+	spheres, err := in.entries()
+	if err != nil {
+		c.add(topKey("SphereRadius"), err.Error(), "give every array the same number of values")
+		return
+	}
+	keys := entryKeys{"sphere", len(in.SphereRadius), map[string]string{"radius": "SphereRadius",
+		"exterior": "SphereExteriorBlockType", "interior": "SphereInteriorBlockType"}}
+	for i, e := range spheres {
+		c.name("sphere", keys.key(i, "name"), e.Name)
+		c.atLeast(keys.key(i, "radius"), "radius", e.Radius, 1, "give the radius in blocks, "+
+			"e.g. radius = 5")
+		c.block(keys.key(i, "exterior"), e.Exterior, false)
+		c.block(keys.key(i, "interior"), e.Interior, true)
+	}
 }
 
 // CreateSphereDriver
 // Driver for creating the sphere Minecraft function files.
-func CreateSphereDriver(inputFile string, basepath string) {
+func CreateSphereDriver(inputFile string, basepath string) error {
 	// Extract pertinent input, using TOML, from the user input file
 	var mcfdInput mcfdControlStruct
 	if _, err := toml.DecodeFile(inputFile, &mcfdInput); err != nil {
-		fmt.Println(err)
-		return
+		return fmt.Errorf("CreateSphere: %v", err)
 	}
 
 	// Consistency check on the user input
-	dim := [3]int{0, 0, 0}
-	dim[0] = len(mcfdInput.SphereRadius)
-	dim[1] = len(mcfdInput.SphereExteriorBlockType)
-	dim[2] = len(mcfdInput.SphereInteriorBlockType)
-	maxdim := dim[0]
-	mindim := dim[0]
-	for _, v := range dim {
-		if v < mindim {
-			mindim = v
-		}
-		if v > maxdim {
-			maxdim = v
-		}
-	}
-	if maxdim != mindim {
-		fmt.Println("CreateSphere user input FATAL ERROR")
-		fmt.Println("You must specify the same number of array values for all the")
-		fmt.Println("CreateSphere arrays - SphereRadius, SphereExteriorBlockType, ...")
-		return
+	spheres, err := mcfdInput.entries()
+	if err != nil {
+		return fmt.Errorf("CreateSphere user input: %v", err)
 	}
+	maxdim := len(spheres)
 
 	// Create the spheres requested by the user in the user input file.
 	if maxdim > 0 {
@@ -61,31 +102,31 @@ func CreateSphereDriver(inputFile string, basepath string) {
 		table := tablewriter.NewWriter(os.Stdout)
 		table.SetHeader([]string{"Filename", "Radius", "Exterior", "Interior"})
 		filename := make([]string, maxdim)
-		for i := 0; i < maxdim; i++ {
+		for i, sp := range spheres {
 			// Minecraft functions must have a suffix of ".mcfunction"
-			srad := fmt.Sprintf("%d", mcfdInput.SphereRadius[i])
-			blkname := mcfdInput.SphereExteriorBlockType[i]
-			if mcfdInput.SphereInteriorBlockType[i] != "none" {
-				blkname = mcfdInput.SphereExteriorBlockType[i] + "_" +
-					mcfdInput.SphereInteriorBlockType[i]
+			srad := fmt.Sprintf("%d", sp.Radius)
+			blkname := sp.Exterior
+			if sp.Interior != "none" {
+				blkname = sp.Exterior + "_" + sp.Interior
 			}
 			filename[i] = "s_" + blkname + "_" + srad + ".mcfunction"
+			if sp.Name != "" {
+				filename[i] = "s_" + sp.Name + ".mcfunction"
+			}
 
-			table.Append([]string{filename[i], srad, mcfdInput.SphereExteriorBlockType[i],
-				mcfdInput.SphereInteriorBlockType[i]})
+			table.Append([]string{filename[i], srad, sp.Exterior, sp.Interior})
 		}
 		table.Render()
 
 		// Now actually create the sphere functions
-		for i := 0; i < maxdim; i++ {
-			err := CreateSphere(basepath, filename[i], mcfdInput.SphereRadius[i],
-				mcfdInput.SphereExteriorBlockType[i],
-				mcfdInput.SphereInteriorBlockType[i])
+		for i, sp := range spheres {
+			err := CreateSphere(basepath, filename[i], sp.Radius, sp.Exterior, sp.Interior)
 			if err != nil {
-				log.Fatalln(err)
+				return err
 			}
 		}
 	}
+	return nil
 }
 
 // CreateSphere
@@ -94,27 +135,35 @@ func CreateSphere(basepath string, filename string, radius int, exteriorBlockTyp
 	center := mcshapes.XYZ{X: radius, Y: 0, Z: radius + 2}
 
 	fname := basepath + "/Sphere/" + filename
-	f, err := os.OpenFile(fname, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
+	f, err := CreateFunctionFile(basepath, "Sphere", filename)
 	if err != nil {
 		return fmt.Errorf("CreateSphere open %v: %v", fname, err)
 	}
-	defer f.Close()
 
+	// "none" is not a block, it is a sphere with nothing inside
+	interior := interiorBlockType
+	if interior != "none" {
+		interior = "minecraft:" + interior
+	}
 	b := mcshapes.NewSphere(mcshapes.WithRadius(radius), mcshapes.WithCenter(center),
 		mcshapes.WithSphereSurface("minecraft:"+exteriorBlockType),
-		mcshapes.WithSphereInteriorSurface("minecraft:"+interiorBlockType))
+		mcshapes.WithSphereInteriorSurface(interior))
 	err = b.WriteShape(f)
 	if err != nil {
 		return fmt.Errorf("CreateSphere write mcfunctions: %v", err)
 	}
+	if err = f.Close(); err != nil {
+		return err
+	}
 
 	var buf bytes.Buffer
 	if err = b.WriteShape(&buf); err != nil {
 		return fmt.Errorf("CreateSphere write to buffer: %v", err)		
 	}
 
+	// The stl file has no colors, the obj and gltf ExportFormats write colored meshes.
 	stlname := "stlFiles/" + strings.Replace(filename, "mcfunction", "stl", 1)
-	err = mcrender.CreateSTLFromInput(&buf, stlname)
+	err = writeSTL(stlname, &buf)
 	if err != nil {
 		return fmt.Errorf("CreateSphere render to stl file: %v", err)
 	}
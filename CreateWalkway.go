@@ -3,7 +3,7 @@ package main
 import (
 	//"bytes"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	//"strings"
 
@@ -14,22 +14,60 @@ import (
 )
 
 // Structure for using TOML to extract input from the user.
+//    Walkway          The walkways, see walkwayEntry
+//    WalkwayLength    The lengths of the walkways in the old input form
 type mcfdWalkwayInputStruct struct {
-	WalkwayLength []int `toml:"WalkwayLength"`
+	Walkway       []walkwayEntry `toml:"walkway"`
+	WalkwayLength []int          `toml:"WalkwayLength"`
+}
+
+// walkwayEntry is one walkway in the input file, see Input.go.
+//    name     Name of the functions instead of the length, optional
+//    length   Length of the walkway, default 10. The angled walkways are only made for
+//             lengths of 10 or more.
+type walkwayEntry struct {
+	Name   string `toml:"name"`
+	Length int    `toml:"length"`
+}
+
+// entries returns the walkways in the input, the old array first, with the defaults set.
+func (in mcfdWalkwayInputStruct) entries() []walkwayEntry {
+	var entries []walkwayEntry
+	for _, length := range in.WalkwayLength {
+		entries = append(entries, walkwayEntry{Length: length})
+	}
+	for _, e := range in.Walkway {
+		defaultInt(&e.Length, 10)
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// checkWalkwayInput checks the walkways in the input file, see ValidateInput.
+func checkWalkwayInput(c *inputCheck) {
+	var in mcfdWalkwayInputStruct
+	if !c.decode(&in) {
+		return
+	}
+	keys := entryKeys{"walkway", len(in.WalkwayLength), map[string]string{"length": "WalkwayLength"}}
+	for i, e := range in.entries() {
+		c.name("walkway", keys.key(i, "name"), e.Name)
+		c.atLeast(keys.key(i, "length"), "length", e.Length, 1, "e.g. length = 10")
+	}
 }
 
 // CreateWalkwayDriver
 // Driver for creating the walkway Minecraft function files.
-func CreateWalkwayDriver(inputFile string, basepath string) {
+func CreateWalkwayDriver(inputFile string, basepath string) error {
 	// Extract pertinent input, using TOML, from the user input file
 	var mcfdInput mcfdWalkwayInputStruct
 	if _, err := toml.DecodeFile(inputFile, &mcfdInput); err != nil {
-		fmt.Println(err)
-		return
+		return fmt.Errorf("CreateWalkway: %v", err)
 	}
+	walkways := mcfdInput.entries()
 
 	// Create the walkways requested by the user in the user input file.
-	dim := len(mcfdInput.WalkwayLength)
+	dim := len(walkways)
 	if dim > 0 {
 		// First echo user input to stdout so the user knows what was done.
 		// This also sets the filename to write the Minecraft function data.
@@ -60,17 +98,21 @@ func CreateWalkwayDriver(inputFile string, basepath string) {
 				dname := directionNames[j]
 				k := j + i*ndirvals
 				// Minecraft functions must have a suffix of ".mcfunction"
-				slen := fmt.Sprintf("%d", mcfdInput.WalkwayLength[i])
-				filename[k] = "ww_" + dname + "_" + slen + ".mcfunction"
+				slen := fmt.Sprintf("%d", walkways[i].Length)
+				sname := slen
+				if walkways[i].Name != "" {
+					sname = walkways[i].Name
+				}
+				filename[k] = "ww_" + dname + "_" + sname + ".mcfunction"
 				filename_cap[k] = "ww_" + dname + "_cap.mcfunction"
-				filename_rm[k] = "ww_" + dname + "_" + slen + "_rm.mcfunction"
+				filename_rm[k] = "ww_" + dname + "_" + sname + "_rm.mcfunction"
 				if dname=="N" || dname=="E" || dname=="S" || dname=="W" {
 					table.Append([]string{filename[k], slen})
 					table.Append([]string{filename_cap[k], slen})
 					table.Append([]string{filename_rm[k], slen})
 				}
 				if dname=="NW" || dname=="NE" || dname=="SE" || dname=="SW" {
-					wlen := mcfdInput.WalkwayLength[i]
+					wlen := walkways[i].Length
 					if wlen >= 10 {
 						table.Append([]string{filename[k], slen})
 						table.Append([]string{filename_rm[k], slen})
@@ -91,10 +133,10 @@ func CreateWalkwayDriver(inputFile string, basepath string) {
 				// Functions to create the walkways
 				if dname=="N" || dname=="E" || dname=="S" || dname=="W" {
 					err = CreateWalkway(basepath, filename[k], direction,
-						mcfdInput.WalkwayLength[i])
+						walkways[i].Length)
 				}
 				if dname=="NW" || dname=="NE" || dname=="SE" || dname=="SW" {
-					wlen := mcfdInput.WalkwayLength[i]
+					wlen := walkways[i].Length
 					if wlen >= 10 {
 						nconun := wlen / 10
 						err = CreateAngledWalkway(basepath, filename[k], nconun,
@@ -102,16 +144,16 @@ func CreateWalkwayDriver(inputFile string, basepath string) {
 					}
 				}
 				if err != nil {
-					log.Fatalln(err)
+					return err
 				}
 
 				// Functions to remove the walkways
 				if dname=="N" || dname=="E" || dname=="S" || dname=="W" {
 					err = RmWalkway(basepath, filename_rm[k], direction,
-						mcfdInput.WalkwayLength[i])
+						walkways[i].Length)
 				}
 				if dname=="NW" || dname=="NE" || dname=="SE" || dname=="SW" {
-					wlen := mcfdInput.WalkwayLength[i]
+					wlen := walkways[i].Length
 					if wlen >= 10 {
 						nconun := wlen / 10
 						err = RmAngledWalkway(basepath, filename_rm[k], nconun,
@@ -119,7 +161,7 @@ func CreateWalkwayDriver(inputFile string, basepath string) {
 					}
 				}
 				if err != nil {
-					log.Fatalln(err)
+					return err
 				}
 			}
 		}
@@ -134,12 +176,13 @@ func CreateWalkwayDriver(inputFile string, basepath string) {
 				if dname=="N" || dname=="E" || dname=="S" || dname=="W" {
 					err := CreateWalkwayCap(basepath, filename_cap[k], direction)
 					if err != nil {
-						log.Fatalln(err)
+						return err
 					}
 				}
 			}
 		}
 	}
+	return nil
 }
 
 
@@ -148,74 +191,74 @@ func CreateWalkway(basepath string, filename string, direction string,
 	wlength int) error {
 
 	fname := basepath + "/Walkway/" + filename
-	f, err := os.OpenFile(fname, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
+	f, err := CreateFunctionFile(basepath, "Walkway", filename)
 	if err != nil {
 		return fmt.Errorf("CreateWalkway open %v: %v", fname, err)
 	}
-	defer f.Close()
+	w := &walkwayWriter{f: f, direction: direction}
 
 	// First replace everything in the walkway with air, i.e.
 	// clear it out.
-	WriteWalkwayBox( -8, 0, -1,  8, 0, -wlength, "air", direction, f)
-	WriteWalkwayBox( -8, 1, -1,  8, 1, -wlength, "air", direction, f)
-	WriteWalkwayBox( -7, 2, -1,  7, 2, -wlength, "air", direction, f)
-	WriteWalkwayBox( -7, 3, -1,  7, 3, -wlength, "air", direction, f)
-	WriteWalkwayBox( -6, 4, -1,  6, 4, -wlength, "air", direction, f)
-	WriteWalkwayBox( -5, 5, -1,  5, 5, -wlength, "air", direction, f)
-	WriteWalkwayBox( -4, 6, -1,  4, 6, -wlength, "air", direction, f)
-	WriteWalkwayBox( -2, 7, -1,  2, 7, -wlength, "air", direction, f)
-	WriteWalkwayBox( -2, 8, -1,  2, 8, -wlength, "air", direction, f)
+	w.box( -8, 0, -1,  8, 0, -wlength, "air")
+	w.box( -8, 1, -1,  8, 1, -wlength, "air")
+	w.box( -7, 2, -1,  7, 2, -wlength, "air")
+	w.box( -7, 3, -1,  7, 3, -wlength, "air")
+	w.box( -6, 4, -1,  6, 4, -wlength, "air")
+	w.box( -5, 5, -1,  5, 5, -wlength, "air")
+	w.box( -4, 6, -1,  4, 6, -wlength, "air")
+	w.box( -2, 7, -1,  2, 7, -wlength, "air")
+	w.box( -2, 8, -1,  2, 8, -wlength, "air")
 
 	// Layer beneath the character.
-	WriteWalkwayBox( 0, -1, -1,  0, -1, -wlength, "gold_block", direction, f)
-	WriteWalkwayBox(-1, -1, -1, -1, -1, -wlength, "glowstone", direction, f)
-	WriteWalkwayBox( 1, -1, -1,  1, -1, -wlength, "glowstone", direction, f)
-	WriteWalkwayBox(-2, -1, -1, -2, -1, -wlength, "lapis_block", direction, f)
-	WriteWalkwayBox( 2, -1, -1,  2, -1, -wlength, "lapis_block", direction, f)
-	WriteWalkwayBox(-3, -1, -1, -3, -1, -wlength, "redstone_block", direction, f)
-	WriteWalkwayBox( 3, -1, -1,  3, -1, -wlength, "redstone_block", direction, f)
-	WriteWalkwayBox(-3,  0, -1, -3,  0, -wlength, "golden_rail", direction, f)
-	WriteWalkwayBox( 3,  0, -1,  3,  0, -wlength, "golden_rail", direction, f)
-	WriteWalkwayBox(-4, -1, -1, -4, -1, -wlength, "lapis_block", direction, f)
-	WriteWalkwayBox( 4, -1, -1,  4, -1, -wlength, "lapis_block", direction, f)
-	WriteWalkwayBox(-5, -1, -1, -5, -1, -wlength, "sea_lantern", direction, f)
-	WriteWalkwayBox( 5, -1, -1,  5, -1, -wlength, "sea_lantern", direction, f)
-	WriteWalkwayBox(-6, -1, -1, -8, -1, -wlength, "stone 4", direction, f)
-	WriteWalkwayBox( 6, -1, -1,  8, -1, -wlength, "stone 4", direction, f)
+	w.box( 0, -1, -1,  0, -1, -wlength, "gold_block")
+	w.box(-1, -1, -1, -1, -1, -wlength, "glowstone")
+	w.box( 1, -1, -1,  1, -1, -wlength, "glowstone")
+	w.box(-2, -1, -1, -2, -1, -wlength, "lapis_block")
+	w.box( 2, -1, -1,  2, -1, -wlength, "lapis_block")
+	w.box(-3, -1, -1, -3, -1, -wlength, "redstone_block")
+	w.box( 3, -1, -1,  3, -1, -wlength, "redstone_block")
+	w.box(-3,  0, -1, -3,  0, -wlength, "golden_rail")
+	w.box( 3,  0, -1,  3,  0, -wlength, "golden_rail")
+	w.box(-4, -1, -1, -4, -1, -wlength, "lapis_block")
+	w.box( 4, -1, -1,  4, -1, -wlength, "lapis_block")
+	w.box(-5, -1, -1, -5, -1, -wlength, "sea_lantern")
+	w.box( 5, -1, -1,  5, -1, -wlength, "sea_lantern")
+	w.box(-6, -1, -1, -8, -1, -wlength, "stone 4")
+	w.box( 6, -1, -1,  8, -1, -wlength, "stone 4")
 
 	// Work up the left and right sides.
-	WriteWalkwayBox(-8,  0, -1, -8,  1, -wlength, "glass",   direction, f)
-	WriteWalkwayBox( 8,  0, -1,  8,  1, -wlength, "glass",   direction, f)
-	WriteWalkwayBox(-7,  2, -1, -7,  3, -wlength, "glass",   direction, f)
-	WriteWalkwayBox( 7,  2, -1,  7,  3, -wlength, "glass",   direction, f)
-	WriteWalkwayBox(-6,  4, -1, -6,  4, -wlength, "stone 4", direction, f)
-	WriteWalkwayBox( 6,  4, -1,  6,  4, -wlength, "stone 4", direction, f)
-	WriteWalkwayBox(-5,  5, -1, -5,  5, -wlength, "stone 4", direction, f)
-	WriteWalkwayBox( 5,  5, -1,  5,  5, -wlength, "stone 4", direction, f)
-	WriteWalkwayBox(-4,  6, -1, -3,  6, -wlength, "stone 4", direction, f)
-	WriteWalkwayBox( 4,  6, -1,  3,  6, -wlength, "stone 4", direction, f)
-	WriteWalkwayBox(-2,  7, -1,  2,  7, -wlength, "stone 4", direction, f)
+	w.box(-8,  0, -1, -8,  1, -wlength, "glass")
+	w.box( 8,  0, -1,  8,  1, -wlength, "glass")
+	w.box(-7,  2, -1, -7,  3, -wlength, "glass")
+	w.box( 7,  2, -1,  7,  3, -wlength, "glass")
+	w.box(-6,  4, -1, -6,  4, -wlength, "stone 4")
+	w.box( 6,  4, -1,  6,  4, -wlength, "stone 4")
+	w.box(-5,  5, -1, -5,  5, -wlength, "stone 4")
+	w.box( 5,  5, -1,  5,  5, -wlength, "stone 4")
+	w.box(-4,  6, -1, -3,  6, -wlength, "stone 4")
+	w.box( 4,  6, -1,  3,  6, -wlength, "stone 4")
+	w.box(-2,  7, -1,  2,  7, -wlength, "stone 4")
 
 	// Place the chandeliers
 	for z := -3; z > -wlength; z -= 5 {
-		WriteWalkwayBox( 0, 6, z,   0,  6, z,   "fence", direction, f)
-		WriteWalkwayBox( 0, 5, z,   0,  5, z,   "fence", direction, f)
-		WriteWalkwayBox(-1, 5, z,   -1, 5, z,   "fence", direction, f)
-		WriteWalkwayBox( 1, 5, z,   1,  5, z,   "fence", direction, f)
-		WriteWalkwayBox( 0, 5, z-1, 0,  5, z-1, "fence", direction, f)
-		WriteWalkwayBox( 0, 5, z+1, 0,  5, z+1, "fence", direction, f)
-
-		WriteWalkwayBox(-1, 4, z,   -1, 4, z,   "glowstone", direction, f)
-		WriteWalkwayBox( 1, 4, z,    1, 4, z,   "glowstone", direction, f)
-		WriteWalkwayBox( 0, 4, z-1,  0, 4, z-1, "glowstone", direction, f)
-		WriteWalkwayBox( 0, 4, z+1,  0, 4, z+1, "glowstone", direction, f)
+		w.box( 0, 6, z,   0,  6, z,   "fence")
+		w.box( 0, 5, z,   0,  5, z,   "fence")
+		w.box(-1, 5, z,   -1, 5, z,   "fence")
+		w.box( 1, 5, z,   1,  5, z,   "fence")
+		w.box( 0, 5, z-1, 0,  5, z-1, "fence")
+		w.box( 0, 5, z+1, 0,  5, z+1, "fence")
+
+		w.box(-1, 4, z,   -1, 4, z,   "glowstone")
+		w.box( 1, 4, z,    1, 4, z,   "glowstone")
+		w.box( 0, 4, z-1,  0, 4, z-1, "glowstone")
+		w.box( 0, 4, z+1,  0, 4, z+1, "glowstone")
 	}
 
 	// Add a layer of sea lanterns above the walkway so it can be seen
 	// from the air.
-	WriteWalkwayBox( -2, 8, -1,  2, 8, -wlength, "sea_lantern", direction, f)
+	w.box( -2, 8, -1,  2, 8, -wlength, "sea_lantern")
 
-	return nil
+	return w.close()
 }
 
 
@@ -224,24 +267,24 @@ func CreateWalkwayCap(basepath string, filename_cap string,
 	direction string) error {
 
 	fname_cap := basepath + "/Walkway/" + filename_cap
-	f, err := os.OpenFile(fname_cap, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
+	f, err := CreateFunctionFile(basepath, "Walkway", filename_cap)
 	if err != nil {
 		return fmt.Errorf("CreateWalkwayCap open %v: %v", fname_cap, err)
 	}
-	defer f.Close()
-
-	WriteWalkwayBox( -8, -1, -1,  8, -1, -1, "stained_glass 5", direction, f)
-	WriteWalkwayBox( -8,  0, -1,  8,  0, -1, "stained_glass 5", direction, f)
-	WriteWalkwayBox( -8,  1, -1,  8,  1, -1, "stained_glass 5", direction, f)
-	WriteWalkwayBox( -7,  2, -1,  7,  2, -1, "stained_glass 5", direction, f)
-	WriteWalkwayBox( -7,  3, -1,  7,  3, -1, "stained_glass 5", direction, f)
-	WriteWalkwayBox( -6,  4, -1,  6,  4, -1, "stained_glass 5", direction, f)
-	WriteWalkwayBox( -5,  5, -1,  5,  5, -1, "stained_glass 5", direction, f)
-	WriteWalkwayBox( -4,  6, -1,  4,  6, -1, "stained_glass 5", direction, f)
-	WriteWalkwayBox( -2,  7, -1,  2,  7, -1, "stained_glass 5", direction, f)
-	WriteWalkwayBox( -2,  8, -1,  2,  8, -1, "stained_glass 5", direction, f)
-
-	return nil
+	w := &walkwayWriter{f: f, direction: direction}
+
+	w.box( -8, -1, -1,  8, -1, -1, "stained_glass 5")
+	w.box( -8,  0, -1,  8,  0, -1, "stained_glass 5")
+	w.box( -8,  1, -1,  8,  1, -1, "stained_glass 5")
+	w.box( -7,  2, -1,  7,  2, -1, "stained_glass 5")
+	w.box( -7,  3, -1,  7,  3, -1, "stained_glass 5")
+	w.box( -6,  4, -1,  6,  4, -1, "stained_glass 5")
+	w.box( -5,  5, -1,  5,  5, -1, "stained_glass 5")
+	w.box( -4,  6, -1,  4,  6, -1, "stained_glass 5")
+	w.box( -2,  7, -1,  2,  7, -1, "stained_glass 5")
+	w.box( -2,  8, -1,  2,  8, -1, "stained_glass 5")
+
+	return w.close()
 }
 
 
@@ -250,101 +293,101 @@ func CreateAngledWalkway(basepath string, filename string, nchunk int,
 	direction string) error {
 
 	fname := basepath + "/Walkway/" + filename
-	f, err := os.OpenFile(fname, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
+	f, err := CreateFunctionFile(basepath, "Walkway", filename)
 	if err != nil {
 		return fmt.Errorf("CreateWalkway open %v: %v", fname, err)
 	}
-	defer f.Close()
+	w := &walkwayWriter{f: f, direction: direction}
 
 	xt := 0
 	zt := 0
 	for nc := 0; nc < nchunk; nc++ {
 		xt = -nc*10
 		zt = xt
-		WriteAngledWalkwayPath( xt-1, -1, zt-1,  10, 8, "gold_block",     direction, "n", f)
-		WriteAngledWalkwayPath( xt-1, -1, zt-2,  10, 8, "gold_block",     direction, "y", f)
-		WriteAngledWalkwayPath( xt+0, -1, zt-2,  10, 8, "glowstone",      direction, "y", f)
-		WriteAngledWalkwayPath( xt+0, -1, zt-3,  10, 8, "glowstone",      direction, "y", f)
-		WriteAngledWalkwayPath( xt+1, -1, zt-3,  10, 8, "lapis_block",    direction, "y", f)
-		WriteAngledWalkwayPath( xt+1, -1, zt-4,  10, 8, "lapis_block",    direction, "y", f)
-		WriteAngledWalkwayPath( xt+2, -1, zt-4,  10, 6, "redstone_block", direction, "y", f)
-		WriteAngledWalkwayPath( xt+2, -1, zt-5,  10, 6, "redstone_block", direction, "y", f)
-		WriteAngledWalkwayPath( xt+3, -1, zt-5,  10, 6, "lapis_block",    direction, "y", f)
-		WriteAngledWalkwayPath( xt+3, -1, zt-6,  10, 6, "lapis_block",    direction, "y", f)
-		WriteAngledWalkwayPath( xt+4, -1, zt-6,  10, 5, "sea_lantern",    direction, "y", f)
-		WriteAngledWalkwayPath( xt+4, -1, zt-7,  10, 5, "sea_lantern",    direction, "y", f)
-		WriteAngledWalkwayPath( xt+5, -1, zt-7,  10, 4, "stone 4",        direction, "y", f)
-		WriteAngledWalkwayPath( xt+5, -1, zt-8,  10, 4, "stone 4",        direction, "y", f)
-		WriteAngledWalkwayPath( xt+6, -1, zt-8,  10, 3, "stone 4",        direction, "y", f)
-		WriteAngledWalkwayPath( xt+6, -1, zt-9,  10, 3, "stone 4",        direction, "y", f)
-		WriteAngledWalkwayPath( xt+7, -1, zt-9,  10, 1, "stone 4",        direction, "y", f)
-		WriteAngledWalkwayPath( xt+7,  0, zt-9,  10, 0, "glass",          direction, "y", f)
-		WriteAngledWalkwayPath( xt+7,  1, zt-9,  10, 0, "glass",          direction, "y", f)
-		WriteAngledWalkwayPath( xt+6,  2, zt-8,  10, 0, "glass",          direction, "y", f)
-		WriteAngledWalkwayPath( xt+6,  2, zt-9,  10, 0, "glass",          direction, "y", f)
-		WriteAngledWalkwayPath( xt+6,  3, zt-8,  10, 0, "glass",          direction, "y", f)
-		WriteAngledWalkwayPath( xt+6,  3, zt-9,  10, 0, "glass",          direction, "y", f)
-		WriteAngledWalkwayPath( xt+5,  4, zt-7,  10, 0, "stone 4",        direction, "y", f)
-		WriteAngledWalkwayPath( xt+5,  4, zt-8,  10, 0, "stone 4",        direction, "y", f)
-		WriteAngledWalkwayPath( xt+4,  5, zt-6,  10, 0, "stone 4",        direction, "y", f)
-		WriteAngledWalkwayPath( xt+4,  5, zt-7,  10, 0, "stone 4",        direction, "y", f)
-		WriteAngledWalkwayPath( xt+3,  6, zt-5,  10, 0, "stone 4",        direction, "y", f)
-		WriteAngledWalkwayPath( xt+3,  6, zt-6,  10, 0, "stone 4",        direction, "y", f)
-		WriteAngledWalkwayPath( xt+2,  6, zt-4,  10, 0, "stone 4",        direction, "y", f)
-		WriteAngledWalkwayPath( xt+2,  6, zt-5,  10, 0, "stone 4",        direction, "y", f)
-		WriteAngledWalkwayPath( xt+1,  7, zt-3,  10, 0, "stone 4",        direction, "y", f)
-		WriteAngledWalkwayPath( xt+1,  7, zt-4,  10, 0, "stone 4",        direction, "y", f)
-		WriteAngledWalkwayPath( xt+0,  7, zt-2,  10, 0, "stone 4",        direction, "y", f)
-		WriteAngledWalkwayPath( xt+0,  7, zt-3,  10, 0, "stone 4",        direction, "y", f)
-		WriteAngledWalkwayPath( xt-1,  7, zt-2,  10, 0, "stone 4",        direction, "y", f)
-		WriteAngledWalkwayPath( xt-1,  7, zt-1,  10, 0, "stone 4",        direction, "n", f)
-		WriteAngledWalkwayPath( xt+1,  8, zt-3,  10, 0, "sea_lantern",    direction, "y", f)
-		WriteAngledWalkwayPath( xt+1,  8, zt-4,  10, 0, "sea_lantern",    direction, "y", f)
-		WriteAngledWalkwayPath( xt+0,  8, zt-2,  10, 0, "sea_lantern",    direction, "y", f)
-		WriteAngledWalkwayPath( xt+0,  8, zt-3,  10, 0, "sea_lantern",    direction, "y", f)
-		WriteAngledWalkwayPath( xt-1,  8, zt-2,  10, 0, "sea_lantern",    direction, "y", f)
-		WriteAngledWalkwayPath( xt-1,  8, zt-1,  10, 0, "sea_lantern",    direction, "n", f)
+		w.path( xt-1, -1, zt-1,  10, 8, "gold_block",     "n")
+		w.path( xt-1, -1, zt-2,  10, 8, "gold_block",     "y")
+		w.path( xt+0, -1, zt-2,  10, 8, "glowstone",      "y")
+		w.path( xt+0, -1, zt-3,  10, 8, "glowstone",      "y")
+		w.path( xt+1, -1, zt-3,  10, 8, "lapis_block",    "y")
+		w.path( xt+1, -1, zt-4,  10, 8, "lapis_block",    "y")
+		w.path( xt+2, -1, zt-4,  10, 6, "redstone_block", "y")
+		w.path( xt+2, -1, zt-5,  10, 6, "redstone_block", "y")
+		w.path( xt+3, -1, zt-5,  10, 6, "lapis_block",    "y")
+		w.path( xt+3, -1, zt-6,  10, 6, "lapis_block",    "y")
+		w.path( xt+4, -1, zt-6,  10, 5, "sea_lantern",    "y")
+		w.path( xt+4, -1, zt-7,  10, 5, "sea_lantern",    "y")
+		w.path( xt+5, -1, zt-7,  10, 4, "stone 4",        "y")
+		w.path( xt+5, -1, zt-8,  10, 4, "stone 4",        "y")
+		w.path( xt+6, -1, zt-8,  10, 3, "stone 4",        "y")
+		w.path( xt+6, -1, zt-9,  10, 3, "stone 4",        "y")
+		w.path( xt+7, -1, zt-9,  10, 1, "stone 4",        "y")
+		w.path( xt+7,  0, zt-9,  10, 0, "glass",          "y")
+		w.path( xt+7,  1, zt-9,  10, 0, "glass",          "y")
+		w.path( xt+6,  2, zt-8,  10, 0, "glass",          "y")
+		w.path( xt+6,  2, zt-9,  10, 0, "glass",          "y")
+		w.path( xt+6,  3, zt-8,  10, 0, "glass",          "y")
+		w.path( xt+6,  3, zt-9,  10, 0, "glass",          "y")
+		w.path( xt+5,  4, zt-7,  10, 0, "stone 4",        "y")
+		w.path( xt+5,  4, zt-8,  10, 0, "stone 4",        "y")
+		w.path( xt+4,  5, zt-6,  10, 0, "stone 4",        "y")
+		w.path( xt+4,  5, zt-7,  10, 0, "stone 4",        "y")
+		w.path( xt+3,  6, zt-5,  10, 0, "stone 4",        "y")
+		w.path( xt+3,  6, zt-6,  10, 0, "stone 4",        "y")
+		w.path( xt+2,  6, zt-4,  10, 0, "stone 4",        "y")
+		w.path( xt+2,  6, zt-5,  10, 0, "stone 4",        "y")
+		w.path( xt+1,  7, zt-3,  10, 0, "stone 4",        "y")
+		w.path( xt+1,  7, zt-4,  10, 0, "stone 4",        "y")
+		w.path( xt+0,  7, zt-2,  10, 0, "stone 4",        "y")
+		w.path( xt+0,  7, zt-3,  10, 0, "stone 4",        "y")
+		w.path( xt-1,  7, zt-2,  10, 0, "stone 4",        "y")
+		w.path( xt-1,  7, zt-1,  10, 0, "stone 4",        "n")
+		w.path( xt+1,  8, zt-3,  10, 0, "sea_lantern",    "y")
+		w.path( xt+1,  8, zt-4,  10, 0, "sea_lantern",    "y")
+		w.path( xt+0,  8, zt-2,  10, 0, "sea_lantern",    "y")
+		w.path( xt+0,  8, zt-3,  10, 0, "sea_lantern",    "y")
+		w.path( xt-1,  8, zt-2,  10, 0, "sea_lantern",    "y")
+		w.path( xt-1,  8, zt-1,  10, 0, "sea_lantern",    "n")
 
 		// Add the rails to the redstone blocks.
-		WriteAngledWalkwayPath( xt+2,  0, zt-4,  10, 6, "rail",           direction, "y", f)
-		WriteAngledWalkwayPath( xt+2,  0, zt-5,  10, 6, "rail",           direction, "y", f)
+		w.path( xt+2,  0, zt-4,  10, 6, "rail",           "y")
+		w.path( xt+2,  0, zt-5,  10, 6, "rail",           "y")
 
-		WriteWalkwayBox(xt-7,  -1, zt-12,  xt-7,  -1, zt-12,  "redstone_block",  direction, f)
-		WriteWalkwayBox(xt-6,   0, zt-13,  xt-6,   0, zt-13,  "air",             direction, f)
-		WriteWalkwayBox(xt-6,   0, zt-12,  xt-6,   0, zt-12,  "golden_rail",     direction, f)
-		WriteWalkwayBox(xt-7,   0, zt-12,  xt-7,   0, zt-12,  "rail",            direction, f)
-		WriteWalkwayBox(xt-7,   0, zt-13,  xt-7,   0, zt-13,  "golden_rail",     direction, f)
+		w.box(xt-7,  -1, zt-12,  xt-7,  -1, zt-12,  "redstone_block")
+		w.box(xt-6,   0, zt-13,  xt-6,   0, zt-13,  "air")
+		w.box(xt-6,   0, zt-12,  xt-6,   0, zt-12,  "golden_rail")
+		w.box(xt-7,   0, zt-12,  xt-7,   0, zt-12,  "rail")
+		w.box(xt-7,   0, zt-13,  xt-7,   0, zt-13,  "golden_rail")
 
-		WriteWalkwayBox(zt-12,  -1, xt-7,  zt-12, -1, xt-7,   "redstone_block",  direction, f)
-		WriteWalkwayBox(zt-13,   0, xt-6,  zt-13,  0, xt-6,   "air",             direction, f)
-		WriteWalkwayBox(zt-12,   0, xt-6,  zt-12,  0, xt-6,   "golden_rail",     direction, f)
-		WriteWalkwayBox(zt-12,   0, xt-7,  zt-12,  0, xt-7,   "rail",            direction, f)
-		WriteWalkwayBox(zt-13,   0, xt-7,  zt-13,  0, xt-7,   "golden_rail",     direction, f)
+		w.box(zt-12,  -1, xt-7,  zt-12, -1, xt-7,   "redstone_block")
+		w.box(zt-13,   0, xt-6,  zt-13,  0, xt-6,   "air")
+		w.box(zt-12,   0, xt-6,  zt-12,  0, xt-6,   "golden_rail")
+		w.box(zt-12,   0, xt-7,  zt-12,  0, xt-7,   "rail")
+		w.box(zt-13,   0, xt-7,  zt-13,  0, xt-7,   "golden_rail")
 
 		// Place the chandeliers
 		for zc := -3; zc >= -8; zc -= 5 {
 			xc := zc
-			WriteWalkwayBox(xt+xc,   6, zt+zc,   xt+xc,   6, zt+zc,   "fence", direction, f)
-			WriteWalkwayBox(xt+xc,   5, zt+zc,   xt+xc,   5, zt+zc,   "fence", direction, f)
-			WriteWalkwayBox(xt+xc-1, 5, zt+zc,   xt+xc-1, 5, zt+zc,   "fence", direction, f)
-			WriteWalkwayBox(xt+xc+1, 5, zt+zc,   xt+xc+1, 5, zt+zc,   "fence", direction, f)
-			WriteWalkwayBox(xt+xc,   5, zt+zc-1, xt+xc,   5, zt+zc-1, "fence", direction, f)
-			WriteWalkwayBox(xt+xc,   5, zt+zc+1, xt+xc,   5, zt+zc+1, "fence", direction, f)
-
-			WriteWalkwayBox(xt+xc-1, 4, zt+zc,   xt+xc-1, 4, zt+zc,   "glowstone", direction, f)
-			WriteWalkwayBox(xt+xc+1, 4, zt+zc,   xt+xc+1, 4, zt+zc,   "glowstone", direction, f)
-			WriteWalkwayBox(xt+xc,   4, zt+zc-1, xt+xc,   4, zt+zc-1, "glowstone", direction, f)
-			WriteWalkwayBox(xt+xc,   4, zt+zc+1, xt+xc,   4, zt+zc+1, "glowstone", direction, f)
+			w.box(xt+xc,   6, zt+zc,   xt+xc,   6, zt+zc,   "fence")
+			w.box(xt+xc,   5, zt+zc,   xt+xc,   5, zt+zc,   "fence")
+			w.box(xt+xc-1, 5, zt+zc,   xt+xc-1, 5, zt+zc,   "fence")
+			w.box(xt+xc+1, 5, zt+zc,   xt+xc+1, 5, zt+zc,   "fence")
+			w.box(xt+xc,   5, zt+zc-1, xt+xc,   5, zt+zc-1, "fence")
+			w.box(xt+xc,   5, zt+zc+1, xt+xc,   5, zt+zc+1, "fence")
+
+			w.box(xt+xc-1, 4, zt+zc,   xt+xc-1, 4, zt+zc,   "glowstone")
+			w.box(xt+xc+1, 4, zt+zc,   xt+xc+1, 4, zt+zc,   "glowstone")
+			w.box(xt+xc,   4, zt+zc-1, xt+xc,   4, zt+zc-1, "glowstone")
+			w.box(xt+xc,   4, zt+zc+1, xt+xc,   4, zt+zc+1, "glowstone")
 		}
 	}
 
 
-	return nil
+	return w.close()
 }
 
 
 func WriteAngledWalkwayPath(xs int, ys int, zs int, nblocks int, ymax int,
-	block_type string, direction string, reflect string, f *os.File) error {
+	block_type string, direction string, reflect string, f io.Writer) error {
 
 	yv := ys
 	for n:=0; n < nblocks; n++ {
@@ -352,19 +395,31 @@ func WriteAngledWalkwayPath(xs int, ys int, zs int, nblocks int, ymax int,
 		z1 := zs - n
 		if yv == -1 {
 			for y := 0; y <= ymax; y++ {
-				WriteWalkwayBox( x1, y, z1, x1, y, z1, "air", direction, f)
+				err := WriteWalkwayBox(x1, y, z1, x1, y, z1, "air", direction, f)
+				if err != nil {
+					return err
+				}
 			}
 		}
-		WriteWalkwayBox( x1, yv, z1, x1, yv, z1, block_type, direction, f)
+		err := WriteWalkwayBox(x1, yv, z1, x1, yv, z1, block_type, direction, f)
+		if err != nil {
+			return err
+		}
 		if reflect == "y" {
 			z2 := x1
 			x2 := z1
 			if yv == -1 {
 				for y := 0; y <= ymax; y++ {
-					WriteWalkwayBox( x2, y, z2, x2, y, z2, "air", direction, f)
+					err := WriteWalkwayBox(x2, y, z2, x2, y, z2, "air", direction, f)
+					if err != nil {
+						return err
+					}
 				}
 			}
-			WriteWalkwayBox( x2, yv, z2, x2, yv, z2, block_type, direction, f)
+			err = WriteWalkwayBox(x2, yv, z2, x2, yv, z2, block_type, direction, f)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
@@ -386,24 +441,24 @@ func RmWalkway(basepath string, filename string, direction string,
 	wlength int) error {
 
 	fname := basepath + "/Walkway/" + filename
-	f, err := os.OpenFile(fname, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
+	f, err := CreateFunctionFile(basepath, "Walkway", filename)
 	if err != nil {
 		return fmt.Errorf("CreateWalkway open %v: %v", fname, err)
 	}
-	defer f.Close()
-
-	WriteWalkwayBox( -8, -1, -1,  8, -1, -wlength, "dirt", direction, f)
-	WriteWalkwayBox( -8,  0, -1,  8,  0, -wlength, "air", direction, f)
-	WriteWalkwayBox( -8,  1, -1,  8,  1, -wlength, "air", direction, f)
-	WriteWalkwayBox( -7,  2, -1,  7,  2, -wlength, "air", direction, f)
-	WriteWalkwayBox( -7,  3, -1,  7,  3, -wlength, "air", direction, f)
-	WriteWalkwayBox( -6,  4, -1,  6,  4, -wlength, "air", direction, f)
-	WriteWalkwayBox( -5,  5, -1,  5,  5, -wlength, "air", direction, f)
-	WriteWalkwayBox( -4,  6, -1,  4,  6, -wlength, "air", direction, f)
-	WriteWalkwayBox( -2,  7, -1,  2,  7, -wlength, "air", direction, f)
-	WriteWalkwayBox( -2,  8, -1,  2,  8, -wlength, "air", direction, f)
-
-	return nil
+	w := &walkwayWriter{f: f, direction: direction}
+
+	w.box( -8, -1, -1,  8, -1, -wlength, "dirt")
+	w.box( -8,  0, -1,  8,  0, -wlength, "air")
+	w.box( -8,  1, -1,  8,  1, -wlength, "air")
+	w.box( -7,  2, -1,  7,  2, -wlength, "air")
+	w.box( -7,  3, -1,  7,  3, -wlength, "air")
+	w.box( -6,  4, -1,  6,  4, -wlength, "air")
+	w.box( -5,  5, -1,  5,  5, -wlength, "air")
+	w.box( -4,  6, -1,  4,  6, -wlength, "air")
+	w.box( -2,  7, -1,  2,  7, -wlength, "air")
+	w.box( -2,  8, -1,  2,  8, -wlength, "air")
+
+	return w.close()
 }
 
 
@@ -412,51 +467,57 @@ func RmAngledWalkway(basepath string, filename string, nchunk int,
 	direction string) error {
 
 	fname := basepath + "/Walkway/" + filename
-	f, err := os.OpenFile(fname, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
+	f, err := CreateFunctionFile(basepath, "Walkway", filename)
 	if err != nil {
 		return fmt.Errorf("CreateWalkway open %v: %v", fname, err)
 	}
-	defer f.Close()
+	w := &walkwayWriter{f: f, direction: direction}
 
 	xt := 0
 	zt := 0
 	for nc := 0; nc < nchunk; nc++ {
 		xt = -nc*10
 		zt = xt
-		RmAngledWalkwayPath( xt-1, -1, zt-1,  10, 8, direction, "n", f)
-		RmAngledWalkwayPath( xt-1, -1, zt-2,  10, 8, direction, "y", f)
-		RmAngledWalkwayPath( xt+0, -1, zt-2,  10, 8, direction, "y", f)
-		RmAngledWalkwayPath( xt+0, -1, zt-3,  10, 8, direction, "y", f)
-		RmAngledWalkwayPath( xt+1, -1, zt-3,  10, 8, direction, "y", f)
-		RmAngledWalkwayPath( xt+1, -1, zt-4,  10, 8, direction, "y", f)
-		RmAngledWalkwayPath( xt+2, -1, zt-4,  10, 6, direction, "y", f)
-		RmAngledWalkwayPath( xt+2, -1, zt-5,  10, 6, direction, "y", f)
-		RmAngledWalkwayPath( xt+3, -1, zt-5,  10, 6, direction, "y", f)
-		RmAngledWalkwayPath( xt+3, -1, zt-6,  10, 6, direction, "y", f)
-		RmAngledWalkwayPath( xt+4, -1, zt-6,  10, 5, direction, "y", f)
-		RmAngledWalkwayPath( xt+4, -1, zt-7,  10, 5, direction, "y", f)
-		RmAngledWalkwayPath( xt+5, -1, zt-7,  10, 4, direction, "y", f)
-		RmAngledWalkwayPath( xt+5, -1, zt-8,  10, 4, direction, "y", f)
-		RmAngledWalkwayPath( xt+6, -1, zt-8,  10, 3, direction, "y", f)
-		RmAngledWalkwayPath( xt+6, -1, zt-9,  10, 3, direction, "y", f)
-		RmAngledWalkwayPath( xt+7, -1, zt-9,  10, 1, direction, "y", f)
+		w.rmPath( xt-1, -1, zt-1,  10, 8, "n")
+		w.rmPath( xt-1, -1, zt-2,  10, 8, "y")
+		w.rmPath( xt+0, -1, zt-2,  10, 8, "y")
+		w.rmPath( xt+0, -1, zt-3,  10, 8, "y")
+		w.rmPath( xt+1, -1, zt-3,  10, 8, "y")
+		w.rmPath( xt+1, -1, zt-4,  10, 8, "y")
+		w.rmPath( xt+2, -1, zt-4,  10, 6, "y")
+		w.rmPath( xt+2, -1, zt-5,  10, 6, "y")
+		w.rmPath( xt+3, -1, zt-5,  10, 6, "y")
+		w.rmPath( xt+3, -1, zt-6,  10, 6, "y")
+		w.rmPath( xt+4, -1, zt-6,  10, 5, "y")
+		w.rmPath( xt+4, -1, zt-7,  10, 5, "y")
+		w.rmPath( xt+5, -1, zt-7,  10, 4, "y")
+		w.rmPath( xt+5, -1, zt-8,  10, 4, "y")
+		w.rmPath( xt+6, -1, zt-8,  10, 3, "y")
+		w.rmPath( xt+6, -1, zt-9,  10, 3, "y")
+		w.rmPath( xt+7, -1, zt-9,  10, 1, "y")
 	}
 
-	return nil
+	return w.close()
 }
 
 
 func RmAngledWalkwayPath(xs int, ys int, zs int, nblocks int, ymax int,
-	direction string, reflect string, f *os.File) error {
+	direction string, reflect string, f io.Writer) error {
 
 	yv := ys
 	for n:=0; n < nblocks; n++ {
 		x1 := xs - n
 		z1 := zs - n
 		if yv == -1 {
-			WriteWalkwayBox( x1, -1, z1, x1, -1, z1, "dirt", direction, f)
+			err := WriteWalkwayBox(x1, -1, z1, x1, -1, z1, "dirt", direction, f)
+			if err != nil {
+				return err
+			}
 			for y := 0; y <= ymax; y++ {
-				WriteWalkwayBox( x1, y, z1, x1, y, z1, "air", direction, f)
+				err := WriteWalkwayBox(x1, y, z1, x1, y, z1, "air", direction, f)
+				if err != nil {
+					return err
+				}
 			}
 		}
 
@@ -464,9 +525,15 @@ func RmAngledWalkwayPath(xs int, ys int, zs int, nblocks int, ymax int,
 			z2 := x1
 			x2 := z1
 			if yv == -1 {
-				WriteWalkwayBox( x2, -1, z2, x2, -1, z2, "dirt", direction, f)
+				err := WriteWalkwayBox(x2, -1, z2, x2, -1, z2, "dirt", direction, f)
+				if err != nil {
+					return err
+				}
 				for y := 0; y <= ymax; y++ {
-					WriteWalkwayBox( x2, y, z2, x2, y, z2, "air", direction, f)
+					err := WriteWalkwayBox(x2, y, z2, x2, y, z2, "air", direction, f)
+					if err != nil {
+						return err
+					}
 				}
 			}
 		}
@@ -482,10 +549,49 @@ func RmAngledWalkwayPath(xs int, ys int, zs int, nblocks int, ymax int,
 //**************************************************************************************************
 //**************************************************************************************************
 
+// walkwayWriter writes the boxes of one walkway function in one direction. It keeps the first
+// error so the many boxes of a walkway are checked once, when the function is closed.
+type walkwayWriter struct {
+	f         *mcfdFunctionFile
+	direction string
+	err       error
+}
+
+// box writes a box, see WriteWalkwayBox
+func (w *walkwayWriter) box(x1 int, y1 int, z1 int, x2 int, y2 int, z2 int, block_type string) {
+	if w.err == nil {
+		w.err = WriteWalkwayBox(x1, y1, z1, x2, y2, z2, block_type, w.direction, w.f)
+	}
+}
+
+// path writes a path of an angled walkway, see WriteAngledWalkwayPath
+func (w *walkwayWriter) path(xs int, ys int, zs int, nblocks int, ymax int, block_type string,
+	reflect string) {
+	if w.err == nil {
+		w.err = WriteAngledWalkwayPath(xs, ys, zs, nblocks, ymax, block_type, w.direction,
+			reflect, w.f)
+	}
+}
+
+// rmPath removes a path of an angled walkway, see RmAngledWalkwayPath
+func (w *walkwayWriter) rmPath(xs int, ys int, zs int, nblocks int, ymax int, reflect string) {
+	if w.err == nil {
+		w.err = RmAngledWalkwayPath(xs, ys, zs, nblocks, ymax, w.direction, reflect, w.f)
+	}
+}
+
+// close writes the function file, unless writing a box failed
+func (w *walkwayWriter) close() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.f.Close()
+}
+
 
 // WriteWalkwayBox writes out a low level box for the walkway.
 func WriteWalkwayBox(x1 int, y1 int, z1 int, x2 int, y2 int, z2 int,
-	block_type string, direction string, f *os.File) error {
+	block_type string, direction string, f io.Writer) error {
 
 	corner1 := mcshapes.XYZ{X: x1, Y: y1, Z: z1}
 	corner2 := mcshapes.XYZ{X: x2, Y: y2, Z: z2}
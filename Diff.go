@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+	"github.com/olekukonko/tablewriter"
+)
+
+//**************************************************************************************************
+//**************************************************************************************************
+// Comparing two sets of generated functions
+//
+//    mcFunctionDev diff [-patch <file or dir>] <old> <new>
+//
+// Looking at the fill commands does not tell what changes in the game, a small change to a
+// generator moves and splits the commands around. Instead both functions are run into block
+// models, see ReadFunctionModel, and the models are compared block by block.
+// old and new are two function files or two directories of function files, e.g. a copy of
+// the Walkway directory from before a change and the Walkway directory after it. Functions
+// in directories are matched by name.
+//
+// For every function that changed the blocks added, removed and changed are listed for each
+// type of block, with the boxes they are in. With -patch a function is written that changes
+// what the old function built into what the new function builds, without building the rest
+// again. Removed blocks are replaced with air, whatever was there before the old function
+// was run is not known.
+//**************************************************************************************************
+//**************************************************************************************************
+
+// The sub-functions of a split function, e.g. s_glass_50_003.mcfunction
+var partFunction = regexp.MustCompile(`_[0-9]{3}\.mcfunction$`)
+
+// Most boxes listed for one type of block in one function.
+const maxDiffBoxes = 8
+
+// DiffCommand runs the diff command with the arguments after "diff".
+func DiffCommand(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	patch := flags.String("patch", "",
+		"write a function (a directory of functions) that changes old into new")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mcFunctionDev diff [-patch <file or dir>] <old> <new>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return fmt.Errorf("diff needs two function files or two directories")
+	}
+	oldPath, newPath := flags.Arg(0), flags.Arg(1)
+
+	oldInfo, err := os.Stat(oldPath)
+	if err != nil {
+		return err
+	}
+	newInfo, err := os.Stat(newPath)
+	if err != nil {
+		return err
+	}
+	if oldInfo.IsDir() != newInfo.IsDir() {
+		return fmt.Errorf("diff %v %v: cannot compare a file and a directory", oldPath, newPath)
+	}
+
+	if !oldInfo.IsDir() {
+		a, err := ReadFunctionModel(oldPath)
+		if err != nil {
+			return err
+		}
+		b, err := ReadFunctionModel(newPath)
+		if err != nil {
+			return err
+		}
+		if !DiffFunction(path.Base(newPath), a, b) {
+			fmt.Println("No changes")
+			return nil
+		}
+		if *patch != "" {
+			return WritePatchFunction(*patch, a, b)
+		}
+		return nil
+	}
+
+	// Functions in either directory, by their path in the directory
+	oldFiles, err := functionFiles(oldPath)
+	if err != nil {
+		return err
+	}
+	newFiles, err := functionFiles(newPath)
+	if err != nil {
+		return err
+	}
+	names := make(map[string]bool)
+	for _, name := range append(oldFiles, newFiles...) {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	nchanged := 0
+	for _, name := range sorted {
+		a, err := readFunctionModelIfExists(path.Join(oldPath, name))
+		if err != nil {
+			return err
+		}
+		b, err := readFunctionModelIfExists(path.Join(newPath, name))
+		if err != nil {
+			return err
+		}
+		if !DiffFunction(name, a, b) {
+			continue
+		}
+		nchanged++
+		if *patch != "" {
+			err = WritePatchFunction(path.Join(*patch, name), a, b)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	fmt.Printf("\n%d of %d functions changed\n", nchanged, len(sorted))
+	return nil
+}
+
+// DiffFunction prints the differences between the blocks built by two versions of a
+// function. It reports whether there are any.
+func DiffFunction(name string, a *mcshapes.Model, b *mcshapes.Model) bool {
+	changes := mcshapes.Diff(a, b)
+	if len(changes) == 0 {
+		return false
+	}
+
+	// Group the changes by what changed, e.g. "added" "minecraft:water"
+	type group struct {
+		change string
+		block  string
+	}
+	groups := make(map[group]*mcshapes.Model)
+	nchanges := make(map[string]int)
+	for _, c := range changes {
+		g := group{change: "changed",
+			block: mcshapes.BlockString(c.Old) + " -> " + mcshapes.BlockString(c.New)}
+		switch {
+		case c.Old == "":
+			g = group{change: "added", block: mcshapes.BlockString(c.New)}
+		case c.New == "":
+			g = group{change: "removed", block: mcshapes.BlockString(c.Old)}
+		}
+		if groups[g] == nil {
+			groups[g] = mcshapes.NewModel()
+		}
+		groups[g].SetBlock(c.XYZ, g.block)
+		nchanges[g.change]++
+	}
+	keys := make([]group, 0, len(groups))
+	for g := range groups {
+		keys = append(keys, g)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].change != keys[j].change {
+			return keys[i].change < keys[j].change
+		}
+		return keys[i].block < keys[j].block
+	})
+
+	fmt.Printf("\n%v: %d added, %d removed, %d changed\n", name,
+		nchanges["added"], nchanges["removed"], nchanges["changed"])
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Change", "Block", "Blocks", "Where"})
+	table.SetAutoWrapText(false)
+	for _, g := range keys {
+		boxes := mcshapes.NewStructure(mcshapes.WithModel(groups[g])).Boxes()
+		var where []string
+		for i, box := range boxes {
+			if i == maxDiffBoxes {
+				where = append(where, fmt.Sprintf("... %d more", len(boxes)-i))
+				break
+			}
+			c1, c2 := box.Corners()
+			if c1 == c2 {
+				where = append(where, fmt.Sprintf("~%d ~%d ~%d", c1.X, c1.Y, c1.Z))
+			} else {
+				where = append(where, fmt.Sprintf("~%d ~%d ~%d to ~%d ~%d ~%d",
+					c1.X, c1.Y, c1.Z, c2.X, c2.Y, c2.Z))
+			}
+		}
+		table.Append([]string{g.change, g.block, fmt.Sprintf("%d", groups[g].Len()),
+			strings.Join(where, "\n")})
+	}
+	table.Render()
+	return true
+}
+
+// WritePatchFunction writes the function fname that changes what function a builds into
+// what function b builds. Only the blocks that are different are placed.
+func WritePatchFunction(fname string, a *mcshapes.Model, b *mcshapes.Model) error {
+	m := mcshapes.NewModel()
+	for _, c := range mcshapes.Diff(a, b) {
+		if c.New == "" {
+			m.SetBlock(c.XYZ, "minecraft:air")
+		} else {
+			m.SetBlock(c.XYZ, c.New)
+		}
+	}
+
+	var buf bytes.Buffer
+	err := mcshapes.NewStructure(mcshapes.WithModel(m)).WriteShape(&buf)
+	if err != nil {
+		return fmt.Errorf("WritePatchFunction %v: %v", fname, err)
+	}
+	if err := os.MkdirAll(path.Dir(fname), 0755); err != nil {
+		return fmt.Errorf("WritePatchFunction mkdir: %v", err)
+	}
+	if err := writeFile(fname, buf.Bytes()); err != nil {
+		return fmt.Errorf("WritePatchFunction: %v", err)
+	}
+	fmt.Printf("Patch function %v\n", fname)
+	return nil
+}
+
+// functionFiles returns the paths, relative to dir, of the function files in dir and below.
+// Sub-functions of split functions are left out, they are part of the function calling them.
+func functionFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(p, ".mcfunction") {
+			return err
+		}
+		if partFunction.MatchString(p) {
+			parent := partFunction.ReplaceAllString(p, ".mcfunction")
+			if _, err := os.Stat(parent); err == nil {
+				return nil
+			}
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	return files, err
+}
+
+// readFunctionModelIfExists is ReadFunctionModel, with an empty model for a function that
+// does not exist.
+func readFunctionModelIfExists(fname string) (*mcshapes.Model, error) {
+	if _, err := os.Stat(fname); os.IsNotExist(err) {
+		return mcshapes.NewModel(), nil
+	}
+	return ReadFunctionModel(fname)
+}
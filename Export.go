@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	mcnbt "github.com/GreenSeaTurtle/mcFunctionDev/mcNBT"
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+	mcview "github.com/GreenSeaTurtle/mcFunctionDev/mcView"
+)
+
+//**************************************************************************************************
+//**************************************************************************************************
+// Exporting the generated objects to other file formats
+//
+// Every function written by a generator can also be written in other file formats. The
+// commands of the function, the same commands that are given to mcrender for the STL files,
+// are run into a block model, mcshapes.Model, and the model is written out. This works the
+// same for falls, walls, signs, spheres, walkways, ...
+// Functions that only place air, the _rm functions for example, are not exported.
+//
+// Formats
+//    nbt     Vanilla structure file in the structures directory of the datapack, placed in the
+//            game with a structure block or /place template
+//    schem   Sponge schematic in ExportDir/<generator>, for WorldEdit and other editors. It is
+//            pasted at the player position the same as the function builds it.
+//    litematic
+//            Litematica file in ExportDir/<generator>, shown in the game as a guide for
+//            building by hand. Copy it to the .minecraft/schematics directory.
+//    obj     Wavefront OBJ and MTL files in ExportDir/<generator>, a colored mesh for 3D
+//            viewers, one material per block type. See mcview.Mesh.
+//    gltf    Binary glTF file, .glb, in ExportDir/<generator>, the same mesh in one file
+//    stl     One STL file per block type in ExportDir/<generator>, <function>_<block>.stl, for
+//            printing each block type in its own color. The blocks are ExportPrintScale mm.
+//    3mf     One 3MF file in ExportDir/<generator> with an object per block type in the
+//            color of the block, for multi-material printers. The blocks are ExportPrintScale mm.
+//    html    Web page in ExportDir/<generator> with the blocks in 3D, turned with the mouse,
+//            with a Y slider and the block under the mouse. It is one file that works
+//            without a network, for build proposals. See mcview.WriteHTML.
+// ExportPrintHollow takes out the inside of the stl and 3mf prints, see mcview.Hollow.
+// The colors of the obj, gltf, stl, 3mf and html files are the colors of the preview
+// images, so PreviewColor changes them too.
+//**************************************************************************************************
+//**************************************************************************************************
+
+// Structure for using TOML to extract input from the user.
+//    ExportFormats           File formats to write for every function, e.g. ["nbt", "schem",
+//                            "litematic", "obj", "gltf", "stl", "3mf", "html"]
+//    ExportDataVersion       Minecraft data version written to the files, default 3465 (1.20.1)
+//    ExportDir               Directory for the files that do not go in the datapack,
+//                            default "exports"
+//    ExportSchematicVersion  Sponge schematic version, 2 or 3 (default). WorldEdit before 7.3
+//                            only reads version 2.
+//    ExportPrintScale        Size of a block in mm in the stl and 3mf files, default 2
+//    ExportPrintHollow       Walls in blocks of the stl and 3mf prints with the inside taken
+//                            out, default 0 for solid prints
+//    ExportPrintDrainHoles   Hollow prints get a hole from the bottom of the inside, so resin
+//                            or support material can get out
+type mcfdExportInputStruct struct {
+	ExportFormats          []string `toml:"ExportFormats"`
+	ExportDataVersion      int      `toml:"ExportDataVersion"`
+	ExportDir              string   `toml:"ExportDir"`
+	ExportSchematicVersion int      `toml:"ExportSchematicVersion"`
+	ExportPrintScale       float64  `toml:"ExportPrintScale"`
+	ExportPrintHollow      int      `toml:"ExportPrintHollow"`
+	ExportPrintDrainHoles  bool     `toml:"ExportPrintDrainHoles"`
+}
+
+// Options for exporting, read from the user input file by ReadExportOptions.
+var exportOptions = mcfdExportInputStruct{
+	ExportDataVersion:      mcnbt.DefaultDataVersion,
+	ExportDir:              "exports",
+	ExportSchematicVersion: 3,
+	ExportPrintScale:       2,
+}
+
+// ReadExportOptions reads the export options from the user input file.
+func ReadExportOptions(inputFile string) error {
+	var mcfdInput mcfdExportInputStruct
+	if _, err := toml.DecodeFile(inputFile, &mcfdInput); err != nil {
+		return err
+	}
+	for _, format := range mcfdInput.ExportFormats {
+		switch format {
+		case "nbt", "schem", "litematic", "obj", "gltf", "stl", "3mf", "html":
+		default:
+			return fmt.Errorf("ExportFormats: unknown format %q", format)
+		}
+	}
+	if mcfdInput.ExportDataVersion <= 0 {
+		mcfdInput.ExportDataVersion = mcnbt.DefaultDataVersion
+	}
+	if mcfdInput.ExportDir == "" {
+		mcfdInput.ExportDir = "exports"
+	}
+	switch mcfdInput.ExportSchematicVersion {
+	case 0:
+		mcfdInput.ExportSchematicVersion = 3
+	case 2, 3:
+	default:
+		return fmt.Errorf("ExportSchematicVersion must be 2 or 3, not %d",
+			mcfdInput.ExportSchematicVersion)
+	}
+	if mcfdInput.ExportPrintScale < 0 || mcfdInput.ExportPrintHollow < 0 {
+		return fmt.Errorf("ExportPrintScale and ExportPrintHollow can not be negative")
+	}
+	if mcfdInput.ExportPrintScale == 0 {
+		mcfdInput.ExportPrintScale = 2
+	}
+	exportOptions = mcfdInput
+	return nil
+}
+
+// ExportFunction writes the blocks placed by the commands of a function in all the export
+// formats.
+func ExportFunction(f *mcfdFunctionFile) error {
+	if len(exportOptions.ExportFormats) == 0 {
+		return nil
+	}
+	m, err := f.model()
+	if err != nil {
+		return fmt.Errorf("export %v", err)
+	} else if m == nil {
+		return nil
+	}
+
+	basepath, dir := f.basepath, f.dir
+	base := strings.TrimSuffix(f.filename, ".mcfunction")
+	for _, format := range exportOptions.ExportFormats {
+		switch format {
+		case "nbt":
+			err = ExportStructure(basepath, dir, base, m)
+		case "schem":
+			err = ExportSchematic(dir, base, m)
+		case "litematic":
+			err = ExportLitematic(dir, base, m)
+		case "obj":
+			err = ExportOBJ(dir, base, m)
+		case "gltf":
+			err = ExportGLTF(dir, base, m)
+		case "stl":
+			err = ExportSTL(dir, base, m)
+		case "3mf":
+			err = Export3MF(dir, base, m)
+		case "html":
+			err = ExportHTML(dir, base, m)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportStructure writes a model as a vanilla structure file,
+//    data/<namespace>/structures/<dir>/<base>.nbt
+// The game only accepts lower case structure names, so the path is in lower case, e.g.
+// Falls/waterfall_NWE_10_7 is placed with /place template mcfd:falls/waterfall_nwe_10_7
+func ExportStructure(basepath string, dir string, base string, m *mcshapes.Model) error {
+	// Minecraft 1.21 renamed "functions" to "function" and "structures" to "structure".
+	sdir := "structures"
+	if path.Base(basepath) == "function" {
+		sdir = "structure"
+	}
+	sdir = path.Join(path.Dir(basepath), sdir, strings.ToLower(dir))
+	if err := os.MkdirAll(sdir, 0755); err != nil {
+		return fmt.Errorf("ExportStructure mkdir %v: %v", sdir, err)
+	}
+
+	fname := path.Join(sdir, strings.ToLower(base)+".nbt")
+	var buf bytes.Buffer
+	err := mcnbt.WriteStructure(&buf, m, exportOptions.ExportDataVersion)
+	if err != nil {
+		return fmt.Errorf("ExportStructure write %v: %v", fname, err)
+	}
+	return writeFile(fname, buf.Bytes())
+}
+
+// ExportSchematic writes a model as a Sponge schematic, ExportDir/<dir>/<base>.schem
+func ExportSchematic(dir string, base string, m *mcshapes.Model) error {
+	f, err := createExportFile(dir, base+".schem")
+	if err != nil {
+		return fmt.Errorf("ExportSchematic: %v", err)
+	}
+
+	err = mcnbt.WriteSchematic(f, m, exportOptions.ExportDataVersion,
+		exportOptions.ExportSchematicVersion)
+	if err != nil {
+		return fmt.Errorf("ExportSchematic write %v: %v", f.Name(), err)
+	}
+	return f.Close()
+}
+
+// ExportLitematic writes a model as a Litematica file, ExportDir/<dir>/<base>.litematic
+func ExportLitematic(dir string, base string, m *mcshapes.Model) error {
+	f, err := createExportFile(dir, base+".litematic")
+	if err != nil {
+		return fmt.Errorf("ExportLitematic: %v", err)
+	}
+
+	err = mcnbt.WriteLitematic(f, m, base, exportOptions.ExportDataVersion)
+	if err != nil {
+		return fmt.Errorf("ExportLitematic write %v: %v", f.Name(), err)
+	}
+	return f.Close()
+}
+
+// ExportOBJ writes a model as a colored mesh, ExportDir/<dir>/<base>.obj with the materials
+// in ExportDir/<dir>/<base>.mtl
+func ExportOBJ(dir string, base string, m *mcshapes.Model) error {
+	mesh := mcview.NewMesh(m, mcview.WithMeshPalette(previewPalette))
+
+	f, err := createExportFile(dir, base+".mtl")
+	if err != nil {
+		return fmt.Errorf("ExportOBJ: %v", err)
+	}
+	if err = mesh.WriteMTL(f); err != nil {
+		return fmt.Errorf("ExportOBJ write %v: %v", f.Name(), err)
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	f, err = createExportFile(dir, base+".obj")
+	if err != nil {
+		return fmt.Errorf("ExportOBJ: %v", err)
+	}
+	if err = mesh.WriteOBJ(f, base, base+".mtl"); err != nil {
+		return fmt.Errorf("ExportOBJ write %v: %v", f.Name(), err)
+	}
+	return f.Close()
+}
+
+// ExportGLTF writes a model as a colored mesh in binary glTF, ExportDir/<dir>/<base>.glb
+func ExportGLTF(dir string, base string, m *mcshapes.Model) error {
+	f, err := createExportFile(dir, base+".glb")
+	if err != nil {
+		return fmt.Errorf("ExportGLTF: %v", err)
+	}
+
+	mesh := mcview.NewMesh(m, mcview.WithMeshPalette(previewPalette))
+	if err = mesh.WriteGLB(f, base); err != nil {
+		return fmt.Errorf("ExportGLTF write %v: %v", f.Name(), err)
+	}
+	return f.Close()
+}
+
+// ExportSTL writes a model for 3D printing as one STL file per block type,
+// ExportDir/<dir>/<base>_<block>.stl, e.g. Sign7/sign_a_lapis_block.stl
+func ExportSTL(dir string, base string, m *mcshapes.Model) error {
+	mesh := printMesh(m)
+	for _, block := range mesh.Materials() {
+		name := strings.TrimPrefix(block, "minecraft:")
+		f, err := createExportFile(dir, base+"_"+strings.Replace(name, ":", "_", -1)+".stl")
+		if err != nil {
+			return fmt.Errorf("ExportSTL: %v", err)
+		}
+		err = mesh.WriteSTL(f, block, exportOptions.ExportPrintScale)
+		if err != nil {
+			return fmt.Errorf("ExportSTL write %v: %v", f.Name(), err)
+		}
+		if err = f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Export3MF writes a model for 3D printing as a 3MF file, ExportDir/<dir>/<base>.3mf
+func Export3MF(dir string, base string, m *mcshapes.Model) error {
+	f, err := createExportFile(dir, base+".3mf")
+	if err != nil {
+		return fmt.Errorf("Export3MF: %v", err)
+	}
+
+	if err = printMesh(m).Write3MF(f, base, exportOptions.ExportPrintScale); err != nil {
+		return fmt.Errorf("Export3MF write %v: %v", f.Name(), err)
+	}
+	return f.Close()
+}
+
+// ExportHTML writes a model as a web page with a 3D view, ExportDir/<dir>/<base>.html
+func ExportHTML(dir string, base string, m *mcshapes.Model) error {
+	f, err := createExportFile(dir, base+".html")
+	if err != nil {
+		return fmt.Errorf("ExportHTML: %v", err)
+	}
+
+	if err = mcview.WriteHTML(f, m, base, previewPalette); err != nil {
+		return fmt.Errorf("ExportHTML write %v: %v", f.Name(), err)
+	}
+	return f.Close()
+}
+
+// printMesh returns the mesh of a model for 3D printing, hollow if ExportPrintHollow is set
+func printMesh(m *mcshapes.Model) *mcview.Mesh {
+	if exportOptions.ExportPrintHollow > 0 {
+		m = mcview.Hollow(m, exportOptions.ExportPrintHollow, exportOptions.ExportPrintDrainHoles)
+	}
+	return mcview.NewMesh(m, mcview.WithMeshSolids(), mcview.WithMeshPalette(previewPalette))
+}
+
+// exportFile is a file in ExportDir. It is written with writeFile when it is closed, so the
+// exports are only replaced when all the generators succeed, see StageFiles. Nothing is
+// written if it is not closed.
+type exportFile struct {
+	bytes.Buffer
+	name string
+}
+
+// Name returns the path of the file
+func (f *exportFile) Name() string {
+	return f.name
+}
+
+// Close writes the file
+func (f *exportFile) Close() error {
+	return writeFile(f.name, f.Bytes())
+}
+
+// createExportFile returns the file ExportDir/dir/filename, and creates the directories if
+// needed.
+func createExportFile(dir string, filename string) (*exportFile, error) {
+	edir := path.Join(exportOptions.ExportDir, dir)
+	if err := os.MkdirAll(edir, 0755); err != nil {
+		return nil, err
+	}
+	return &exportFile{name: path.Join(edir, filename)}, nil
+}
+
+// placesBlocks reports whether any fill or setblock command places something other than air.
+// This is much cheaper than building the model of a large clear volume.
+func placesBlocks(commands []byte) bool {
+	for _, line := range strings.Split(string(commands), "\n") {
+		c, err := mcshapes.ParseCommand(line)
+		if err == nil && !mcshapes.IsAir(c.Box.Surface()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,581 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+	"github.com/benmcclelland/mcrender"
+)
+
+//**************************************************************************************************
+//**************************************************************************************************
+// Writing Minecraft function files
+//
+// The generators do not write their function files directly. The commands are collected and
+// written when the function is closed. This gives one place to deal with functions that are
+// too long for the game.
+//
+// Minecraft only runs maxCommandChainLength commands (gamerule, default 65536) for one function
+// call and silently drops the rest. A large sphere easily has more commands than that. With
+// FunctionChunkSchedule such a function is split into numbered sub-functions that are run one
+// game tick apart,
+//    s_glass_50.mcfunction       schedule function mcfd:sphere/s_glass_50_001 1t
+//    s_glass_50_001.mcfunction   the first FunctionChunkSize-1 commands,
+//                                schedule function mcfd:sphere/s_glass_50_002 1t
+//    s_glass_50_002.mcfunction   the next FunctionChunkSize-1 commands ...
+// Scheduling needs 1.17, so the parts are always written with lower case ids and files, see
+// resourceName.
+// The player still runs the original function. Each tick has its own limit and the server
+// does not freeze. Scheduled functions are not run at the player position, so a marker entity
+// is left at the player position and every scheduled command is run at the marker.
+//
+// Sub-functions called directly from the function would not help, the commands they run count
+// against the limit of the function that called them. So without FunctionChunkSchedule a
+// function with more than FunctionChunkSize commands is an error.
+//
+// Large builds, a full falls plus clear or a large ClearVol, can also freeze the server even
+// when they fit in one function. With ScheduleCommandsPerTick the construction is spread over
+// game ticks, at most that many commands are run each tick. The fill commands are split into
+// layers one block tall and the layers are built bottom up, so the players can watch it build.
+// This can be set for all generators or just for some with ScheduleGenerator.
+//
+// The generators write the blocks of Minecraft 1.12, e.g. minecraft:stone 4. FunctionVersion
+// gives the version the functions are for, for 1.13 and later the blocks are written with
+// their new names, minecraft:polished_diorite, see mcshapes.ModernCommand. Scheduled functions
+// use the schedule command and marker entities, so they need FunctionVersion 1.17 or later.
+//**************************************************************************************************
+//**************************************************************************************************
+
+// Structure for using TOML to extract input from the user.
+//    FunctionVersion                   Minecraft version the functions are for, default "1.12"
+//    FunctionChunkSize                 Max number of commands in one function file, default 65536
+//    FunctionChunkSchedule             Split longer functions into sub-functions run one game
+//                                      tick apart, without it a longer function is an error
+//    ScheduleCommandsPerTick           Build over several game ticks with at most this many
+//                                      commands per tick. 0 (default) writes single functions.
+//    ScheduleGenerator                 Generators with their own commands per tick, e.g. "Falls",
+//                                      "ClearVol". This is the directory name of the functions.
+//    ScheduleGeneratorCommandsPerTick  Commands per tick for each ScheduleGenerator, 0 turns
+//                                      scheduling off for that generator
+type mcfdFunctionInputStruct struct {
+	FunctionVersion                  string   `toml:"FunctionVersion"`
+	FunctionChunkSize                int      `toml:"FunctionChunkSize"`
+	FunctionChunkSchedule            bool     `toml:"FunctionChunkSchedule"`
+	ScheduleCommandsPerTick          int      `toml:"ScheduleCommandsPerTick"`
+	ScheduleGenerator                []string `toml:"ScheduleGenerator"`
+	ScheduleGeneratorCommandsPerTick []int    `toml:"ScheduleGeneratorCommandsPerTick"`
+
+	// version is the minor number of FunctionVersion, see mcshapes.ParseVersion
+	version int
+}
+
+// The default for the maxCommandChainLength gamerule.
+const maxCommandChainLength = 65536
+
+// The first version with the schedule command and marker entities.
+const scheduleVersion = 17
+
+// Options for writing the function files, read from the user input file by
+// ReadFunctionOptions.
+var functionOptions = mcfdFunctionInputStruct{FunctionVersion: "1.12", version: 12,
+	FunctionChunkSize: maxCommandChainLength}
+
+// ReadFunctionOptions reads the options for writing function files from the user input file.
+func ReadFunctionOptions(inputFile string) error {
+	var mcfdInput mcfdFunctionInputStruct
+	if _, err := toml.DecodeFile(inputFile, &mcfdInput); err != nil {
+		return err
+	}
+	version, err := readFunctionVersion(inputFile)
+	if err != nil {
+		return err
+	}
+	if mcfdInput.FunctionVersion == "" {
+		mcfdInput.FunctionVersion = "1.12"
+	}
+	mcfdInput.version = version
+	if mcfdInput.FunctionChunkSize <= 0 {
+		mcfdInput.FunctionChunkSize = maxCommandChainLength
+	}
+
+	// A part of a split function has one command for the schedule of the next part, see write
+	tooSmall := ""
+	switch {
+	case mcfdInput.FunctionChunkSize == 1:
+		tooSmall = "FunctionChunkSize"
+	case mcfdInput.ScheduleCommandsPerTick == 1:
+		tooSmall = "ScheduleCommandsPerTick"
+	}
+	for _, perTick := range mcfdInput.ScheduleGeneratorCommandsPerTick {
+		if perTick == 1 && tooSmall == "" {
+			tooSmall = "ScheduleGeneratorCommandsPerTick"
+		}
+	}
+	if tooSmall != "" {
+		return fmt.Errorf("%v must be at least 2, every part of a split function also "+
+			"schedules the next part", tooSmall)
+	}
+	if len(mcfdInput.ScheduleGenerator) != len(mcfdInput.ScheduleGeneratorCommandsPerTick) {
+		return fmt.Errorf("ScheduleGenerator and ScheduleGeneratorCommandsPerTick " +
+			"must have the same number of array values")
+	}
+	if version < scheduleVersion {
+		scheduled := ""
+		switch {
+		case mcfdInput.FunctionChunkSchedule:
+			scheduled = "FunctionChunkSchedule"
+		case mcfdInput.ScheduleCommandsPerTick > 0:
+			scheduled = "ScheduleCommandsPerTick"
+		}
+		for _, perTick := range mcfdInput.ScheduleGeneratorCommandsPerTick {
+			if perTick > 0 && scheduled == "" {
+				scheduled = "ScheduleGeneratorCommandsPerTick"
+			}
+		}
+		if scheduled != "" {
+			return fmt.Errorf("%v needs FunctionVersion 1.%d or later, scheduled functions "+
+				"use the schedule command and marker entities", scheduled, scheduleVersion)
+		}
+	}
+	functionOptions = mcfdInput
+	return nil
+}
+
+// readFunctionVersion reads FunctionVersion from the user input file and returns its minor
+// number, 12 when it is not set.
+func readFunctionVersion(inputFile string) (int, error) {
+	var mcfdInput struct {
+		FunctionVersion string `toml:"FunctionVersion"`
+	}
+	if _, err := toml.DecodeFile(inputFile, &mcfdInput); err != nil {
+		return 0, err
+	}
+	if mcfdInput.FunctionVersion == "" {
+		return 12, nil
+	}
+	version, err := mcshapes.ParseVersion(mcfdInput.FunctionVersion)
+	if err != nil {
+		return 0, fmt.Errorf("FunctionVersion %v", err)
+	}
+	return version, nil
+}
+
+// resourceName returns the name of a generator directory or function file as it is written
+// for FunctionVersion. From 1.13 on function ids are resource locations, which must be lower
+// case, and the game does not load function files with upper case letters in their path, so
+// Falls/waterfall_NWE_10_7.mcfunction is written as falls/waterfall_nwe_10_7.mcfunction.
+// Minecraft 1.12 lower cases the ids itself and the names are kept as they are.
+func resourceName(name string) string {
+	if functionOptions.version >= 13 {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// CommandsPerTick returns the number of commands to run each game tick for the functions of
+// a generator, or 0 if the generator is not scheduled.
+func (o mcfdFunctionInputStruct) CommandsPerTick(dir string) int {
+	for i, g := range o.ScheduleGenerator {
+		if strings.EqualFold(g, dir) {
+			return o.ScheduleGeneratorCommandsPerTick[i]
+		}
+	}
+	return o.ScheduleCommandsPerTick
+}
+
+// mcfdFunctionFile collects the commands of one function file. The commands are written when
+// the function file is closed.
+type mcfdFunctionFile struct {
+	basepath string
+	dir      string
+	filename string
+	buf      bytes.Buffer
+
+	// The blocks placed by the commands, see model
+	blocks     *mcshapes.Model
+	blocksErr  error
+	blocksRead bool
+}
+
+// CreateFunctionFile starts the function file basepath/dir/filename, it is written by Close.
+// For Minecraft 1.13 and later the directory and file name are lower case, see resourceName.
+// Every function written this way that builds something is added to the tag for its generator,
+// so all the falls in Falls/ can be built in the game with #<namespace>:falls.
+func CreateFunctionFile(basepath string, dir string, filename string) (*mcfdFunctionFile, error) {
+	dir, filename = resourceName(dir), resourceName(filename)
+	if info, err := os.Stat(path.Join(basepath, dir)); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("the functions directory %v is not there", path.Join(basepath, dir))
+	}
+	AddGeneratedFunction(basepath, dir, filename)
+	return &mcfdFunctionFile{basepath: basepath, dir: dir, filename: filename}, nil
+}
+
+// Write satisfies io.Writer, the commands are kept until Close.
+func (m *mcfdFunctionFile) Write(p []byte) (int, error) {
+	return m.buf.Write(p)
+}
+
+// Close writes the function file and then exports, counts and draws the blocks of the whole
+// function, see ExportFunction, BOMFunction and PreviewFunction.
+func (m *mcfdFunctionFile) Close() error {
+	if err := m.write(); err != nil {
+		return err
+	}
+	if err := ExportFunction(m); err != nil {
+		return err
+	}
+	if err := BOMFunction(m); err != nil {
+		return err
+	}
+	return PreviewFunction(m)
+}
+
+// model returns the blocks placed by the commands of the function, nil if it only places
+// air. The commands are only read once for all the exports.
+func (m *mcfdFunctionFile) model() (*mcshapes.Model, error) {
+	if !m.blocksRead {
+		m.blocksRead = true
+		if placesBlocks(m.buf.Bytes()) {
+			m.blocks, m.blocksErr = mcshapes.ReadModel(bytes.NewReader(m.buf.Bytes()))
+		}
+		if m.blocksErr != nil {
+			m.blocksErr = fmt.Errorf("%v/%v: %v", m.dir, m.filename, m.blocksErr)
+		}
+	}
+	return m.blocks, m.blocksErr
+}
+
+// write writes the function file, splitting it into sub-functions if it is too long. The
+// files are written with writeFile, so a function in the world is only replaced by a whole
+// new one.
+func (m *mcfdFunctionFile) write() error {
+	lines := strings.SplitAfter(m.buf.String(), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	// The generators write the blocks of 1.12
+	if functionOptions.version >= 13 {
+		for i, line := range lines {
+			lines[i] = mcshapes.ModernCommand(line)
+		}
+	}
+
+	// The region is backed up before building so the build can be undone.
+	lines, err := SnapshotFunction(m.basepath, m.dir, m.filename, lines)
+	if err != nil {
+		return err
+	}
+
+	chunk := functionOptions.FunctionChunkSize
+	schedule := functionOptions.FunctionChunkSchedule
+	if perTick := functionOptions.CommandsPerTick(m.dir); perTick > 0 {
+		chunk = perTick
+		schedule = true
+		lines = LayerCommands(lines)
+	}
+
+	fname := path.Join(m.basepath, m.dir, m.filename)
+	if len(lines) <= chunk {
+		if err := writeFile(fname, []byte(strings.Join(lines, ""))); err != nil {
+			return err
+		}
+		return m.removeParts(1)
+	}
+
+	if !schedule {
+		return fmt.Errorf("%v/%v has %d commands, more than FunctionChunkSize %d, set "+
+			"FunctionChunkSchedule = true to run it over several game ticks", m.dir, m.filename,
+			len(lines), chunk)
+	}
+	// Every part ends with the schedule of the next part, or the kill of the marker, so it
+	// has room for chunk-1 commands and runs no more than chunk commands.
+	per := chunk - 1
+	nparts := (len(lines) + per - 1) / per
+	fmt.Printf("%v/%v has %d commands, building over %d game ticks\n",
+		m.dir, m.filename, len(lines), nparts)
+
+	// The parts find the player position with a marker entity, tagged with the name of the
+	// function so several builds can run at the same time.
+	base := strings.TrimSuffix(m.filename, ".mcfunction")
+	marker := "@e[type=minecraft:marker,tag=mcfd_" + base + ",limit=1]"
+
+	var parent bytes.Buffer
+	fmt.Fprintf(&parent, "summon minecraft:marker ~ ~ ~ {Tags:[\"mcfd_%s\"]}\n", base)
+	fmt.Fprintf(&parent, "schedule function %s 1t\n", m.partID(1))
+	for n := 1; n <= nparts; n++ {
+		end := n * per
+		if end > len(lines) {
+			end = len(lines)
+		}
+		var part bytes.Buffer
+		for _, line := range lines[(n-1)*per : end] {
+			part.WriteString("execute at " + marker + " run ")
+			part.WriteString(line)
+		}
+		if n < nparts {
+			fmt.Fprintf(&part, "schedule function %s 1t\n", m.partID(n+1))
+		} else {
+			fmt.Fprintf(&part, "kill %s\n", marker)
+		}
+
+		if err := writeFile(path.Join(m.basepath, m.dir, m.partName(n)), part.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	if err := writeFile(fname, parent.Bytes()); err != nil {
+		return err
+	}
+	return m.removeParts(nparts + 1)
+}
+
+// partName is the file name of the n-th sub-function, e.g. s_glass_50_003.mcfunction
+func (m *mcfdFunctionFile) partName(n int) string {
+	return fmt.Sprintf("%s_%03d.mcfunction", strings.TrimSuffix(m.filename, ".mcfunction"), n)
+}
+
+// partID is the function id of the n-th sub-function.
+func (m *mcfdFunctionFile) partID(n int) string {
+	return FunctionID(m.basepath, m.dir, m.partName(n))
+}
+
+// removeParts removes sub-functions, starting at the n-th, left over from an earlier run
+// where the function was longer, see removeFile.
+func (m *mcfdFunctionFile) removeParts(n int) error {
+	for ; ; n++ {
+		fname := path.Join(m.basepath, m.dir, m.partName(n))
+		if _, err := os.Stat(fname); os.IsNotExist(err) {
+			return nil
+		}
+		if err := removeFile(fname); err != nil {
+			return err
+		}
+	}
+}
+
+//**************************************************************************************************
+// Replacing the files in the world
+//
+// A file is written to a temporary file next to it, .s_glass_50.mcfunction.123.tmp, which is
+// then renamed to the file. Renaming replaces the file at once, so the game never sees half a
+// function, and a write that fails leaves the old function as it was. The function tags, the
+// structures, the STL files and the files in ExportDir and PreviewDir are written the same
+// way, and so are the files of the layers, blueprint and diff commands.
+//
+// A run of the generators stages its files, see StageFiles. The renames and the removes are
+// kept until the run is done and CommitFiles does them all. When a generator fails the run
+// calls DiscardFiles instead, the temporary files are removed and every function in the world
+// is left as it was.
+//**************************************************************************************************
+
+// stagedFile is a temporary file to rename to fname, or a file to remove when tmp is ""
+type stagedFile struct {
+	tmp   string
+	fname string
+}
+
+// The staged files in the order they were written
+var stagedFiles []stagedFile
+
+// staging is set between StageFiles and CommitFiles or DiscardFiles
+var staging bool
+
+// StageFiles starts keeping the files written until CommitFiles
+func StageFiles() {
+	stagedFiles = nil
+	staging = true
+}
+
+// CommitFiles replaces the files that were staged and removes the files to remove.
+func CommitFiles() error {
+	defer func() {
+		stagedFiles = nil
+		staging = false
+	}()
+	for i, s := range stagedFiles {
+		var err error
+		if s.tmp == "" {
+			err = os.Remove(s.fname)
+			if os.IsNotExist(err) {
+				err = nil
+			}
+		} else {
+			err = os.Rename(s.tmp, s.fname)
+		}
+		if err != nil {
+			// The rest are not done, their temporary files are removed
+			for _, rest := range stagedFiles[i+1:] {
+				if rest.tmp != "" {
+					os.Remove(rest.tmp)
+				}
+			}
+			return fmt.Errorf("replace %v: %v", s.fname, err)
+		}
+	}
+	return nil
+}
+
+// DiscardFiles removes the temporary files that were staged, nothing is replaced.
+func DiscardFiles() {
+	for _, s := range stagedFiles {
+		if s.tmp != "" {
+			os.Remove(s.tmp)
+		}
+	}
+	stagedFiles = nil
+	staging = false
+}
+
+// writeFile writes data to a temporary file and renames it to fname, or stages the rename.
+func writeFile(fname string, data []byte) error {
+	f, err := os.CreateTemp(path.Dir(fname), "."+path.Base(fname)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("write %v: %v", fname, err)
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("write %v: %v", fname, err)
+	}
+	if staging {
+		stagedFiles = append(stagedFiles, stagedFile{tmp: f.Name(), fname: fname})
+		return nil
+	}
+	if err := os.Rename(f.Name(), fname); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("write %v: %v", fname, err)
+	}
+	return nil
+}
+
+// writeSTL renders the fill commands read from r into the STL file fname, see writeFile.
+// mcrender only writes to a file name, so it renders to a temporary directory first.
+func writeSTL(fname string, r io.Reader) error {
+	dir, err := os.MkdirTemp("", "mcfd-stl")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	tmp := path.Join(dir, path.Base(fname))
+	if err := mcrender.CreateSTLFromInput(r, tmp); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(tmp)
+	if err != nil {
+		return err
+	}
+	return writeFile(fname, data)
+}
+
+// removeFile removes fname, or stages the remove.
+func removeFile(fname string) error {
+	if staging {
+		stagedFiles = append(stagedFiles, stagedFile{fname: fname})
+		return nil
+	}
+	return os.Remove(fname)
+}
+
+// LayerCommands reorders fill commands so the build goes from the bottom to the top.
+// Every fill is split into layers one block tall and the layers are sorted by their Y level.
+// Layers at different Y levels never overlap, and the sort keeps the order of the layers at
+// the same level, so the finished build is the same. Other commands, fills that do not fill
+// the whole box such as hollow and outline fills, and destroy fills, see mcshapes.ParseBox,
+// are left in place and nothing is moved past them.
+func LayerCommands(lines []string) []string {
+	var layered []string
+	var layers []*mcshapes.Box
+	flush := func() {
+		sort.SliceStable(layers, func(i, j int) bool {
+			c1, _ := layers[i].Corners()
+			c2, _ := layers[j].Corners()
+			return c1.Y < c2.Y
+		})
+		for _, l := range layers {
+			var buf bytes.Buffer
+			l.WriteShape(&buf)
+			layered = append(layered, buf.String())
+		}
+		layers = layers[:0]
+	}
+
+	for _, line := range lines {
+		b, err := mcshapes.ParseBox(line)
+		if err != nil {
+			flush()
+			layered = append(layered, line)
+			continue
+		}
+		layers = append(layers, b.Layers()...)
+	}
+	flush()
+
+	return layered
+}
+
+// ReadFunctionModel runs a function file into a block model, see mcshapes.Model.Run. The
+// sub-functions of a split or scheduled function are run where they are called, so the model
+// is the whole build. Only sub-functions next to the function file are found, which is where
+// CreateFunctionFile puts them.
+func ReadFunctionModel(fname string) (*mcshapes.Model, error) {
+	m := mcshapes.NewModel()
+	err := readFunctionModel(m, fname, make(map[string]bool))
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// readFunctionModel runs one function file into the model. Functions already run are
+// skipped, so a function calling itself does not go on forever.
+func readFunctionModel(m *mcshapes.Model, fname string, seen map[string]bool) error {
+	if seen[fname] {
+		return nil
+	}
+	seen[fname] = true
+
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return err
+	}
+	for n, line := range strings.Split(string(data), "\n") {
+		if id := calledFunction(line); id != "" {
+			sub := path.Join(path.Dir(fname), path.Base(id)+".mcfunction")
+			if err := readFunctionModel(m, sub, seen); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := m.Run(line); err != nil {
+			return fmt.Errorf("%v line %d: %v", fname, n+1, err)
+		}
+	}
+	return nil
+}
+
+// calledFunction returns the id of the function called by a function or schedule command,
+// "" for any other command or for a call to a function tag.
+func calledFunction(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) > 0 && fields[0] == "schedule" {
+		fields = fields[1:]
+	}
+	if len(fields) < 2 || fields[0] != "function" || strings.HasPrefix(fields[1], "#") {
+		return ""
+	}
+	return fields[1]
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/olekukonko/tablewriter"
+)
+
+//**************************************************************************************************
+//**************************************************************************************************
+// Function tags for the generated datapack
+//
+// Minecraft (1.13 and later) groups functions together with function tags. A tag is a small
+// JSON file in the datapack listing function ids, for example
+//    data/mcfd/tags/functions/falls.json
+//    {"replace": false, "values": ["mcfd:falls/waterfall_nwe_10_7", ...]}
+// The whole group can then be run in the game with
+//    /function #mcfd:falls
+// Only the functions that build something are in the generator tags. The _rm, _cfw and _undo
+// functions remove or undo a build, running them from the tag would remove what was just built.
+// Two tags are special. Functions in minecraft:load are run every time the world is loaded
+// (or /reload is done) and functions in minecraft:tick are run every game tick. These are used
+// to ship self initializing helpers such as scoreboards or menus along with the functions that
+// build falls, walls, signs, ...
+//
+// The init file gives the functions directory of the datapack, which is normally
+//    <world>/datapacks/<pack>/data/<namespace>/functions
+// and the tag directories are found relative to it.
+//**************************************************************************************************
+//**************************************************************************************************
+
+// Structure for using TOML to extract input from the user.
+//    FunctionTagLoad   Function ids to add to the minecraft:load tag, e.g. "mcfd:setup"
+//    FunctionTagTick   Function ids to add to the minecraft:tick tag
+type mcfdTagsInputStruct struct {
+	FunctionTagLoad []string `toml:"FunctionTagLoad"`
+	FunctionTagTick []string `toml:"FunctionTagTick"`
+}
+
+// Namespace of the generated functions. If it is not set in the init file it is taken from
+// the directory above the functions directory.
+var mcNamespace string
+
+// Function tags collected while the generators write their function files. The key is the tag
+// name without the namespace, e.g. "falls", the value is the list of function ids in the tag.
+var functionTags = make(map[string][]string)
+
+// All the functions written by the generators, with the same keys as functionTags. These are
+// the functions the list command shows.
+var generatedFunctions = make(map[string][]string)
+
+// Functions ending in these remove or undo a build and are not added to the generator tags.
+var undoSuffixes = []string{"_rm", "_cfw", "_undo"}
+
+// functionTagFile is the JSON layout of a function tag file.
+type functionTagFile struct {
+	Replace bool     `json:"replace"`
+	Values  []string `json:"values"`
+}
+
+// FunctionNamespace returns the namespace of the generated functions.
+func FunctionNamespace(basepath string) string {
+	if mcNamespace != "" {
+		return mcNamespace
+	}
+	return path.Base(path.Dir(basepath))
+}
+
+// FunctionID returns the id used in the game to run the function file dir/filename, for
+// example "mcfd:Falls/waterfall_NWE_10_7", or "mcfd:falls/waterfall_nwe_10_7" for Minecraft
+// 1.13 and later where the files are written in lower case, see resourceName.
+func FunctionID(basepath string, dir string, filename string) string {
+	return FunctionNamespace(basepath) + ":" + resourceName(dir) + "/" +
+		resourceName(strings.TrimSuffix(filename, ".mcfunction"))
+}
+
+// FunctionTagID returns the id of a function as it is listed in a function tag. Ids in tags
+// must be resource locations, which are lower case, e.g. "mcfd:falls/waterfall_nwe_10_7".
+// From 1.13 on this is the id of the lower case file, see FunctionID. Minecraft 1.12 has
+// no tags and lower cases the ids of the function files the same way.
+func FunctionTagID(basepath string, dir string, filename string) string {
+	return strings.ToLower(FunctionID(basepath, dir, filename))
+}
+
+// BuildFunction reports whether a function builds something, rather than removing or undoing
+// a build.
+func BuildFunction(filename string) bool {
+	base := strings.TrimSuffix(filename, ".mcfunction")
+	for _, suffix := range undoSuffixes {
+		if strings.HasSuffix(base, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// AddFunctionTag adds a function id to a tag. A function is only listed once in a tag.
+func AddFunctionTag(tag string, id string) {
+	functionTags[tag] = appendOnce(functionTags[tag], id)
+}
+
+// AddGeneratedFunction records a function written by a generator, and adds it to the tag of
+// the generator if it builds something.
+func AddGeneratedFunction(basepath string, dir string, filename string) {
+	tag := strings.ToLower(dir)
+	generatedFunctions[tag] = appendOnce(generatedFunctions[tag],
+		FunctionID(basepath, dir, filename))
+	if BuildFunction(filename) {
+		AddFunctionTag(tag, FunctionTagID(basepath, dir, filename))
+	}
+}
+
+// appendOnce appends id to ids if it is not there already
+func appendOnce(ids []string, id string) []string {
+	for _, v := range ids {
+		if v == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
+
+// CreateFunctionTagsDriver
+// Driver for writing the function tag files. This must be run after all the other drivers
+// so the generator tags are complete.
+func CreateFunctionTagsDriver(inputFile string, basepath string) error {
+	// Extract pertinent input, using TOML, from the user input file
+	var mcfdInput mcfdTagsInputStruct
+	if _, err := toml.DecodeFile(inputFile, &mcfdInput); err != nil {
+		return fmt.Errorf("CreateFunctionTags: %v", err)
+	}
+
+	// Tags in our own namespace go next to the functions directory. The load and tick tags
+	// belong to the minecraft namespace. Minecraft 1.21 renamed "functions" to "function",
+	// so use whatever name the functions directory has.
+	fdir := path.Base(basepath)
+	tagdir := path.Join(path.Dir(basepath), "tags", fdir)
+	mctagdir := path.Join(path.Dir(path.Dir(basepath)), "minecraft", "tags", fdir)
+
+	// A load or tick tag left from an earlier run would keep running its functions, so it is
+	// removed when nothing is in it any more.
+	for tag, ids := range map[string][]string{"load": mcfdInput.FunctionTagLoad,
+		"tick": mcfdInput.FunctionTagTick} {
+		if len(ids) > 0 {
+			continue
+		}
+		if err := RemoveFunctionTag(mctagdir, tag); err != nil {
+			return err
+		}
+	}
+
+	// If the user has not specified anything and no functions were written then there is
+	// nothing left to do.
+	if len(functionTags) == 0 && len(mcfdInput.FunctionTagLoad) == 0 &&
+		len(mcfdInput.FunctionTagTick) == 0 {
+		return nil
+	}
+
+	// First echo to stdout so the user knows what was done.
+	fmt.Println("\nCreating Function Tags for Minecraft")
+	fmt.Println("The following table summarizes the function tags:")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Tag", "Functions"})
+
+	tags := make([]string, 0, len(functionTags))
+	for tag := range functionTags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	ns := FunctionNamespace(basepath)
+	for _, tag := range tags {
+		err := WriteFunctionTag(tagdir, tag, functionTags[tag])
+		if err != nil {
+			return err
+		}
+		table.Append([]string{"#" + ns + ":" + tag, fmt.Sprintf("%d", len(functionTags[tag]))})
+	}
+
+	if len(mcfdInput.FunctionTagLoad) > 0 {
+		err := WriteFunctionTag(mctagdir, "load", mcfdInput.FunctionTagLoad)
+		if err != nil {
+			return err
+		}
+		table.Append([]string{"#minecraft:load", strings.Join(mcfdInput.FunctionTagLoad, " ")})
+	}
+	if len(mcfdInput.FunctionTagTick) > 0 {
+		err := WriteFunctionTag(mctagdir, "tick", mcfdInput.FunctionTagTick)
+		if err != nil {
+			return err
+		}
+		table.Append([]string{"#minecraft:tick", strings.Join(mcfdInput.FunctionTagTick, " ")})
+	}
+	table.Render()
+	return nil
+}
+
+// WriteFunctionTag writes one function tag file, dir/tag.json.
+// Tags from several datapacks are merged by the game, so "replace" is always false.
+func WriteFunctionTag(dir string, tag string, ids []string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("WriteFunctionTag mkdir %v: %v", dir, err)
+	}
+
+	data, err := json.MarshalIndent(functionTagFile{Values: ids}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("WriteFunctionTag %v: %v", tag, err)
+	}
+
+	fname := path.Join(dir, tag+".json")
+	if err := writeFile(fname, append(data, '\n')); err != nil {
+		return fmt.Errorf("WriteFunctionTag: %v", err)
+	}
+	return nil
+}
+
+// RemoveFunctionTag removes the function tag file dir/tag.json if it is there.
+func RemoveFunctionTag(dir string, tag string) error {
+	fname := path.Join(dir, tag+".json")
+	if _, err := os.Stat(fname); os.IsNotExist(err) {
+		return nil
+	}
+	fmt.Printf("Removing the empty function tag %v\n", fname)
+	if err := removeFile(fname); err != nil {
+		return fmt.Errorf("RemoveFunctionTag: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	mcnbt "github.com/GreenSeaTurtle/mcFunctionDev/mcNBT"
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+	"github.com/olekukonko/tablewriter"
+)
+
+//**************************************************************************************************
+//**************************************************************************************************
+// Importing builds from structure, schematic and function files
+//
+// A build made by hand in the game can be saved with a structure block (.nbt) or with
+// WorldEdit (.schem) and turned into a function here. Function files (.mcfunction) can be
+// imported too, for example old generated functions whose input is lost.
+// The function is placed the same way as the generated falls and walls, facing north the
+// build starts 2 blocks in front of the player, and there are functions for all 8 directions
+// plus _rm functions to remove it again.
+//    Structure files have no position, the build is placed with its south side 2 blocks in
+//    front of the player and its west side at the player.
+//    Schematics remember where the player stood when the build was copied, the build is placed
+//    at the same place relative to the player.
+//    Functions are placed where they build, they are facing north the same as the generated
+//    functions.
+// Materials can be swapped while importing, e.g. all the oak planks with spruce planks.
+// Block states are turned with the build, so stairs, doors, ... face the same way relative to
+// the rest of the build. Functions for Minecraft 1.12 get the 1.12 ids of the blocks, a build
+// with blocks or block states that 1.12 does not have needs FunctionVersion 1.13 or later.
+//**************************************************************************************************
+//**************************************************************************************************
+
+// Structure for using TOML to extract input from the user.
+//    ImportFile        Structure (.nbt), schematic (.schem) or function (.mcfunction) files
+//                      to import
+//    ImportName        Name of the functions for each file, e.g. "house" gives im_house_NWE, ...
+//    ImportRemapFrom   Blocks to replace in all the imported builds, e.g. "minecraft:oak_planks"
+//    ImportRemapTo     Block to use instead, one for each ImportRemapFrom
+//    ImportSkipAir     Do not place the air blocks of the builds, build over the terrain
+//
+// The builds can also be Import tables, see importEntry. The remapping and ImportSkipAir are
+// for all the builds, the tables and the arrays.
+type mcfdImportInputStruct struct {
+	Import          []importEntry `toml:"import"`
+	ImportFile      []string      `toml:"ImportFile"`
+	ImportName      []string      `toml:"ImportName"`
+	ImportRemapFrom []string      `toml:"ImportRemapFrom"`
+	ImportRemapTo   []string      `toml:"ImportRemapTo"`
+	ImportSkipAir   bool          `toml:"ImportSkipAir"`
+}
+
+// importEntry is one imported build in the input file, see Input.go.
+//    file       Structure, schematic or function file to import
+//    name       Name of the functions, default the name of the file without the extension
+//    remap      Blocks to replace in this build, after the ones for all the builds, e.g.
+//               remap = {"minecraft:oak_planks" = "minecraft:spruce_planks"}
+//    skip_air   Do not place the air blocks of this build
+type importEntry struct {
+	File    string            `toml:"file"`
+	Name    string            `toml:"name"`
+	Remap   map[string]string `toml:"remap"`
+	SkipAir bool              `toml:"skip_air"`
+}
+
+// entries returns the builds in the input, the old arrays first, with the defaults set.
+func (in mcfdImportInputStruct) entries() ([]importEntry, error) {
+	n, err := parallelArrays("import", []string{"ImportFile", "ImportName"},
+		[]int{len(in.ImportFile), len(in.ImportName)})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parallelArrays("import", []string{"ImportRemapFrom", "ImportRemapTo"},
+		[]int{len(in.ImportRemapFrom), len(in.ImportRemapTo)}); err != nil {
+		return nil, err
+	}
+	var entries []importEntry
+	for i := 0; i < n; i++ {
+		entries = append(entries, importEntry{File: in.ImportFile[i], Name: in.ImportName[i]})
+	}
+	for _, e := range in.Import {
+		defaultString(&e.Name, strings.TrimSuffix(path.Base(e.File), path.Ext(e.File)))
+		entries = append(entries, e)
+	}
+	for i := range entries {
+		entries[i].SkipAir = entries[i].SkipAir || in.ImportSkipAir
+	}
+	return entries, nil
+}
+
+// checkImportInput checks the builds to import in the input file, see ValidateInput.
+func checkImportInput(c *inputCheck) {
+	var in mcfdImportInputStruct
+	if !c.decode(&in) {
+		return
+	}
+	builds, err := in.entries()
+	if err != nil {
+		c.add(topKey("ImportFile"), err.Error(), "give every array the same number of values")
+		return
+	}
+	keys := entryKeys{"import", len(in.ImportFile),
+		map[string]string{"file": "ImportFile", "name": "ImportName"}}
+	for i, e := range builds {
+		switch {
+		case e.File == "":
+			c.add(keys.key(i, "file"), "there is no file to import",
+				"give the structure, schematic or function file, e.g. file = \"house.nbt\"")
+		case !importable(e.File):
+			c.add(keys.key(i, "file"), fmt.Sprintf("%v is not a structure, schematic or "+
+				"function file", e.File), "import .nbt, .schem or .mcfunction files")
+		case !fileExists(e.File):
+			c.add(keys.key(i, "file"), fmt.Sprintf("%v is not there", e.File),
+				"give the path from the directory mcFunctionDev is run in")
+		case !c.quiet:
+			// A broken file stops the run as well, so it is found before anything is written
+			m, err := ReadImportFile(e.File)
+			if err != nil {
+				c.add(keys.key(i, "file"), fmt.Sprintf("%v can not be read, %v", e.File, err),
+					"save the build to the file again")
+			} else if functionOptions.version < 13 {
+				if _, err := legacyModel(m); err != nil {
+					c.add(keys.key(i, "file"), fmt.Sprintf("%v can not be built in "+
+						"Minecraft 1.12, %v", e.File, err), "set FunctionVersion = \"1.13\" or later")
+				}
+			}
+		}
+		c.name("import", keys.key(i, "name"), e.Name)
+	}
+}
+
+// CreateImportDriver
+// Driver for creating the Minecraft function files for imported builds.
+func CreateImportDriver(inputFile string, basepath string) error {
+	// Extract pertinent input, using TOML, from the user input file
+	var mcfdInput mcfdImportInputStruct
+	if _, err := toml.DecodeFile(inputFile, &mcfdInput); err != nil {
+		return fmt.Errorf("CreateImport: %v", err)
+	}
+
+	// Consistency check on the user input
+	builds, err := mcfdInput.entries()
+	if err != nil {
+		return fmt.Errorf("CreateImport user input: %v", err)
+	}
+
+	// If the user has not specified anything then there is nothing left
+	// to do.
+	if len(builds) == 0 {
+		return nil
+	}
+
+	err = os.MkdirAll(path.Join(basepath, "Import"), 0755)
+	if err != nil {
+		return err
+	}
+
+	// First echo user input to stdout so the user knows what was done.
+	fmt.Println("\nCreating Import Functions for Minecraft")
+	fmt.Println("The following table summarizes user input for the imported builds:")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Filename", "File", "Width", "Height", "Depth", "Blocks"})
+
+	directionValues := []string{"north", "north_refl", "east", "east_refl", "south_refl",
+		"south", "west_refl", "west"}
+	directionNames := []string{"NWE", "NEW", "ENS", "ESN", "SWE", "SEW", "WNS", "WSN"}
+	for _, build := range builds {
+		file := build.File
+		m, err := ReadImportFile(file)
+		if err != nil {
+			return err
+		}
+		if functionOptions.version < 13 {
+			if m, err = legacyModel(m); err != nil {
+				return fmt.Errorf("CreateImport %v: %v", file, err)
+			}
+		}
+		lo, hi := m.Bounds()
+		swidth := fmt.Sprintf("%d", hi.X-lo.X+1)
+		sheight := fmt.Sprintf("%d", hi.Y-lo.Y+1)
+		sdepth := fmt.Sprintf("%d", hi.Z-lo.Z+1)
+
+		for j, direction := range directionValues {
+			filename := "im_" + build.Name + "_" + directionNames[j] + ".mcfunction"
+			filenameRm := "im_" + build.Name + "_" + directionNames[j] + "_rm.mcfunction"
+
+			s := mcshapes.NewStructure(mcshapes.WithModel(m),
+				mcshapes.WithSkipAir(build.SkipAir))
+			for k, from := range mcfdInput.ImportRemapFrom {
+				s.Remap(from, mcfdInput.ImportRemapTo[k])
+			}
+			for _, from := range mcshapes.SortedKeys(build.Remap) {
+				s.Remap(from, build.Remap[from])
+			}
+			s.Orient(direction)
+			err = CreateImport(basepath, filename, s)
+			if err != nil {
+				return err
+			}
+
+			// Removing puts air everywhere the build placed a block.
+			s = mcshapes.NewStructure(mcshapes.WithModel(m),
+				mcshapes.WithSkipAir(build.SkipAir))
+			s.Orient(direction)
+			err = CreateImport(basepath, filenameRm, s.Air())
+			if err != nil {
+				return err
+			}
+
+			table.Append([]string{filename, file, swidth, sheight, sdepth,
+				fmt.Sprintf("%d", m.Len())})
+			table.Append([]string{filenameRm, file, swidth, sheight, sdepth,
+				fmt.Sprintf("%d", m.Len())})
+		}
+	}
+	table.Render()
+	return nil
+}
+
+// ReadImportFile reads a structure (.nbt), schematic (.schem) or function (.mcfunction) file
+// into a model placed relative to the player.
+func ReadImportFile(file string) (*mcshapes.Model, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("ReadImportFile: %v", err)
+	}
+	defer f.Close()
+
+	switch strings.ToLower(path.Ext(file)) {
+	case ".nbt":
+		m, err := mcnbt.ReadStructure(f)
+		if err != nil {
+			return nil, fmt.Errorf("ReadImportFile %v: %v", file, err)
+		}
+		// Structures start at 0,0,0 and go to the south (positive Z). Move the south side
+		// to 2 blocks in front of the player.
+		_, hi := m.Bounds()
+		return moveModel(m, mcshapes.XYZ{Z: -2 - hi.Z}), nil
+
+	case ".schem":
+		m, err := mcnbt.ReadSchematic(f)
+		if err != nil {
+			return nil, fmt.Errorf("ReadImportFile %v: %v", file, err)
+		}
+		return m, nil
+
+	case ".mcfunction":
+		m, err := ReadFunctionModel(file)
+		if err != nil {
+			return nil, fmt.Errorf("ReadImportFile: %v", err)
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("ReadImportFile %v: not a .nbt, .schem or .mcfunction file", file)
+}
+
+// moveModel returns a copy of the model with all the blocks moved by d
+func moveModel(m *mcshapes.Model, d mcshapes.XYZ) *mcshapes.Model {
+	moved := mcshapes.NewModel()
+	for _, xyz := range m.Positions() {
+		moved.SetBlock(mcshapes.XYZ{X: xyz.X + d.X, Y: xyz.Y + d.Y, Z: xyz.Z + d.Z},
+			m.Block(xyz))
+	}
+	return moved
+}
+
+// legacyModel returns a copy of the model with the 1.12 ids of the blocks, see
+// mcshapes.LegacyBlock, or an error with the first block that 1.12 does not have.
+func legacyModel(m *mcshapes.Model) (*mcshapes.Model, error) {
+	legacy := mcshapes.NewModel()
+	for _, xyz := range m.Positions() {
+		block, ok := mcshapes.LegacyBlock(m.Block(xyz))
+		if !ok {
+			return nil, fmt.Errorf("there is no 1.12 block for %v at %v %v %v", m.Block(xyz),
+				xyz.X, xyz.Y, xyz.Z)
+		}
+		legacy.SetBlock(xyz, block)
+	}
+	return legacy, nil
+}
+
+// CreateImport
+// Create one function for an imported build.
+func CreateImport(basepath string, filename string, s *mcshapes.Structure) error {
+	fname := basepath + "/Import/" + filename
+	f, err := CreateFunctionFile(basepath, "Import", filename)
+	if err != nil {
+		return fmt.Errorf("CreateImport open %v: %v", fname, err)
+	}
+
+	err = s.WriteShape(f)
+	if err != nil {
+		return fmt.Errorf("CreateImport write %v: %v", fname, err)
+	}
+	return f.Close()
+}
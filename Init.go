@@ -0,0 +1,311 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go/build"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/olekukonko/tablewriter"
+)
+
+//**************************************************************************************************
+//**************************************************************************************************
+// Finding the init file
+//
+// The init file says where the Minecraft world is on this system. It is looked for in
+//    1. the -init flag
+//    2. the MCFUNCTIONDEV_INIT environment variable
+//    3. mcfunctiondev/mcFunctionDev.init in the user config directory, $XDG_CONFIG_HOME or
+//       ~/.config on Linux, see os.UserConfigDir
+//    4. mcFunctionDev.init in the current directory
+//    5. $GOPATH/mcFunctionDev.init, where it used to be, ~/go/mcFunctionDev.init when GOPATH
+//       is not set
+// The first one found is used. A file given with -init or MCFUNCTIONDEV_INIT must be there,
+// the others are skipped when they are not.
+//
+// One init file can have several worlds, called profiles, e.g. a survival world and a
+// creative world to try things out in,
+//    mc_saves_dir = "/home/me/.minecraft/saves"
+//    mc_world_functions_dir = "Survival/datapacks/mcfd/data/mcfd/functions"
+//    default_profile = "survival"
+//
+//    [profile.survival]
+//    mc_world_functions_dir = "Survival/datapacks/mcfd/data/mcfd/functions"
+//
+//    [profile.creative-test]
+//    mc_world_functions_dir = "Creative Test/datapacks/mcfd/data/mcfd/functions"
+// A profile is selected with -profile, or else the MCFUNCTIONDEV_PROFILE environment
+// variable, or else default_profile. What a profile does not set is taken from the top of
+// the file, so the saves directory is only given once. Without a profile the top of the
+// file is the world, the same as init files without profiles.
+//
+//    mcFunctionDev init [-init <file>] [-profile <name>]
+//    mcFunctionDev init -create [-force] [-saves <dir>] [-world <name>] [-init <file>]
+// init shows the init file that is found, where it was found and its profiles. With -create
+// a starter init file is written, in the user config directory unless -init or
+// MCFUNCTIONDEV_INIT give another file.
+//**************************************************************************************************
+//**************************************************************************************************
+
+// The environment variables for the init file and the profile
+const (
+	initEnv    = "MCFUNCTIONDEV_INIT"
+	profileEnv = "MCFUNCTIONDEV_PROFILE"
+)
+
+// The name of the init file in the directories it is looked for in
+const initFileName = "mcFunctionDev.init"
+
+// errNoInitFile is returned by FindInitFile when no init file is found
+var errNoInitFile = errors.New("no init file")
+
+// initPlace is a place the init file is looked for, from is how it was chosen.
+type initPlace struct {
+	fname string
+	from  string
+}
+
+// initPlaces returns the places the init file is looked for in order. The file given with
+// the -init flag, initFlag, or the environment variable is the only place.
+func initPlaces(initFlag string) []initPlace {
+	if initFlag != "" {
+		return []initPlace{{initFlag, "-init"}}
+	}
+	if env := os.Getenv(initEnv); env != "" {
+		return []initPlace{{env, "$" + initEnv}}
+	}
+	var places []initPlace
+	if dir, err := os.UserConfigDir(); err == nil {
+		places = append(places, initPlace{filepath.Join(dir, "mcfunctiondev", initFileName),
+			"user config directory"})
+	}
+	places = append(places, initPlace{initFileName, "current directory"})
+	if gopath := filepath.SplitList(build.Default.GOPATH); len(gopath) > 0 {
+		places = append(places, initPlace{filepath.Join(gopath[0], initFileName), "GOPATH"})
+	}
+	return places
+}
+
+// FindInitFile returns the init file to use and where it was found, see initPlaces.
+func FindInitFile(initFlag string) (string, string, error) {
+	places := initPlaces(initFlag)
+	if initFlag != "" || os.Getenv(initEnv) != "" {
+		if _, err := os.Stat(places[0].fname); err != nil {
+			return "", "", fmt.Errorf("init file from %v: %v", places[0].from, err)
+		}
+		return places[0].fname, places[0].from, nil
+	}
+
+	msg := ""
+	for _, p := range places {
+		if info, err := os.Stat(p.fname); err == nil && !info.IsDir() {
+			return p.fname, p.from, nil
+		}
+		msg += "\n    " + p.fname
+	}
+	return "", "", fmt.Errorf("%w, looked for%v\n"+
+		"run \"mcFunctionDev init -create\" to write one, or give the functions directory with -output",
+		errNoInitFile, msg)
+}
+
+// ReadInitFile reads the init file fname and returns the world of the profile, see
+// selectProfile.
+func ReadInitFile(fname string, profile string) (mcFunctionPath, error) {
+	var mcwpath mcFunctionPath
+	if _, err := toml.DecodeFile(fname, &mcwpath); err != nil {
+		return mcFunctionPath{}, fmt.Errorf("init file %v: %v", fname, err)
+	}
+	world, err := mcwpath.selectProfile(profile)
+	if err != nil {
+		return mcFunctionPath{}, fmt.Errorf("init file %v: %v", fname, err)
+	}
+	if world.MCSavesDir == "" && world.MCFunctionsDir == "" {
+		return mcFunctionPath{}, fmt.Errorf(
+			"init file %v: mc_saves_dir and mc_world_functions_dir are not set", fname)
+	}
+	return world, nil
+}
+
+// selectProfile returns the world of the profile, with what the profile does not set taken
+// from the top of the init file. An empty profile is the profile in MCFUNCTIONDEV_PROFILE,
+// or else default_profile, or else the top of the init file.
+func (m mcFunctionPath) selectProfile(profile string) (mcFunctionPath, error) {
+	if profile == "" {
+		profile = os.Getenv(profileEnv)
+	}
+	if profile == "" {
+		profile = m.DefaultProfile
+	}
+	world := m
+	world.Profiles = nil
+	if profile == "" {
+		return world, nil
+	}
+	p, ok := m.Profiles[profile]
+	if !ok {
+		if len(m.Profiles) == 0 {
+			return mcFunctionPath{}, fmt.Errorf("no profile %q, there are no profiles", profile)
+		}
+		return mcFunctionPath{}, fmt.Errorf("no profile %q, the profiles are %v",
+			profile, strings.Join(m.profileNames(), ", "))
+	}
+	if p.Title != "" {
+		world.Title = p.Title
+	}
+	if p.MCSavesDir != "" {
+		world.MCSavesDir = p.MCSavesDir
+	}
+	if p.MCFunctionsDir != "" {
+		world.MCFunctionsDir = p.MCFunctionsDir
+	}
+	if p.MCNamespace != "" {
+		world.MCNamespace = p.MCNamespace
+	}
+	world.DefaultProfile = profile
+	return world, nil
+}
+
+// profileNames returns the names of the profiles, sorted
+func (m mcFunctionPath) profileNames() []string {
+	names := make([]string, 0, len(m.Profiles))
+	for name := range m.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// InitCommand runs the init command with the arguments after "init".
+func InitCommand(args []string) error {
+	flags := flag.NewFlagSet("init", flag.ContinueOnError)
+	initFlag := flags.String("init", "", "init file (default the first one found)")
+	profile := flags.String("profile", "", "profile to show")
+	create := flags.Bool("create", false, "write a starter init file")
+	force := flags.Bool("force", false, "overwrite the init file with -create")
+	saves := flags.String("saves", minecraftSavesDir(), "Minecraft saves directory for -create")
+	world := flags.String("world", "New World", "world for -create")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mcFunctionDev init [-init <file>] [-profile <name>]\n"+
+			"       mcFunctionDev init -create [-force] [-saves <dir>] [-world <name>] [-init <file>]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if *create {
+		return createInitFile(*initFlag, *force, *saves, *world)
+	}
+
+	fname, from, err := FindInitFile(*initFlag)
+	if err != nil {
+		return err
+	}
+	var mcwpath mcFunctionPath
+	if _, err := toml.DecodeFile(fname, &mcwpath); err != nil {
+		return fmt.Errorf("init file %v: %v", fname, err)
+	}
+	selected, err := ReadInitFile(fname, *profile)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("init file %v, from the %v\n", fname, from)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Profile", "Functions Directory", "Namespace"})
+	names := append([]string{""}, mcwpath.profileNames()...)
+	for _, name := range names {
+		w, err := mcwpath.selectProfile(name)
+		if err != nil {
+			return err
+		}
+		label := name
+		if name == "" {
+			label = "(none)"
+		}
+		if name == selected.DefaultProfile {
+			label += " *"
+		}
+		basepath := path.Join(w.MCSavesDir, w.MCFunctionsDir)
+		ns := w.MCNamespace
+		if ns == "" {
+			ns = path.Base(path.Dir(basepath))
+		}
+		table.Append([]string{label, basepath, ns})
+	}
+	table.Render()
+	fmt.Println("* is the profile used without -profile")
+	return nil
+}
+
+// minecraftSavesDir returns the usual Minecraft saves directory on this system
+func minecraftSavesDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	switch runtime.GOOS {
+	case "windows":
+		if appdata := os.Getenv("APPDATA"); appdata != "" {
+			return filepath.Join(appdata, ".minecraft", "saves")
+		}
+	case "darwin":
+		return filepath.Join(home, "Library", "Application Support", "minecraft", "saves")
+	}
+	return filepath.Join(home, ".minecraft", "saves")
+}
+
+// The starter init file, with the saves directory and the world
+const starterInitFile = `# mcFunctionDev init file, where the Minecraft world is on this system.
+# The functions are written to mc_saves_dir/mc_world_functions_dir, the functions directory
+# of a datapack in the world. Minecraft 1.21 and later call it "function".
+title = "mcFunctionDev init file"
+mc_saves_dir = %q
+mc_world_functions_dir = %q
+
+# The namespace of the functions, default the directory above the functions directory.
+#mc_namespace = "mcfd"
+
+# Other worlds, selected with -profile. What a profile does not set is taken from above.
+#default_profile = "survival"
+#
+#[profile.survival]
+#mc_world_functions_dir = "Survival/datapacks/mcfd/data/mcfd/functions"
+#
+#[profile.creative-test]
+#mc_world_functions_dir = "Creative Test/datapacks/mcfd/data/mcfd/functions"
+`
+
+// createInitFile writes a starter init file for the world in the saves directory.
+func createInitFile(initFlag string, force bool, saves string, world string) error {
+	fname := initFlag
+	if fname == "" {
+		fname = os.Getenv(initEnv)
+	}
+	if fname == "" {
+		places := initPlaces("")
+		fname = places[0].fname
+	}
+	if _, err := os.Stat(fname); err == nil && !force {
+		return fmt.Errorf("init file %v is already there, use -force to overwrite it", fname)
+	}
+	if err := os.MkdirAll(filepath.Dir(fname), 0755); err != nil {
+		return err
+	}
+	functions := path.Join(world, "datapacks", "mcfd", "data", "mcfd", "functions")
+	err := os.WriteFile(fname, []byte(fmt.Sprintf(starterInitFile, saves, functions)), 0644)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("wrote %v, change mc_saves_dir and mc_world_functions_dir to your world\n", fname)
+	return nil
+}
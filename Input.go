@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+//**************************************************************************************************
+//**************************************************************************************************
+// Generator input tables
+//
+// Each generator reads a list of entries from the input file, one for each build, as TOML
+// arrays of tables, e.g.
+//    [[sphere]]
+//    radius = 5
+//    exterior = "glass"
+//    interior = "lava"
+// Keys that are left out get the defaults of the generator, see fallsEntry, clearVolEntry,
+// mwallEntry, sign7Entry, sphereEntry, walkwayEntry and importEntry. Any entry can have a name,
+// which is used in the function names instead of the sizes and blocks, e.g. name = "dome"
+// gives s_dome instead of s_glass_lava_5. The tables go at the end of the input file, in TOML
+// all the keys after a [[sphere]] line belong to that sphere.
+//
+// Input files from before the tables have one array for each key, the first values of all
+// the arrays are the first build and so on, e.g.
+//    SphereRadius            = [5,       10]
+//    SphereExteriorBlockType = ["glass", "glass"]
+//    SphereInteriorBlockType = ["lava",  "none"]
+// These still work. The arrays are turned into entries, which come before the tables when a
+// file has both.
+//**************************************************************************************************
+//**************************************************************************************************
+
+// parallelArrays checks that the arrays of the old input form of a generator all have the
+// same number of values and returns it. table is the name of the tables of the generator.
+func parallelArrays(table string, names []string, lengths []int) (int, error) {
+	for _, n := range lengths[1:] {
+		if n != lengths[0] {
+			counts := make([]string, len(lengths))
+			for i, n := range lengths {
+				counts[i] = fmt.Sprintf("%v has %d", names[i], n)
+			}
+			return 0, fmt.Errorf("the arrays %v must have the same number of values, "+
+				"%v; or use [[%v]] tables", strings.Join(names, ", "), strings.Join(counts, ", "),
+				table)
+		}
+	}
+	return lengths[0], nil
+}
+
+// defaultInt sets v to d if it is not set
+func defaultInt(v *int, d int) {
+	if *v == 0 {
+		*v = d
+	}
+}
+
+// defaultString sets v to d if it is not set
+func defaultString(v *string, d string) {
+	if *v == "" {
+		*v = d
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path"
+	"strings"
+
+	mcview "github.com/GreenSeaTurtle/mcFunctionDev/mcView"
+)
+
+//**************************************************************************************************
+//**************************************************************************************************
+// Build guides
+//
+//    mcFunctionDev layers [-out <dir>] [-cell <pixels>] [-columns <n>] <function file> ...
+//
+// Survival builders copy the falls and castle walls by hand, block by block. A build guide is
+// a blueprint of the blocks of a function, one PNG image per Y layer seen from above, with the
+// relative coordinates of the function along the edges and the player start position, ~0 ~0
+// ~0, marked in red. See mcview.Plan. For each function file these are written,
+//    <out>/<function>/<function>_layer_01.png   the bottom layer
+//    <out>/<function>/<function>_layer_02.png   the next layer up ...
+//    <out>/<function>/<function>_sheet.png      all the layers on one page for printing
+// Any function can be drawn, the blocks are read the same as for diff, see ReadFunctionModel.
+//**************************************************************************************************
+//**************************************************************************************************
+
+// LayersCommand runs the layers command with the arguments after "layers".
+func LayersCommand(args []string) error {
+	flags := flag.NewFlagSet("layers", flag.ContinueOnError)
+	out := flags.String("out", "layers", "directory for the images")
+	cell := flags.Int("cell", 16, "size of one block in pixels")
+	columns := flags.Int("columns", 3, "layers across the page of the sheet")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(),
+			"usage: mcFunctionDev layers [-out <dir>] [-cell <pixels>] [-columns <n>] <function file> ...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("layers needs function files")
+	}
+	if *cell < 4 {
+		return fmt.Errorf("layers: -cell must be at least 4 pixels")
+	}
+
+	for _, fname := range flags.Args() {
+		m, err := ReadFunctionModel(fname)
+		if err != nil {
+			return err
+		}
+		plan := mcview.NewPlan(m, mcview.WithCellSize(*cell))
+		ys := plan.Ys()
+		if len(ys) == 0 {
+			fmt.Printf("%v places no blocks\n", fname)
+			continue
+		}
+
+		base := strings.TrimSuffix(path.Base(fname), ".mcfunction")
+		dir := path.Join(*out, base)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("layers mkdir %v: %v", dir, err)
+		}
+		for i, y := range ys {
+			err := writePNG(path.Join(dir, fmt.Sprintf("%s_layer_%02d.png", base, i+1)),
+				plan.Layer(y))
+			if err != nil {
+				return err
+			}
+		}
+		sheet := path.Join(dir, base+"_sheet.png")
+		if err := writePNG(sheet, plan.Sheet(*columns)); err != nil {
+			return err
+		}
+		fmt.Printf("%v: %d layers, Y ~%d to ~%d, %v\n", fname, len(ys), ys[0], ys[len(ys)-1],
+			sheet)
+	}
+	return nil
+}
+
+// writePNG writes an image to the PNG file fname, see writeFile
+func writePNG(fname string, img image.Image) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return fmt.Errorf("writePNG write %v: %v", fname, err)
+	}
+	return writeFile(fname, buf.Bytes())
+}
@@ -0,0 +1,669 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+)
+
+//**************************************************************************************************
+//**************************************************************************************************
+// Checking generated functions
+//
+//    mcFunctionDev lint [-target <version>] <file or dir> ...
+//
+// Minecraft does not say much about a bad command in a function. The function is left out
+// when the datapack is loaded and the reason is only in the game log. lint checks every line
+// of the function files for the mistakes that are easy to make in a generator,
+//    extra spaces, Minecraft does not allow them (see the README)
+//    commands and execute forms that are not in the target version
+//    unknown blocks, data values and block state keys, see mcshapes.KnownBlock
+//    fills and clones of more than 32768 blocks
+//    Y coordinates that are outside the world
+//    calls to functions and function tags that are not in the datapack
+//    function ids and function files with upper case letters, 1.13 and later only load
+//    resource locations of a-z, 0-9, _, -, . and /
+// Every problem is printed as file:line: message, or file: message for the name of the file,
+// and lint fails if there are any. Warnings are printed the same way but do not fail lint.
+//
+// The target is the Minecraft version the functions are for, 1.12 by default which is the
+// version the generators write block names for. Called functions are looked for in the
+// datapack the function file is in, found from the data directory above it, or else in the
+// directory given to lint.
+//**************************************************************************************************
+//**************************************************************************************************
+
+// Commands by the version they were added in, and the version removed commands were
+// removed in. The commands only a server operator can run are left out, functions can not
+// run them.
+var lintCommands = map[string]struct{ since, until int }{
+	"advancement": {12, 0}, "blockdata": {12, 13}, "clear": {12, 0}, "clone": {12, 0},
+	"debug": {12, 0}, "defaultgamemode": {12, 0}, "difficulty": {12, 0}, "effect": {12, 0},
+	"enchant": {12, 0}, "entitydata": {12, 13}, "execute": {12, 0}, "experience": {12, 0},
+	"fill": {12, 0}, "function": {12, 0}, "gamemode": {12, 0}, "gamerule": {12, 0},
+	"give": {12, 0}, "help": {12, 0}, "kill": {12, 0}, "list": {12, 0}, "locate": {12, 0},
+	"me": {12, 0}, "msg": {12, 0}, "particle": {12, 0}, "playsound": {12, 0},
+	"recipe": {12, 0}, "reload": {12, 0}, "replaceitem": {12, 17}, "say": {12, 0},
+	"scoreboard": {12, 0}, "seed": {12, 0}, "setblock": {12, 0}, "setworldspawn": {12, 0},
+	"spawnpoint": {12, 0}, "spreadplayers": {12, 0}, "stats": {12, 13}, "stopsound": {12, 0},
+	"summon": {12, 0}, "teleport": {12, 0}, "tell": {12, 0}, "tellraw": {12, 0},
+	"testfor": {12, 13}, "testforblock": {12, 13}, "testforblocks": {12, 13}, "time": {12, 0},
+	"title": {12, 0}, "toggledownfall": {12, 13}, "tp": {12, 0}, "trigger": {12, 0},
+	"w": {12, 0}, "weather": {12, 0}, "worldborder": {12, 0}, "xp": {12, 0},
+	"bossbar": {13, 0}, "data": {13, 0}, "datapack": {13, 0}, "tag": {13, 0},
+	"team": {13, 0}, "teammsg": {13, 0}, "tm": {13, 0},
+	"forceload": {14, 0}, "loot": {14, 0}, "schedule": {14, 0},
+	"spectate":  {15, 0},
+	"attribute": {16, 0}, "locatebiome": {16, 19},
+	"item": {17, 0}, "jfr": {17, 0},
+	"place":  {19, 0},
+	"damage": {20, 0}, "fillbiome": {20, 0}, "random": {20, 0}, "return": {20, 0},
+	"ride": {20, 0}, "tick": {20, 0}, "transfer": {20, 0},
+	"rotate": {21, 0}, "test": {21, 0}, "dialog": {21, 0}, "version": {21, 0},
+	"waypoint": {21, 0}, "stopwatch": {21, 0}, "fetchprofile": {21, 0},
+}
+
+// The newest version in lintCommands. For later targets an unknown command may be a new one,
+// it is a warning and not a problem.
+const lintCommandsVersion = 21
+
+// The start of the problems that do not fail lint
+const lintWarning = "warning: "
+
+// A namespace and a path of a resource location in Minecraft 1.13 and later, e.g. the
+// function id mcfd:falls/fl_nwe
+var (
+	resourceNamespace = regexp.MustCompile(`^[a-z0-9_.\-]+$`)
+	resourcePath      = regexp.MustCompile(`^[a-z0-9_.\-/]+$`)
+)
+
+// linter checks the commands of a function file for one version of Minecraft.
+type linter struct {
+	version int
+
+	// exists reports whether a called function, or a function tag starting with #, is in
+	// the datapack
+	exists func(id string) bool
+}
+
+// coordinate is one coordinate of a command, kind is "" for absolute, "~" for relative
+// and "^" for local.
+type coordinate struct {
+	kind string
+	v    int
+}
+
+// LintCommand runs the lint command with the arguments after "lint".
+func LintCommand(args []string) error {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	target := flags.String("target", "1.12", "Minecraft version the functions are for")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mcFunctionDev lint [-target <version>] <file or dir> ...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("lint needs function files or directories")
+	}
+	version, err := mcshapes.ParseVersion(*target)
+	if err != nil {
+		return err
+	}
+
+	nfiles, nbad, nproblems := 0, 0, 0
+	for _, arg := range flags.Args() {
+		n, bad, problems, err := LintPath(arg, version)
+		if err != nil {
+			return err
+		}
+		nfiles, nbad, nproblems = nfiles+n, nbad+bad, nproblems+problems
+	}
+
+	if nproblems > 0 {
+		return fmt.Errorf("lint: %d problems in %d of %d functions", nproblems, nbad, nfiles)
+	}
+	fmt.Printf("lint: %d functions, no problems for Minecraft %v\n", nfiles, *target)
+	return nil
+}
+
+// LintPath checks the function file arg, or all the function files in the directory arg,
+// for Minecraft 1.<version> and prints their problems. It returns the number of functions,
+// of functions with problems and of problems, warnings are printed but not counted.
+func LintPath(arg string, version int) (int, int, int, error) {
+	root, files := arg, []string{arg}
+	info, err := os.Stat(arg)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if info.IsDir() {
+		files = nil
+		err = filepath.WalkDir(arg, func(p string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() && strings.HasSuffix(p, ".mcfunction") {
+				files = append(files, p)
+			}
+			return err
+		})
+		if err != nil {
+			return 0, 0, 0, err
+		}
+	} else {
+		root = filepath.Dir(arg)
+	}
+
+	nbad, nproblems := 0, 0
+	for _, fname := range files {
+		problems, err := LintFunction(fname, root, version)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		n := 0
+		for _, p := range problems {
+			fmt.Println(p)
+			if !strings.Contains(p, ": "+lintWarning) {
+				n++
+			}
+		}
+		nproblems += n
+		if n > 0 {
+			nbad++
+		}
+	}
+	return len(files), nbad, nproblems, nil
+}
+
+// LintFunction checks the function file fname for Minecraft 1.<version> and returns its
+// problems as "file:line: message". Called functions that are not in a datapack are looked
+// for in the directory root.
+func LintFunction(fname string, root string, version int) ([]string, error) {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	l := &linter{version: version, exists: datapackFunctions(fname, root)}
+
+	// The path of the file is the id of the function, from the data directory or the
+	// directory given to lint. Checking it here also finds the upper case names a file
+	// system that ignores case would find for a lower case id.
+	var problems []string
+	if version >= 13 {
+		rel, err := filepath.Rel(root, fname)
+		if data := datapackDir(fname); data != "" {
+			rel, err = filepath.Rel(data, fname)
+		}
+		if err == nil && !resourcePath.MatchString(filepath.ToSlash(rel)) {
+			problems = append(problems, fmt.Sprintf("%v: the function file %v is not lower "+
+				"case letters, digits, _, -, . and /, Minecraft 1.%d does not load it", fname,
+				filepath.ToSlash(rel), version))
+		}
+	}
+	for n, line := range strings.Split(string(data), "\n") {
+		for _, p := range l.lintLine(strings.TrimRight(line, "\r")) {
+			problems = append(problems, fmt.Sprintf("%v:%d: %v", fname, n+1, p))
+		}
+	}
+	return problems, nil
+}
+
+// lintLine checks one line of a function file.
+func (l *linter) lintLine(line string) []string {
+	command := strings.TrimSpace(line)
+	if command == "" || strings.HasPrefix(command, "#") {
+		return nil
+	}
+	var problems []string
+	if extraSpaces(command) {
+		problems = append(problems, "extra spaces in the command")
+	}
+	return append(problems, l.lintCommand(command)...)
+}
+
+// lintCommand checks one command, commands run by execute are checked too.
+func (l *linter) lintCommand(command string) []string {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return []string{"missing command"}
+	}
+	name := fields[0]
+	if strings.HasPrefix(name, "/") {
+		return []string{"commands in functions do not start with /"}
+	}
+	c, ok := lintCommands[name]
+	switch {
+	case !ok && l.version > lintCommandsVersion:
+		return []string{fmt.Sprintf("%vunknown command %q, it may be new after Minecraft 1.%d",
+			lintWarning, name, lintCommandsVersion)}
+	case !ok:
+		return []string{fmt.Sprintf("unknown command %q", name)}
+	case l.version < c.since:
+		return []string{fmt.Sprintf("%v is not in Minecraft 1.%d, it was added in 1.%d",
+			name, l.version, c.since)}
+	case c.until != 0 && l.version >= c.until:
+		return []string{fmt.Sprintf("%v was removed in Minecraft 1.%d", name, c.until)}
+	}
+
+	switch name {
+	case "fill", "setblock":
+		return l.lintFill(command)
+	case "clone":
+		return l.lintClone(fields)
+	case "execute":
+		return l.lintExecute(fields)
+	case "function":
+		return l.lintFunctionCall(fields[1:])
+	case "schedule":
+		if len(fields) > 1 && fields[1] == "function" {
+			return l.lintFunctionCall(fields[2:])
+		}
+	}
+	return nil
+}
+
+// lintFill checks a fill or setblock command.
+func (l *linter) lintFill(command string) []string {
+	var corners [][3]coordinate
+	cmd, err := mcshapes.ParseCommandFunc(command,
+		func(fields []string) (mcshapes.XYZ, error) {
+			c, err := parseCoordinates(fields)
+			corners = append(corners, c)
+			return mcshapes.XYZ{X: c[0].v, Y: c[1].v, Z: c[2].v}, err
+		})
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	problems := l.lintCorners(corners)
+	problems = append(problems, l.lintBlock(cmd.Box.Surface())...)
+	if cmd.Filter != "" {
+		problems = append(problems, l.lintBlock(cmd.Filter)...)
+	}
+	return problems
+}
+
+// lintClone checks a clone command.
+func (l *linter) lintClone(fields []string) []string {
+	if len(fields) < 10 {
+		return []string{"clone command is too short"}
+	}
+	var corners [][3]coordinate
+	for i := 0; i < 3; i++ {
+		c, err := parseCoordinates(fields[3*i+1 : 3*i+4])
+		if err != nil {
+			return []string{err.Error()}
+		}
+		corners = append(corners, c)
+	}
+
+	// The destination is checked for being in the world but is not part of the volume
+	problems := l.lintCorners(corners[:2])
+	problems = append(problems, l.lintCorners(corners[2:])...)
+	if len(fields) > 11 && fields[10] == "filtered" {
+		problems = append(problems, l.lintBlock(fields[11])...)
+	}
+	return problems
+}
+
+// lintCorners checks the corners of a box, each is 3 coordinates. A box of two corners is
+// checked for being small enough for one command.
+func (l *linter) lintCorners(corners [][3]coordinate) []string {
+	var problems []string
+
+	// Y of the world, 0 to 255 before 1.18 and -64 to 319 after
+	minY, maxY := 0, 255
+	if l.version >= 18 {
+		minY, maxY = -64, 319
+	}
+	for _, c := range corners {
+		local := 0
+		for _, v := range c {
+			if v.kind == "^" {
+				local++
+			}
+		}
+		if local > 0 && l.version <= 12 {
+			problems = append(problems, "local coordinates ^ are not in Minecraft 1.12")
+		} else if local > 0 && local < 3 {
+			problems = append(problems, "local coordinates ^ cannot be mixed with others")
+		}
+
+		y := c[1]
+		switch {
+		case y.kind == "~" && (y.v > maxY-minY || y.v < minY-maxY):
+			problems = append(problems, fmt.Sprintf(
+				"relative Y ~%d is outside the world, which is %d blocks high", y.v, maxY-minY+1))
+		case y.kind == "" && (y.v < minY || y.v > maxY):
+			problems = append(problems, fmt.Sprintf("Y %d is outside the world, %d to %d",
+				y.v, minY, maxY))
+		}
+	}
+
+	// Coordinates of different kinds can be anywhere, only boxes with the same kinds
+	// of coordinates at both corners are measured.
+	if len(corners) == 2 {
+		volume := 1
+		for i := range corners[0] {
+			a, b := corners[0][i], corners[1][i]
+			if a.kind != b.kind {
+				return problems
+			}
+			d := b.v - a.v
+			if d < 0 {
+				d = -d
+			}
+			volume *= d + 1
+		}
+		if volume > mcshapes.MaxFillBlocks {
+			problems = append(problems, fmt.Sprintf("%d blocks, more than the %d in one command",
+				volume, mcshapes.MaxFillBlocks))
+		}
+	}
+	return problems
+}
+
+// lintBlock checks a block of a fill, setblock or clone, name[states] or the old form with
+// a data value, name <data>.
+func (l *linter) lintBlock(surface string) []string {
+	name := surface
+	if i := strings.IndexAny(name, "[ "); i >= 0 {
+		name = name[:i]
+	}
+	if strings.Contains(name, ":") && !strings.HasPrefix(name, "minecraft:") {
+		// A block from a mod
+		return nil
+	}
+
+	if l.version <= 12 {
+		if strings.Contains(surface, "[") {
+			return []string{fmt.Sprintf("block states of %v are not in Minecraft 1.12, use a "+
+				"data value", name)}
+		}
+		if !mcshapes.KnownBlock(name, l.version) {
+			return []string{fmt.Sprintf("unknown block %v in Minecraft 1.12", name)}
+		}
+		if fields := strings.Fields(surface); len(fields) > 1 {
+			if d, err := strconv.Atoi(fields[1]); err != nil || d < 0 || d > 15 {
+				return []string{fmt.Sprintf("data value %v of %v is not 0 to 15", fields[1], name)}
+			}
+		}
+		return nil
+	}
+
+	if strings.Contains(surface, " ") && !strings.Contains(surface, "[") {
+		return []string{fmt.Sprintf("%q has a data value, Minecraft 1.13 and later use %v",
+			surface, mcshapes.BlockString(surface))}
+	}
+	if !mcshapes.KnownBlock(name, l.version) {
+		// Blocks renamed in 1.13, e.g. fence is oak_fence
+		renamed := mcshapes.BlockName(name)
+		if renamed != "minecraft:"+strings.TrimPrefix(name, "minecraft:") &&
+			mcshapes.KnownBlock(renamed, l.version) {
+			return []string{fmt.Sprintf("unknown block %v in Minecraft 1.%d, use %v", name,
+				l.version, renamed)}
+		}
+		return []string{fmt.Sprintf("unknown block %v in Minecraft 1.%d", name, l.version)}
+	}
+	keys, ok := mcshapes.BlockStateKeys(name)
+	if !ok {
+		return nil
+	}
+	_, props := mcshapes.BlockState(surface)
+	var problems []string
+	for _, k := range mcshapes.SortedKeys(props) {
+		found := false
+		for _, key := range keys {
+			found = found || key == k
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("%v has no block state %q", name, k))
+		}
+	}
+	return problems
+}
+
+// lintExecute checks an execute command and the command it runs.
+func (l *linter) lintExecute(fields []string) []string {
+	if l.version <= 12 {
+		// execute <entity> <x> <y> <z> [detect <x> <y> <z> <block> <data>] <command>
+		for _, f := range fields {
+			if f == "run" {
+				return []string{"execute ... run is not in Minecraft 1.12, it is " +
+					"execute <entity> <x> <y> <z> <command>"}
+			}
+		}
+		i := 5
+		if len(fields) > 5 && fields[5] == "detect" {
+			i = 11
+		}
+		if len(fields) <= i {
+			return []string{"execute has no command"}
+		}
+		return l.lintCommand(strings.Join(fields[i:], " "))
+	}
+
+	// Skip each subcommand by its number of fields, up to run
+	for i := 1; i < len(fields); {
+		next := func(n int) string {
+			if i+n < len(fields) {
+				return fields[i+n]
+			}
+			return ""
+		}
+		switch sub := fields[i]; sub {
+		case "run":
+			if i+1 == len(fields) {
+				return []string{"execute run has no command"}
+			}
+			return l.lintCommand(strings.Join(fields[i+1:], " "))
+		case "align", "anchored", "as", "at", "in":
+			i += 2
+		case "on", "summon":
+			if l.version < 20 {
+				return []string{fmt.Sprintf("execute %v is not in Minecraft 1.%d", sub, l.version)}
+			}
+			i += 2
+		case "facing":
+			i += 4
+		case "positioned":
+			if next(1) == "as" || next(1) == "over" {
+				i += 3
+			} else {
+				i += 4
+			}
+		case "rotated":
+			i += 3
+		case "store":
+			// Fields after store result <kind>
+			n := map[string]int{"block": 6, "bossbar": 2, "entity": 4, "score": 2, "storage": 4}
+			i += 3 + n[next(2)]
+		case "if", "unless":
+			switch next(1) {
+			case "block":
+				if b := next(5); b != "" && !strings.HasPrefix(b, "#") {
+					if problems := l.lintBlock(b); len(problems) > 0 {
+						return problems
+					}
+				}
+				i += 6
+			case "blocks":
+				i += 12
+			case "score":
+				if next(4) == "matches" {
+					i += 6
+				} else {
+					i += 7
+				}
+			case "data":
+				if next(2) == "block" {
+					i += 7
+				} else {
+					i += 5
+				}
+			case "biome":
+				i += 6
+			case "loaded":
+				i += 5
+			case "entity", "predicate", "dimension", "function":
+				i += 3
+			default:
+				return []string{fmt.Sprintf("unknown execute %v condition %q", sub, next(1))}
+			}
+		default:
+			return []string{fmt.Sprintf("unknown execute subcommand %q", sub)}
+		}
+	}
+	return nil
+}
+
+// lintFunctionCall checks the function called by a function or schedule command.
+func (l *linter) lintFunctionCall(args []string) []string {
+	if len(args) == 0 {
+		return []string{"missing function id"}
+	}
+	id := args[0]
+	if l.version >= 13 {
+		ns, p, found := strings.Cut(strings.TrimPrefix(id, "#"), ":")
+		if !found {
+			ns, p = "minecraft", ns
+		}
+		if !resourceNamespace.MatchString(ns) || !resourcePath.MatchString(p) {
+			return []string{fmt.Sprintf("function id %v is not lower case letters, digits, "+
+				"_, -, . and /", id)}
+		}
+	}
+	if strings.HasPrefix(id, "#") {
+		if l.version <= 12 {
+			return []string{"function tags are not in Minecraft 1.12"}
+		}
+		if !l.exists(id) {
+			return []string{fmt.Sprintf("function tag %v is not in the datapack", id)}
+		}
+		return nil
+	}
+	if !l.exists(id) {
+		return []string{fmt.Sprintf("function %v is not in the datapack", id)}
+	}
+	return nil
+}
+
+// datapackFunctions returns a function reporting whether a function id, or a function tag
+// starting with #, is in the datapack of the function file fname. Both datapacks,
+//    data/<namespace>/functions/<path>.mcfunction
+//    data/<namespace>/tags/functions/<path>.json
+// and the Minecraft 1.12 layout, data/functions/<namespace>/<path>.mcfunction, are found
+// from the data directory above fname. If there is none, functions are looked for by their
+// path in root and the directories above it, the functions directory is above root when a
+// generator directory is checked, e.g. mcfd:MWall/mw_NWE_15_10_undo for root .../MWall.
+func datapackFunctions(fname string, root string) func(id string) bool {
+	data := datapackDir(fname)
+	var roots []string
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	for d := root; ; d = filepath.Dir(d) {
+		roots = append(roots, d)
+		if d == filepath.Dir(d) {
+			break
+		}
+	}
+
+	return func(id string) bool {
+		tag := strings.HasPrefix(id, "#")
+		ns, p, found := strings.Cut(strings.TrimPrefix(id, "#"), ":")
+		if !found {
+			ns, p = "minecraft", ns
+		}
+		var candidates []string
+		switch {
+		case data != "" && tag:
+			candidates = []string{
+				filepath.Join(data, ns, "tags", "functions", p+".json"),
+				filepath.Join(data, ns, "tags", "function", p+".json")}
+		case data != "":
+			candidates = []string{
+				filepath.Join(data, ns, "functions", p+".mcfunction"),
+				filepath.Join(data, ns, "function", p+".mcfunction"),
+				filepath.Join(data, "functions", ns, p+".mcfunction")}
+		case !tag:
+			for _, d := range roots {
+				candidates = append(candidates, filepath.Join(d, p+".mcfunction"))
+			}
+		}
+		for _, c := range candidates {
+			if _, err := os.Stat(c); err == nil {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// datapackDir returns the data directory of the datapack the function file fname is in, or
+// "" if it is not in one.
+func datapackDir(fname string) string {
+	for d := filepath.Dir(fname); d != filepath.Dir(d); d = filepath.Dir(d) {
+		if filepath.Base(d) == "data" {
+			return d
+		}
+	}
+	return ""
+}
+
+// parseCoordinates reads 3 coordinates, absolute, ~ relative or ^ local.
+func parseCoordinates(fields []string) ([3]coordinate, error) {
+	var c [3]coordinate
+	for i, s := range fields {
+		if strings.HasPrefix(s, "~") || strings.HasPrefix(s, "^") {
+			c[i].kind, s = s[:1], s[1:]
+			if s == "" {
+				continue
+			}
+		}
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return c, fmt.Errorf("coordinate %q is not a block position", fields[i])
+		}
+		c[i].v = v
+	}
+	return c, nil
+}
+
+// extraSpaces reports whether a command has more than one space between its arguments, or
+// a tab. Strings, block states and NBT data may have any spaces.
+func extraSpaces(command string) bool {
+	depth, quote, escaped := 0, rune(0), false
+	prev := 'x'
+	for _, r := range command {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0 && r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '[' || r == '{':
+			depth++
+		case r == ']' || r == '}':
+			depth--
+		case depth == 0 && unicode.IsSpace(r) && (r != ' ' || prev == ' '):
+			return true
+		}
+		prev = r
+	}
+	return false
+}
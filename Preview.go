@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	mcview "github.com/GreenSeaTurtle/mcFunctionDev/mcView"
+)
+
+//**************************************************************************************************
+//**************************************************************************************************
+// Preview images
+//
+// The STL files written with mcrender have no colors and need a 3D viewer. With PreviewViews
+// every function that places blocks is also drawn as PNG images, in color, from the cameras
+// listed, e.g.
+//    PreviewDir/Falls/waterfall_NWE_10_7_iso.png
+//    PreviewDir/Falls/waterfall_NWE_10_7_top.png
+// The images are small enough to look at in a terminal image viewer or to attach to a code
+// review. See mcview.View for the cameras.
+//
+// Every block has a color close to how it looks in the game. PreviewColorBlock and
+// PreviewColor change the colors, for example to make the glass of the falls stand out.
+//**************************************************************************************************
+//**************************************************************************************************
+
+// Structure for using TOML to extract input from the user.
+//    PreviewViews       Cameras to draw every function from, e.g. ["iso", "top", "south"]
+//    PreviewDir         Directory for the images, default "previews"
+//    PreviewBlockSize   Width of one block in pixels, default 16
+//    PreviewColorBlock  Blocks with their own color, e.g. "minecraft:glass"
+//    PreviewColor       Color of each PreviewColorBlock, "#rrggbb"
+type mcfdPreviewInputStruct struct {
+	PreviewViews      []string `toml:"PreviewViews"`
+	PreviewDir        string   `toml:"PreviewDir"`
+	PreviewBlockSize  int      `toml:"PreviewBlockSize"`
+	PreviewColorBlock []string `toml:"PreviewColorBlock"`
+	PreviewColor      []string `toml:"PreviewColor"`
+}
+
+// Options for the preview images, read from the user input file by ReadPreviewOptions.
+var previewOptions = mcfdPreviewInputStruct{PreviewDir: "previews", PreviewBlockSize: 16}
+
+// Block colors of the preview images, the default colors with PreviewColor.
+var previewPalette = mcview.DefaultPalette()
+
+// ReadPreviewOptions reads the options for the preview images from the user input file.
+func ReadPreviewOptions(inputFile string) error {
+	var mcfdInput mcfdPreviewInputStruct
+	if _, err := toml.DecodeFile(inputFile, &mcfdInput); err != nil {
+		return err
+	}
+	for _, camera := range mcfdInput.PreviewViews {
+		found := false
+		for _, c := range mcview.Cameras {
+			found = found || c == camera
+		}
+		if !found {
+			return fmt.Errorf("PreviewViews: unknown camera %q, one of %v", camera,
+				strings.Join(mcview.Cameras, " "))
+		}
+	}
+	if mcfdInput.PreviewDir == "" {
+		mcfdInput.PreviewDir = "previews"
+	}
+	if mcfdInput.PreviewBlockSize <= 0 {
+		mcfdInput.PreviewBlockSize = 16
+	}
+	if len(mcfdInput.PreviewColorBlock) != len(mcfdInput.PreviewColor) {
+		return fmt.Errorf("PreviewColorBlock and PreviewColor " +
+			"must have the same number of array values")
+	}
+	palette := mcview.DefaultPalette()
+	for i, block := range mcfdInput.PreviewColorBlock {
+		if err := palette.SetColor(block, mcfdInput.PreviewColor[i]); err != nil {
+			return fmt.Errorf("PreviewColor: %v", err)
+		}
+	}
+	previewOptions = mcfdInput
+	previewPalette = palette
+	return nil
+}
+
+// PreviewFunction draws the blocks placed by the commands of a function from all the
+// PreviewViews cameras.
+func PreviewFunction(f *mcfdFunctionFile) error {
+	if len(previewOptions.PreviewViews) == 0 {
+		return nil
+	}
+	m, err := f.model()
+	if err != nil {
+		return fmt.Errorf("preview %v", err)
+	} else if m == nil {
+		return nil
+	}
+
+	dir, filename := f.dir, f.filename
+	pdir := path.Join(previewOptions.PreviewDir, dir)
+	if err := os.MkdirAll(pdir, 0755); err != nil {
+		return fmt.Errorf("preview mkdir %v: %v", pdir, err)
+	}
+	base := strings.TrimSuffix(filename, ".mcfunction")
+	for _, camera := range previewOptions.PreviewViews {
+		v := mcview.NewView(mcview.WithViewCamera(camera),
+			mcview.WithViewPalette(previewPalette),
+			mcview.WithViewBlockSize(previewOptions.PreviewBlockSize))
+		img, err := v.Render(m)
+		if err != nil {
+			return fmt.Errorf("preview %v/%v: %v", dir, filename, err)
+		}
+		if err := writePNG(path.Join(pdir, base+"_"+camera+".png"), img); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+)
+
+//**************************************************************************************************
+//**************************************************************************************************
+// Undoing a build
+//
+// The _rm functions written by the generators only approximate what was there before, they
+// fill with air or put dirt back. With SnapshotUndo every function copies the blocks it is
+// about to build over to a backup area before building, and a matching undo function copies
+// them back,
+//    mw_NWE_15_10.mcfunction        clone the region to the backup area, then build
+//    mw_NWE_15_10_undo.mcfunction   clone the backup back to where it was built
+//    mw_NWE_15_10_rm.mcfunction     function mcfd:MWall/mw_NWE_15_10_undo
+// This works the same for every generator, the region is the box around everything the
+// function places.
+//
+// The build leaves a marker entity where it was built, so the undo can be run from anywhere.
+// Marker entities came in Minecraft 1.17, so SnapshotUndo needs FunctionVersion 1.17 or later.
+// Every function has its own slot in the backup area, along +X from SnapshotOrigin. The slots
+// are kept by function id in mcfd_snapshot_slots.json in the functions directory, so a function
+// keeps its slot when the functions are written again, with other generators or other builds,
+// and the undo functions of earlier runs still find their backups. A function whose region
+// grows gets a new slot after the others. Building the same function again before undoing the
+// first one overwrites the backup, only the last build can be undone.
+//
+// The backup area must be loaded when the functions are run, put it in the spawn chunks or
+// run /forceload add for it once. Everything in the backup area is overwritten.
+//**************************************************************************************************
+//**************************************************************************************************
+
+// Structure for using TOML to extract input from the user.
+//    SnapshotUndo     Back up the region of every build and write undo functions
+//    SnapshotOrigin   Absolute x, y, z of the backup area, e.g. [10000, 0, 10000]
+type mcfdSnapshotInputStruct struct {
+	SnapshotUndo   bool  `toml:"SnapshotUndo"`
+	SnapshotOrigin []int `toml:"SnapshotOrigin"`
+}
+
+// Options for undoing builds, read from the user input file by ReadSnapshotOptions.
+var snapshotOptions mcfdSnapshotInputStruct
+
+// Marker entities, used to find where a build was, came in Minecraft 1.17.
+const snapshotVersion = 17
+
+// The file in the functions directory with the slots of the functions in the backup area.
+const snapshotSlotsFile = "mcfd_snapshot_slots.json"
+
+// snapshotSlot is the slot of one function in the backup area, X is relative to
+// SnapshotOrigin and Width is the room along X it has.
+type snapshotSlot struct {
+	X     int `json:"x"`
+	Width int `json:"width"`
+}
+
+// The slots by function id, read from snapshotSlotsFile in the functions directory
+// snapshotSlotsDir by snapshotSlotX. snapshotSlotsChanged is set when a function got a new
+// slot, see WriteSnapshotSlots.
+var (
+	snapshotSlots        map[string]snapshotSlot
+	snapshotSlotsDir     string
+	snapshotSlotsChanged bool
+)
+
+// ReadSnapshotOptions reads the options for undoing builds from the user input file.
+func ReadSnapshotOptions(inputFile string) error {
+	var mcfdInput mcfdSnapshotInputStruct
+	if _, err := toml.DecodeFile(inputFile, &mcfdInput); err != nil {
+		return err
+	}
+	if mcfdInput.SnapshotUndo && len(mcfdInput.SnapshotOrigin) != 3 {
+		return fmt.Errorf("SnapshotOrigin must be the x, y, z of the backup area")
+	}
+	if mcfdInput.SnapshotUndo {
+		version, err := readFunctionVersion(inputFile)
+		if err != nil {
+			return err
+		}
+		if version < snapshotVersion {
+			return fmt.Errorf("SnapshotUndo needs FunctionVersion 1.%d or later, the undo "+
+				"functions find the build with a marker entity", snapshotVersion)
+		}
+	}
+	snapshotOptions = mcfdInput
+	snapshotSlots = nil
+	return nil
+}
+
+// snapshotSlotX returns the X of the slot of the function id in the backup area, relative to
+// SnapshotOrigin, for a region width blocks long along X. The function keeps the slot it had
+// in the functions directory basepath if the region fits, otherwise it gets a new slot after
+// all the others.
+func snapshotSlotX(basepath string, id string, width int) (int, error) {
+	if snapshotSlots == nil || snapshotSlotsDir != basepath {
+		slots := make(map[string]snapshotSlot)
+		data, err := os.ReadFile(path.Join(basepath, snapshotSlotsFile))
+		if err == nil {
+			err = json.Unmarshal(data, &slots)
+		} else if os.IsNotExist(err) {
+			err = nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("%v: %v", snapshotSlotsFile, err)
+		}
+		snapshotSlots, snapshotSlotsDir, snapshotSlotsChanged = slots, basepath, false
+	}
+	if slot, ok := snapshotSlots[id]; ok && slot.Width >= width {
+		return slot.X, nil
+	}
+
+	// One empty block between the slots
+	next := 0
+	for _, slot := range snapshotSlots {
+		if slot.X+slot.Width+1 > next {
+			next = slot.X + slot.Width + 1
+		}
+	}
+	snapshotSlots[id] = snapshotSlot{X: next, Width: width}
+	snapshotSlotsChanged = true
+	return next, nil
+}
+
+// WriteSnapshotSlots writes the slots of the functions in the backup area to the functions
+// directory basepath when a function got a new slot.
+func WriteSnapshotSlots(basepath string) error {
+	if !snapshotSlotsChanged || snapshotSlotsDir != basepath {
+		return nil
+	}
+	data, err := json.MarshalIndent(snapshotSlots, "", "  ")
+	if err != nil {
+		return fmt.Errorf("WriteSnapshotSlots: %v", err)
+	}
+	if err := writeFile(path.Join(basepath, snapshotSlotsFile), append(data, '\n')); err != nil {
+		return fmt.Errorf("WriteSnapshotSlots: %v", err)
+	}
+	snapshotSlotsChanged = false
+	return nil
+}
+
+// SnapshotFunction adds the backup of the build region to the commands of a function and
+// writes its undo function. The commands of a _rm function are replaced by a call to the
+// undo function of its build.
+func SnapshotFunction(basepath string, dir string, filename string, lines []string) ([]string,
+	error) {
+	base := strings.TrimSuffix(filename, ".mcfunction")
+	if !snapshotOptions.SnapshotUndo || strings.HasSuffix(base, "_undo") {
+		return lines, nil
+	}
+	if strings.HasSuffix(base, "_rm") {
+		undo := FunctionID(basepath, dir, strings.TrimSuffix(base, "_rm")+"_undo")
+		return []string{"function " + undo + "\n"}, nil
+	}
+
+	m, err := mcshapes.ReadModel(strings.NewReader(strings.Join(lines, "")))
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %v/%v: %v", dir, filename, err)
+	}
+	if m.Len() == 0 {
+		return lines, nil
+	}
+
+	// The slot for this function in the backup area
+	lo, hi := m.Bounds()
+	x, err := snapshotSlotX(basepath, FunctionID(basepath, dir, base), hi.X-lo.X+1)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %v/%v: %v", dir, filename, err)
+	}
+	o := snapshotOptions.SnapshotOrigin
+	slot := mcshapes.XYZ{X: o[0] + x, Y: o[1], Z: o[2]}
+
+	markers := "@e[type=minecraft:marker,tag=mcfd_undo_" + base + "]"
+	marker := "@e[type=minecraft:marker,tag=mcfd_undo_" + base + ",limit=1]"
+	backup := []string{
+		"kill " + markers + "\n",
+		fmt.Sprintf("summon minecraft:marker ~ ~ ~ {Tags:[\"mcfd_undo_%s\"]}\n", base),
+	}
+	var undo bytes.Buffer
+	region := mcshapes.NewBox(mcshapes.WithCorner1(lo), mcshapes.WithCorner2(hi))
+	for _, b := range region.Split(mcshapes.MaxFillBlocks) {
+		c1, c2 := b.Corners()
+		to := mcshapes.XYZ{X: slot.X + c1.X - lo.X, Y: slot.Y + c1.Y - lo.Y,
+			Z: slot.Z + c1.Z - lo.Z}
+		backup = append(backup, fmt.Sprintf("clone ~%d ~%d ~%d ~%d ~%d ~%d %d %d %d\n",
+			c1.X, c1.Y, c1.Z, c2.X, c2.Y, c2.Z, to.X, to.Y, to.Z))
+		fmt.Fprintf(&undo, "execute at %s run clone %d %d %d %d %d %d ~%d ~%d ~%d\n", marker,
+			to.X, to.Y, to.Z, to.X+c2.X-c1.X, to.Y+c2.Y-c1.Y, to.Z+c2.Z-c1.Z, c1.X, c1.Y, c1.Z)
+	}
+	fmt.Fprintf(&undo, "kill %s\n", markers)
+
+	f, err := CreateFunctionFile(basepath, dir, base+"_undo.mcfunction")
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %v/%v: %v", dir, filename, err)
+	}
+	if _, err := undo.WriteTo(f); err != nil {
+		return nil, fmt.Errorf("snapshot %v/%v: %v", dir, filename, err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	return append(backup, lines...), nil
+}
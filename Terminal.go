@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	mcview "github.com/GreenSeaTurtle/mcFunctionDev/mcView"
+)
+
+//**************************************************************************************************
+//**************************************************************************************************
+// Terminal preview
+//
+//    mcFunctionDev preview [-y <Y>] [-step] [-color] [-input <file>] [-only <generators>] <function> ...
+//
+// A quick look at a function over SSH, without the game or an image viewer. The blocks are
+// printed one Y layer at a time, seen from above with north at the top, each block type as a
+// letter and, with -color, on a background of its color. See mcview.Plan.WriteText.
+//    -y      print only the layer at relative Y
+//    -step   print one layer at a time, Enter goes up a layer, b back down, a number goes to
+//            that Y and q quits
+// A function is a function file, e.g.
+//    mcFunctionDev preview ~/saves/world/datapacks/mcfd/data/mcfd/functions/Falls/waterfall_NWE_10_7.mcfunction
+// or the name of a function the input file makes, e.g.
+//    mcFunctionDev preview -input all.input Falls/waterfall_NWE_10_7
+// The input file is run the same as always, but into a temporary directory, so nothing in
+// the world changes. Only the functions are printed, not the tables of the generators. With
+// -only just those generators are run, which is faster, e.g. -only falls.
+//**************************************************************************************************
+//**************************************************************************************************
+
+// PreviewCommand runs the preview command with the arguments after "preview".
+func PreviewCommand(args []string) error {
+	flags := flag.NewFlagSet("preview", flag.ContinueOnError)
+	y := flags.String("y", "", "print only the layer at this relative Y")
+	step := flags.Bool("step", false, "print one layer at a time")
+	ansi := flags.Bool("color", false, "color the blocks with ANSI colors")
+	input := flags.String("input", "all.input", "input file for functions that are not files")
+	only := flags.String("only", "", "generators to run for functions that are not files (default all)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(),
+			"usage: mcFunctionDev preview [-y <Y>] [-step] [-color] [-input <file>] [-only <generators>] <function> ...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("preview needs function files or names")
+	}
+
+	// Functions that are not files are made from the input file
+	selected, err := selectGenerators(*only)
+	if err != nil {
+		return err
+	}
+	generated := ""
+	for _, fname := range flags.Args() {
+		if _, err := os.Stat(fname); err == nil {
+			continue
+		}
+		var cleanup func()
+		generated, cleanup, err = generateFunctions(*input, selected)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		break
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	for _, fname := range flags.Args() {
+		if _, err := os.Stat(fname); err != nil {
+			found, err := findFunction(generated, fname)
+			if err != nil {
+				return err
+			}
+			fname = found
+		}
+		m, err := ReadFunctionModel(fname)
+		if err != nil {
+			return err
+		}
+
+		plan := mcview.NewPlan(m, mcview.WithPalette(previewPalette))
+		ys := plan.Ys()
+		fmt.Printf("%v\n", strings.TrimSuffix(path.Base(fname), ".mcfunction"))
+		if len(ys) == 0 {
+			fmt.Println("places no blocks")
+			continue
+		}
+		if *y != "" {
+			ly, err := strconv.Atoi(strings.TrimPrefix(*y, "~"))
+			if err != nil {
+				return fmt.Errorf("preview -y %v: %v", *y, err)
+			}
+			ys = []int{ly}
+		}
+		if !*step {
+			for _, ly := range ys {
+				if err := plan.WriteText(os.Stdout, ly, *ansi); err != nil {
+					return err
+				}
+				fmt.Println()
+			}
+			continue
+		}
+		if err := stepLayers(in, plan, ys, *ansi); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stepLayers prints the layers ys one at a time, reading what to do next from in
+func stepLayers(in *bufio.Reader, plan *mcview.Plan, ys []int, ansi bool) error {
+	for i := 0; i >= 0 && i < len(ys); {
+		if err := plan.WriteText(os.Stdout, ys[i], ansi); err != nil {
+			return err
+		}
+		fmt.Printf("layer %d of %d, Enter up, b down, Y number, q quit: ", i+1, len(ys))
+		line, err := in.ReadString('\n')
+		if err != nil {
+			fmt.Println()
+			return nil
+		}
+		switch line = strings.TrimSpace(line); line {
+		case "":
+			i++
+		case "b":
+			if i > 0 {
+				i--
+			}
+		case "q":
+			return nil
+		default:
+			ly, err := strconv.Atoi(strings.TrimPrefix(line, "~"))
+			if err != nil {
+				fmt.Printf("%q is not a Y\n", line)
+				continue
+			}
+			// The layer at or above ly
+			i = len(ys) - 1
+			for j, y := range ys {
+				if y >= ly {
+					i = j
+					break
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// generateFunctions runs the selected generators with the input file in a temporary
+// directory, with the output of the generators thrown away. It returns the functions
+// directory and a function to remove the temporary directory.
+func generateFunctions(inputFile string, selected []generator) (string, func(), error) {
+	// Checked here so the problems are given with the input file as it was named
+	if err := ValidateInput(inputFile, selected); err != nil {
+		return "", nil, err
+	}
+	input, err := filepath.Abs(inputFile)
+	if err != nil {
+		return "", nil, err
+	}
+	tmp, err := os.MkdirTemp("", "mcfd-generate")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmp) }
+
+	// Everything the drivers write goes in the temporary directory, the function files, the
+	// STL files and the exports. The directories of the generators are made by runDrivers.
+	cwd, err := os.Getwd()
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	basepath := path.Join(tmp, "data", "mcfd", "functions")
+	for _, dir := range []string{path.Join(tmp, "stlFiles"), basepath} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			cleanup()
+			return "", nil, err
+		}
+	}
+	if err := os.Chdir(tmp); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	stdout := os.Stdout
+	if devnull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0); err == nil {
+		os.Stdout = devnull
+		defer devnull.Close()
+	}
+	err = runDrivers(input, basepath, selected)
+	os.Stdout = stdout
+	if cerr := os.Chdir(cwd); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("%v: %w", inputFile, err)
+	}
+	return basepath, cleanup, nil
+}
+
+// findFunction returns the function file for a function name, Falls/waterfall_NWE_10_7 or
+// just waterfall_NWE_10_7, made in the functions directory dir.
+func findFunction(dir string, name string) (string, error) {
+	name = strings.TrimSuffix(name, ".mcfunction")
+	var found []string
+	err := filepath.Walk(dir, func(fname string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(fname, ".mcfunction") {
+			return err
+		}
+		rel := strings.TrimSuffix(filepath.ToSlash(fname[len(dir)+1:]), ".mcfunction")
+		if rel == name || path.Base(rel) == name {
+			found = append(found, fname)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("preview: %v is not a file or a function the input file makes", name)
+	case 1:
+		return found[0], nil
+	}
+	return "", fmt.Errorf("preview: %v is %d functions, give the directory too", name, len(found))
+}
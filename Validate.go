@@ -0,0 +1,493 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+)
+
+//**************************************************************************************************
+//**************************************************************************************************
+// Checking the input file
+//
+// The whole input file is checked before anything is written, so a mistake in the last
+// sphere does not leave half of the functions in the world. Every problem is collected, with
+// where it is in the file and how to fix it, e.g.
+//    all.input:112:1: sphere[2].radius: the radius is 0, it must be at least 1
+//        fix: give the radius in blocks, e.g. radius = 5
+// Entries are counted from 0, sphere[2] is the third [[sphere]] table and SphereRadius[2] is
+// the third value of the SphereRadius array. Keys that are left out of a table are reported
+// at the [[table]] line. Keys that no generator or option reads are reported too, they are
+// usually spelled wrong.
+//
+// Only the entries of the selected generators are checked, the options are always checked.
+// When the input file has problems mcFunctionDev exits with status 3, see main.
+//**************************************************************************************************
+//**************************************************************************************************
+
+// InputProblem is one problem in the input file.
+type InputProblem struct {
+	File    string
+	Line    int
+	Col     int
+	Key     string
+	Message string
+	Hint    string
+}
+
+// String returns the problem as file:line:col: key: message, with the hint on the next line.
+func (p InputProblem) String() string {
+	s := p.File
+	if p.Line > 0 {
+		s += fmt.Sprintf(":%d:%d", p.Line, p.Col)
+	}
+	s += ": "
+	if p.Key != "" {
+		s += p.Key + ": "
+	}
+	s += p.Message
+	if p.Hint != "" {
+		s += "\n    fix: " + p.Hint
+	}
+	return s
+}
+
+// InputError is returned for an input file with problems.
+type InputError struct {
+	File     string
+	Problems []InputProblem
+}
+
+func (e *InputError) Error() string {
+	lines := make([]string, 0, len(e.Problems)+1)
+	for _, p := range e.Problems {
+		lines = append(lines, p.String())
+	}
+	problems := "problems"
+	if len(e.Problems) == 1 {
+		problems = "problem"
+	}
+	lines = append(lines, fmt.Sprintf("%d %v in %v, nothing was written", len(e.Problems),
+		problems, e.File))
+	return strings.Join(lines, "\n")
+}
+
+// inputKey is a key in the input file. It is a key at the top of the file when index is -1,
+// a value of an array of the old input form when table is "", or else a key of a table of
+// a generator, "" for the table itself.
+type inputKey struct {
+	table string
+	index int
+	key   string
+}
+
+// topKey is a key at the top of the input file
+func topKey(key string) inputKey {
+	return inputKey{index: -1, key: key}
+}
+
+func (k inputKey) String() string {
+	switch {
+	case k.index < 0:
+		return k.key
+	case k.table == "":
+		return fmt.Sprintf("%v[%d]", k.key, k.index)
+	case k.key == "":
+		return fmt.Sprintf("%v[%d]", k.table, k.index)
+	}
+	return fmt.Sprintf("%v[%d].%v", k.table, k.index, k.key)
+}
+
+// entryKeys gives the keys of the entries of a generator, see Input.go. The first n entries
+// are from the old arrays, arrays has the array of each key of the tables.
+type entryKeys struct {
+	table  string
+	n      int
+	arrays map[string]string
+}
+
+// key returns the key of the i-th entry for the table key
+func (e entryKeys) key(i int, key string) inputKey {
+	if i < e.n && e.arrays[key] != "" {
+		return inputKey{index: i, key: e.arrays[key]}
+	}
+	if i < e.n {
+		return inputKey{index: i, key: key}
+	}
+	return inputKey{table: e.table, index: i - e.n, key: key}
+}
+
+// inputCheck collects the problems of an input file.
+type inputCheck struct {
+	file     string
+	data     string
+	lines    []string
+	known    map[string]bool
+	names    map[string]map[string]inputKey
+	problems []InputProblem
+
+	// quiet is set while the entries of the generators that are not selected are decoded,
+	// only problems with decoding them are added.
+	quiet bool
+}
+
+// A key = value line of the input file, the key may be quoted
+var inputKeyLine = regexp.MustCompile(`^\s*"?([A-Za-z0-9_\-]+)"?\s*=`)
+
+// The hint for a value of the wrong type
+const typeHint = "check the type of the value, e.g. numbers without quotes"
+
+// A decode error of the TOML package, with the line and the key
+var decodeError = regexp.MustCompile(`^toml: line (\d+) \(last key "([^"]*)"\): (.*)$`)
+
+// find returns the line and column of a key, 0 if it is not there, and the line of the
+// table it is in, 0 if it is a key at the top of the file or the table is not there.
+func (c *inputCheck) find(k inputKey) (int, int, int) {
+	section, count := "", map[string]int{}
+	tableLine := 0
+	for n, line := range c.lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			section = strings.Trim(strings.SplitN(trimmed, "#", 2)[0], "[] \t")
+			count[section]++
+			if k.table != "" && section == k.table && count[section]-1 == k.index {
+				tableLine = n + 1
+			}
+			continue
+		}
+		m := inputKeyLine.FindStringSubmatch(line)
+		if m == nil || m[1] != k.key {
+			continue
+		}
+		if (k.table == "" && section == "") ||
+			(k.table != "" && section == k.table && count[section]-1 == k.index) {
+			return n + 1, strings.Index(line, m[1]) + 1, tableLine
+		}
+	}
+	return 0, 0, tableLine
+}
+
+// position returns the line and column of a key, or of the table it is in when the key is
+// not there. It is 0, 0 when neither is found.
+func (c *inputCheck) position(k inputKey) (int, int) {
+	line, col, tableLine := c.find(k)
+	if line == 0 && tableLine > 0 {
+		return tableLine, 1
+	}
+	return line, col
+}
+
+// push adds a problem, once
+func (c *inputCheck) push(p InputProblem) {
+	for _, q := range c.problems {
+		if q.Line == p.Line && q.Key == p.Key && q.Message == p.Message {
+			return
+		}
+	}
+	c.problems = append(c.problems, p)
+}
+
+// add adds a problem with the key k
+func (c *inputCheck) add(k inputKey, message string, hint string) {
+	if c.quiet {
+		return
+	}
+	line, col := c.position(k)
+	c.push(InputProblem{File: c.file, Line: line, Col: col, Key: k.String(), Message: message,
+		Hint: hint})
+}
+
+// addError adds a problem for an error of the TOML package or of reading the options. The
+// position and the key are taken from the error when it has them.
+func (c *inputCheck) addError(err error, hint string) {
+	var perr toml.ParseError
+	if errors.As(err, &perr) {
+		line := perr.Position.Line
+		// Start is from the start of the file
+		col := perr.Position.Start + 1
+		for n := 0; n < line-1 && n < len(c.lines); n++ {
+			col -= len(c.lines[n]) + 1
+		}
+		if col < 1 {
+			col = 1
+		}
+		message := strings.TrimPrefix(perr.Error(), "toml: ")
+		if m := decodeError.FindStringSubmatch(perr.Error()); m != nil {
+			message = m[3]
+		}
+		c.push(InputProblem{File: c.file, Line: line, Col: col, Key: perr.LastKey,
+			Message: message, Hint: perr.Usage})
+		return
+	}
+	if m := decodeError.FindStringSubmatch(err.Error()); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		if hint == "" {
+			hint = typeHint
+		}
+		key, col := c.keyAt(line, m[2])
+		c.push(InputProblem{File: c.file, Line: line, Col: col, Key: key.String(),
+			Message: m[3], Hint: hint})
+		return
+	}
+	// The options name the key first, e.g. "ExportSchematicVersion must be 2 or 3, not 4"
+	key := strings.Fields(err.Error() + " ")[0]
+	if line, col := c.position(topKey(key)); line > 0 {
+		c.push(InputProblem{File: c.file, Line: line, Col: col, Key: key,
+			Message: strings.TrimPrefix(err.Error(), key+" "), Hint: hint})
+		return
+	}
+	c.push(InputProblem{File: c.file, Message: err.Error(), Hint: hint})
+}
+
+// keyAt returns the key of the TOML package, e.g. "sphere.radius", on a line of the input
+// file with its column. The index of the table is the number of [[table]] lines before it.
+func (c *inputCheck) keyAt(line int, last string) (inputKey, int) {
+	k := topKey(last)
+	if i := strings.Index(last, "."); i >= 0 {
+		k = inputKey{table: last[:i], index: -1, key: last[i+1:]}
+		for n := 0; n < line-1 && n < len(c.lines); n++ {
+			if strings.TrimSpace(c.lines[n]) == "[["+k.table+"]]" {
+				k.index++
+			}
+		}
+	}
+	col := 1
+	if line >= 1 && line <= len(c.lines) {
+		col = strings.Index(c.lines[line-1], k.key) + 1
+	}
+	if k.index < 0 {
+		k = topKey(last)
+	}
+	return k, col
+}
+
+// decode decodes the input file into v, it reports false if that fails. The keys of v are
+// known keys, see unknownKeys.
+func (c *inputCheck) decode(v interface{}) bool {
+	c.addKeys("", reflect.TypeOf(v).Elem())
+	if _, err := toml.Decode(c.data, v); err != nil {
+		c.addError(err, typeHint)
+		return false
+	}
+	return true
+}
+
+// addKeys adds the keys of the fields of the struct t to the known keys, the keys of its
+// tables start with prefix. Any key of a map is known. The TOML package matches keys in any
+// case, so they are kept in lower case.
+func (c *inputCheck) addKeys(prefix string, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			// Not exported, the TOML package does not decode it
+			continue
+		}
+		name := strings.Split(f.Tag.Get("toml"), ",")[0]
+		if name == "" {
+			name = f.Name
+		}
+		key := strings.ToLower(prefix + name)
+		c.known[key] = true
+		ft := f.Type
+		for ft.Kind() == reflect.Slice || ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		switch ft.Kind() {
+		case reflect.Struct:
+			c.addKeys(key+".", ft)
+		case reflect.Map:
+			c.known[key+".*"] = true
+		}
+	}
+}
+
+// unknownKeys adds a problem for every key that is not a key of anything that is decoded.
+// A key of the tables of a generator is reported for every table that has it.
+func (c *inputCheck) unknownKeys() {
+	var all map[string]interface{}
+	md, err := toml.Decode(c.data, &all)
+	if err != nil {
+		return
+	}
+	seen := make(map[string]bool)
+	for _, k := range md.Keys() {
+		key := strings.ToLower(strings.Join(k, "."))
+		if seen[key] || c.knownKey(k) {
+			continue
+		}
+		seen[key] = true
+		// Only the keys in a table are reported, not the table
+		if t := md.Type(k...); t == "Hash" || t == "ArrayHash" {
+			continue
+		}
+		problem := InputProblem{File: c.file, Message: "unknown key, nothing reads it",
+			Hint: "check the spelling"}
+		switch {
+		case len(k) == 1:
+			problem.Key = k[0]
+			problem.Line, problem.Col = c.position(topKey(k[0]))
+			c.push(problem)
+		case md.Type(k[0]) == "Hash":
+			problem.Key = strings.Join(k, ".")
+			problem.Line, problem.Col, _ = c.find(inputKey{table: k[0], key: k[len(k)-1]})
+			c.push(problem)
+		default:
+			for i := 0; ; i++ {
+				key := inputKey{table: k[0], index: i, key: k[1]}
+				line, col, tableLine := c.find(key)
+				if tableLine == 0 {
+					break
+				}
+				if line > 0 {
+					problem.Key = key.String()
+					problem.Line, problem.Col = line, col
+					c.push(problem)
+				}
+			}
+		}
+	}
+}
+
+// knownKey reports whether k is a known key or in a known map
+func (c *inputCheck) knownKey(k toml.Key) bool {
+	for i := len(k); i > 0; i-- {
+		key := strings.ToLower(strings.Join(k[:i], "."))
+		if (i == len(k) && c.known[key]) || c.known[key+".*"] {
+			return true
+		}
+	}
+	return false
+}
+
+// atLeast adds a problem if the value v of k is less than min
+func (c *inputCheck) atLeast(k inputKey, what string, v int, min int, hint string) {
+	if v < min {
+		c.add(k, fmt.Sprintf("the %v is %d, it must be at least %d", what, v, min), hint)
+	}
+}
+
+// block adds a problem if the block of k is not a block of the FunctionVersion, checked the
+// same as lint checks the functions for its target, "none" is allowed if none is set.
+func (c *inputCheck) block(k inputKey, block string, none bool) {
+	if none && block == "none" {
+		return
+	}
+	surface := block
+	if !strings.Contains(strings.Fields(block + " ")[0], ":") {
+		surface = "minecraft:" + block
+	}
+	if functionOptions.version >= 13 {
+		// The functions get the new names, see mcshapes.ModernCommand
+		surface = mcshapes.BlockString(surface)
+	}
+	l := &linter{version: functionOptions.version}
+	problems := l.lintBlock(surface)
+	if len(problems) == 0 {
+		return
+	}
+	hint := "give a Minecraft block without minecraft:, e.g. glass or \"log 1\""
+	if functionOptions.version <= 12 {
+		hint += ", or set FunctionVersion for newer blocks"
+	}
+	if none {
+		hint += ", or \"none\""
+	}
+	c.add(k, fmt.Sprintf("%q is not a Minecraft block, %v", block, problems[0]), hint)
+}
+
+// A name of a function, Minecraft only allows these characters
+var functionName = regexp.MustCompile(`^[a-z0-9_.\-]+$`)
+
+// name adds a problem if the name of an entry can not be in a function name, or if another
+// entry of the generator table has it.
+func (c *inputCheck) name(table string, k inputKey, name string) {
+	if name == "" {
+		return
+	}
+	if !functionName.MatchString(name) {
+		c.add(k, fmt.Sprintf("%q can not be in a function name", name),
+			"use lowercase letters, digits, _, - and .")
+		return
+	}
+	if c.names[table] == nil {
+		c.names[table] = make(map[string]inputKey)
+	}
+	if other, ok := c.names[table][name]; ok {
+		c.add(k, fmt.Sprintf("%v also has the name %q, the functions would overwrite each "+
+			"other", other, name), "give every entry its own name")
+		return
+	}
+	c.names[table][name] = k
+}
+
+// ValidateInput checks the options in the input file and the entries of the selected
+// generators, it returns an *InputError with all the problems.
+func ValidateInput(inputFile string, selected []generator) error {
+	data, err := os.ReadFile(inputFile)
+	if err != nil {
+		return err
+	}
+	c := &inputCheck{file: inputFile, data: string(data), lines: strings.Split(string(data), "\n"),
+		known: make(map[string]bool), names: make(map[string]map[string]inputKey)}
+
+	var all map[string]interface{}
+	if _, err := toml.Decode(c.data, &all); err != nil {
+		c.addError(err, "")
+		return &InputError{File: inputFile, Problems: c.problems}
+	}
+
+	// The options of all the generators
+	options := []func(string) error{ReadFunctionOptions, ReadExportOptions, ReadSnapshotOptions,
+		ReadBOMOptions, ReadPreviewOptions}
+	for _, read := range options {
+		if err := read(inputFile); err != nil {
+			c.addError(err, "")
+		}
+	}
+	for _, v := range []interface{}{&mcfdFunctionInputStruct{}, &mcfdExportInputStruct{},
+		&mcfdSnapshotInputStruct{}, &mcfdBOMInputStruct{}, &mcfdPreviewInputStruct{},
+		&mcfdTagsInputStruct{}} {
+		c.decode(v)
+	}
+
+	// The entries of the selected generators, the others are only decoded so their keys
+	// are known
+	for _, g := range generators {
+		c.quiet = true
+		for _, s := range selected {
+			if s.name == g.name {
+				c.quiet = false
+			}
+		}
+		g.check(c)
+	}
+	c.quiet = false
+	c.unknownKeys()
+
+	// In the order of the file, the problems without a line first
+	sort.SliceStable(c.problems, func(i, j int) bool {
+		return c.problems[i].Line < c.problems[j].Line
+	})
+	if len(c.problems) > 0 {
+		return &InputError{File: inputFile, Problems: c.problems}
+	}
+	return nil
+}
+
+// importable reports whether a file can be imported, see ReadImportFile
+func importable(file string) bool {
+	switch strings.ToLower(path.Ext(file)) {
+	case ".nbt", ".schem", ".mcfunction":
+		return true
+	}
+	return false
+}
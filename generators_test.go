@@ -0,0 +1,878 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+)
+
+// The player position used for the tests, somewhere in a normal world
+var testPlayer = mcshapes.XYZ{X: 100, Y: 64, Z: -200}
+
+// testBasepath creates a functions directory for the generators to write to, with the
+// namespace mcfd.
+func testBasepath(t *testing.T, dirs ...string) string {
+	basepath := path.Join(t.TempDir(), "mcfd", "functions")
+	for _, dir := range dirs {
+		if err := os.MkdirAll(path.Join(basepath, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return basepath
+}
+
+// runFunction runs a function written by a generator in a new world and returns the world
+func runFunction(t *testing.T, basepath string, dir string, filename string,
+	facing string) *mcshapes.World {
+	w := mcshapes.NewWorld(mcshapes.WithPlayer(testPlayer), mcshapes.WithFacing(facing))
+	w.Load = func(id string) ([]string, error) {
+		i := strings.Index(id, ":")
+		data, err := os.ReadFile(path.Join(basepath, id[i+1:]+".mcfunction"))
+		if err != nil {
+			return nil, err
+		}
+		return strings.Split(string(data), "\n"), nil
+	}
+	if err := w.RunFunction(FunctionID(basepath, dir, filename)); err != nil {
+		t.Fatalf("run %v/%v: %v", dir, filename, err)
+	}
+	return w
+}
+
+// at returns a position relative to the test player
+func at(x int, y int, z int) mcshapes.XYZ {
+	return mcshapes.XYZ{X: testPlayer.X + x, Y: testPlayer.Y + y, Z: testPlayer.Z + z}
+}
+
+// Test that a waterfall has lava under glass under water, for every width of the falls
+func TestWaterfall(t *testing.T) {
+	obj := mcshapes.NewMCObject(mcshapes.WithOrientation("north"),
+		mcshapes.WithType("waterfall"), mcshapes.WithWidth(10), mcshapes.WithHeight(7))
+	var buf bytes.Buffer
+	err := mcshapes.WriteShapes(&buf, CreateWaterfall(mcshapes.XYZ{X: 0, Y: 0, Z: -2}, obj))
+	if err != nil {
+		t.Fatalf("WriteShapes: %v", err)
+	}
+	w := mcshapes.NewWorld(mcshapes.WithPlayer(testPlayer))
+	if err := w.RunLines(strings.Split(buf.String(), "\n")); err != nil {
+		t.Fatalf("RunLines: %v", err)
+	}
+
+	for x := 1; x <= 8; x++ {
+		lava := w.Block(at(x, 5, -5))
+		glass := w.Block(at(x, 6, -5))
+		water := w.Block(at(x, 7, -5))
+		if mcshapes.BlockName(lava) != "minecraft:lava" ||
+			mcshapes.BlockName(glass) != "minecraft:glass" ||
+			mcshapes.BlockName(water) != "minecraft:water" {
+			t.Errorf("x %d: expected lava under glass under water, got %v, %v, %v",
+				x, lava, glass, water)
+		}
+	}
+
+	// The basin in front of the falls
+	if b := mcshapes.BlockName(w.Block(at(0, 0, -2))); b != "minecraft:sandstone" {
+		t.Errorf("expected a sandstone basin, got %v", b)
+	}
+}
+
+// Test an M wall facing east, and that its _rm function clears it again
+func TestMWall(t *testing.T) {
+	basepath := testBasepath(t, "MWall")
+	err := CreateMWall(basepath, "mw_ENS_15_10.mcfunction", "east", 15, 10, 1,
+		"log 1", "monster_egg 2")
+	if err != nil {
+		t.Fatalf("CreateMWall: %v", err)
+	}
+	w := runFunction(t, basepath, "MWall", "mw_ENS_15_10.mcfunction", "east")
+
+	// Facing east the wall starts 2 blocks in front of the player and runs north to south,
+	// with the gold blocks and torches on top of the wood.
+	if b := mcshapes.BlockName(w.Block(at(3, 13, 0))); b != "minecraft:gold_block" {
+		t.Errorf("expected a gold block on the wall, got %v", b)
+	}
+	if b := mcshapes.BlockName(w.Block(at(3, 14, 0))); b != "minecraft:torch" {
+		t.Errorf("expected a torch on the gold block, got %v", b)
+	}
+	if b := mcshapes.BlockName(w.Block(at(2, 1, 0))); b != "minecraft:infested_stone_bricks" {
+		t.Errorf("expected the bricks at the front of the wall, got %v", b)
+	}
+	if b := w.Block(at(2, 1, 10)); b != "" {
+		t.Errorf("expected nothing past the end of the wall, got %v", b)
+	}
+
+	err = RmMWall(basepath, "mw_ENS_15_10_rm.mcfunction", "east", 15, 10, 1)
+	if err != nil {
+		t.Fatalf("RmMWall: %v", err)
+	}
+	rm := runFunction(t, basepath, "MWall", "mw_ENS_15_10_rm.mcfunction", "east")
+	for _, c := range mcshapes.Diff(rm.Model(), w.Model()) {
+		if c.New != "" && !mcshapes.IsAir(c.New) && c.Old == "" {
+			t.Errorf("the _rm function does not clear %v at %v", c.New, c.XYZ)
+			break
+		}
+	}
+}
+
+// Test that a sign has its text in front of the back of the sign
+func TestSign7(t *testing.T) {
+	basepath := testBasepath(t, "Sign7")
+	err := CreateSign7(basepath, "s_N_0.mcfunction", "s_N_0_rm.mcfunction", "north",
+		[]string{"HI", "none", "none"}, "lapis_block", "sea_lantern", "gold_block")
+	if err != nil {
+		t.Fatalf("CreateSign7: %v", err)
+	}
+	w := runFunction(t, basepath, "Sign7", "s_N_0.mcfunction", "north")
+
+	counts := make(map[string]int)
+	for _, xyz := range w.Model().Positions() {
+		if xyz.Z != testPlayer.Z-2 {
+			t.Fatalf("expected the sign 2 blocks in front of the player, got a block at %v", xyz)
+		}
+		counts[mcshapes.BlockName(w.Block(xyz))]++
+	}
+	// H is 17 blocks and I is 11 blocks
+	if counts["minecraft:gold_block"] != 28 {
+		t.Errorf("expected 28 gold blocks for HI, got %d", counts["minecraft:gold_block"])
+	}
+	if counts["minecraft:lapis_block"] == 0 || counts["minecraft:sea_lantern"] == 0 {
+		t.Errorf("expected the back and edges of the sign, got %v", counts)
+	}
+}
+
+// Test that the functions written by the generators pass lint, and that lint finds the
+// mistakes it is for
+func TestLint(t *testing.T) {
+	basepath := testBasepath(t, "MWall", "Lint")
+	err := CreateMWall(basepath, "mw_NWE_15_10.mcfunction", "north", 15, 10, 1,
+		"log 1", "monster_egg 2")
+	if err != nil {
+		t.Fatalf("CreateMWall: %v", err)
+	}
+	problems, err := LintFunction(path.Join(basepath, "MWall", "mw_NWE_15_10.mcfunction"),
+		basepath, 12)
+	if err != nil {
+		t.Fatalf("LintFunction: %v", err)
+	}
+	for _, p := range problems {
+		t.Errorf("unexpected problem: %v", p)
+	}
+
+	// Checking the generator directory, the functions it calls are found in the functions
+	// directory above it
+	call := path.Join(basepath, "MWall", "mw_call.mcfunction")
+	if err := os.WriteFile(call, []byte("function mcfd:MWall/mw_NWE_15_10\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, n, err := LintPath(path.Join(basepath, "MWall"), 12); err != nil || n != 0 {
+		t.Errorf("expected no problems in the MWall directory, got %d, %v", n, err)
+	}
+
+	bad := []string{
+		"fill ~0 ~0 ~-2  ~9 ~0 ~-2 minecraft:stone",
+		"fill ~0 ~0 ~0 ~99 ~9 ~99 minecraft:stone",
+		"setblock ~0 ~300 ~0 minecraft:stone",
+		"setblock ~0 ~0 ~0 minecraft:oak_planks",
+		"setblock ~0 ~0 ~0 minecraft:stone 16",
+		"function mcfd:Lint/missing",
+		"schedule function mcfd:MWall/mw_NWE_15_10 1t",
+	}
+	fname := path.Join(basepath, "Lint", "bad.mcfunction")
+	if err := os.WriteFile(fname, []byte(strings.Join(bad, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	problems, err = LintFunction(fname, basepath, 12)
+	if err != nil {
+		t.Fatalf("LintFunction: %v", err)
+	}
+	if len(problems) != len(bad) {
+		t.Errorf("expected a problem on every line, got %q", problems)
+	}
+
+	// In 1.20 the old block names are a problem, and so are the upper case letters of the
+	// scheduled function and of the file, the schedule itself is not
+	problems, err = LintFunction(fname, basepath, 20)
+	if err != nil {
+		t.Fatalf("LintFunction: %v", err)
+	}
+	named := false
+	for _, p := range problems {
+		if strings.Contains(p, ":7:") && !strings.Contains(p, "lower case") {
+			t.Errorf("unexpected problem in 1.20: %v", p)
+		}
+		if strings.Contains(p, ":5:") && !strings.Contains(p, "data value") {
+			t.Errorf("expected the data value of stone 16 to be a problem, got %v", p)
+		}
+		named = named || strings.HasPrefix(p, fname+": the function file Lint/bad.mcfunction")
+	}
+	if !named {
+		t.Errorf("expected the upper case file name to be a problem in 1.20, got %q", problems)
+	}
+	lower := path.Join(basepath, "lint.mcfunction")
+	if err := os.WriteFile(lower, []byte("function mcfd:lint\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if problems, err = LintFunction(lower, basepath, 20); err != nil || len(problems) != 0 {
+		t.Errorf("expected no problems for lower case ids, got %q, %v", problems, err)
+	}
+
+	// Commands of the newest versions, and commands lint does not know yet
+	exists := func(string) bool { return true }
+	for _, test := range []struct {
+		version  int
+		command  string
+		expected string
+	}{
+		{21, "tick freeze", ""},
+		{19, "tick freeze", "tick is not in Minecraft 1.19"},
+		{21, "rotate @s 90 0", ""},
+		{21, "newcommand 1", "unknown command"},
+		{30, "newcommand 1", lintWarning + "unknown command"},
+	} {
+		l := &linter{version: test.version, exists: exists}
+		problems := l.lintLine(test.command)
+		if test.expected == "" && len(problems) != 0 ||
+			test.expected != "" && (len(problems) != 1 ||
+				!strings.HasPrefix(problems[0], test.expected)) {
+			t.Errorf("1.%d %q: expected %q, got %q", test.version, test.command,
+				test.expected, problems)
+		}
+	}
+}
+
+// Test selecting generators by name for -only
+func TestSelectGenerators(t *testing.T) {
+	all, err := selectGenerators("")
+	if err != nil || len(all) != len(generators) {
+		t.Errorf("expected all %d generators, got %d, %v", len(generators), len(all), err)
+	}
+	selected, err := selectGenerators("Sphere, falls", "falls")
+	if err != nil {
+		t.Fatalf("selectGenerators: %v", err)
+	}
+	if len(selected) != 2 || selected[0].dir != "Falls" || selected[1].dir != "Sphere" {
+		t.Errorf("expected Falls and Sphere in the order they are run, got %v", selected)
+	}
+	if _, err := selectGenerators("falls,waterfalls"); err == nil ||
+		!strings.Contains(err.Error(), "waterfalls") {
+		t.Errorf("expected an error for the unknown generator waterfalls, got %v", err)
+	}
+}
+
+// Test finding the init file and selecting a profile from it
+func TestInitFile(t *testing.T) {
+	fname := path.Join(t.TempDir(), "mcFunctionDev.init")
+	data := "mc_saves_dir = \"/saves\"\n" +
+		"mc_world_functions_dir = \"World/datapacks/mcfd/data/mcfd/functions\"\n" +
+		"[profile.creative-test]\n" +
+		"mc_world_functions_dir = \"Test/datapacks/mcfd/data/mcfd/functions\"\n"
+	if err := os.WriteFile(fname, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(initEnv, fname)
+	t.Setenv(profileEnv, "")
+	found, from, err := FindInitFile("")
+	if err != nil || found != fname || from != "$"+initEnv {
+		t.Errorf("expected %v from $%v, got %v from %v, %v", fname, initEnv, found, from, err)
+	}
+
+	world, err := ReadInitFile(fname, "")
+	if err != nil || world.MCFunctionsDir != "World/datapacks/mcfd/data/mcfd/functions" {
+		t.Errorf("expected the world at the top of the init file, got %+v, %v", world, err)
+	}
+	world, err = ReadInitFile(fname, "creative-test")
+	if err != nil || world.MCSavesDir != "/saves" ||
+		world.MCFunctionsDir != "Test/datapacks/mcfd/data/mcfd/functions" {
+		t.Errorf("expected the creative-test world in /saves, got %+v, %v", world, err)
+	}
+	if _, err := ReadInitFile(fname, "survival"); err == nil {
+		t.Errorf("expected an error for the profile survival that is not there")
+	}
+}
+
+// Test the generator tables with their defaults and the old arrays before them
+func TestInputTables(t *testing.T) {
+	input := "SphereRadius = [5]\n" +
+		"SphereExteriorBlockType = [\"sea_lantern\"]\n" +
+		"SphereInteriorBlockType = [\"none\"]\n" +
+		"[[sphere]]\n" +
+		"radius = 10\n" +
+		"name = \"dome\"\n"
+	var in mcfdControlStruct
+	if _, err := toml.Decode(input, &in); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	spheres, err := in.entries()
+	if err != nil {
+		t.Fatalf("entries: %v", err)
+	}
+	expected := []sphereEntry{
+		{Radius: 5, Exterior: "sea_lantern", Interior: "none"},
+		{Name: "dome", Radius: 10, Exterior: "glass", Interior: "none"},
+	}
+	if len(spheres) != len(expected) {
+		t.Fatalf("expected %d spheres, got %d", len(expected), len(spheres))
+	}
+	for i := range expected {
+		if spheres[i] != expected[i] {
+			t.Errorf("expected sphere %d to be %+v, got %+v", i, expected[i], spheres[i])
+		}
+	}
+
+	in.SphereExteriorBlockType = nil
+	if _, err := in.entries(); err == nil || !strings.Contains(err.Error(), "[[sphere]]") {
+		t.Errorf("expected an error for arrays of different lengths, got %v", err)
+	}
+}
+
+// Test that all the problems in an input file are found, with where they are
+func TestValidateInput(t *testing.T) {
+	input := "FunctionChunkSize = 1000\n" +
+		"SphereRadus = [3]\n" +
+		"[[sphere]]\n" +
+		"radius = 0\n" +
+		"exterior = \"glas\"\n" +
+		"[[sphere]]\n" +
+		"name = \"dome\"\n" +
+		"radius = 5\n" +
+		"[[walkway]]\n" +
+		"length = \"long\"\n"
+	fname := path.Join(t.TempDir(), "bad.input")
+	if err := os.WriteFile(fname, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sphere, err := selectGenerators("sphere")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ValidateInput(fname, sphere)
+	inputErr, ok := err.(*InputError)
+	if !ok {
+		t.Fatalf("expected an *InputError, got %v", err)
+	}
+	expected := []InputProblem{
+		{Line: 2, Col: 1, Key: "SphereRadus"},
+		{Line: 4, Col: 1, Key: "sphere[0].radius"},
+		{Line: 5, Col: 1, Key: "sphere[0].exterior"},
+		{Line: 10, Col: 1, Key: "walkway[0].length"},
+	}
+	if len(inputErr.Problems) != len(expected) {
+		t.Fatalf("expected %d problems, got\n%v", len(expected), inputErr)
+	}
+	for i, p := range inputErr.Problems {
+		if p.Line != expected[i].Line || p.Col != expected[i].Col || p.Key != expected[i].Key {
+			t.Errorf("expected problem %d at %d:%d %v, got %v", i, expected[i].Line,
+				expected[i].Col, expected[i].Key, p)
+		}
+	}
+
+	// The spheres are fine without the first one, the walkway is not selected but it is
+	// still decoded
+	fixed := strings.Replace(input, "SphereRadus = [3]\n[[sphere]]\nradius = 0\nexterior = \"glas\"\n",
+		"", 1)
+	fixed = strings.Replace(fixed, "\"long\"", "12", 1)
+	if err := os.WriteFile(fname, []byte(fixed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateInput(fname, sphere); err != nil {
+		t.Errorf("expected no problems, got %v", err)
+	}
+
+	// The blocks are checked for the FunctionVersion
+	defer func(o mcfdFunctionInputStruct) { functionOptions = o }(functionOptions)
+	for version, problems := range map[string]int{"1.12": 1, "1.20": 0} {
+		input := "FunctionVersion = \"" + version + "\"\n[[sphere]]\nradius = 5\n" +
+			"exterior = \"deepslate\"\ninterior = \"log 1\"\n"
+		if err := os.WriteFile(fname, []byte(input), 0644); err != nil {
+			t.Fatal(err)
+		}
+		err := ValidateInput(fname, sphere)
+		inputErr, _ := err.(*InputError)
+		if problems == 0 && err != nil || problems > 0 && (inputErr == nil ||
+			len(inputErr.Problems) != problems ||
+			inputErr.Problems[0].Key != "sphere[0].exterior") {
+			t.Errorf("expected %d problems with the blocks for %v, got %v", problems, version, err)
+		}
+	}
+}
+
+// Test that staged functions only replace the functions in the world when they are committed
+func TestStageFiles(t *testing.T) {
+	basepath := testBasepath(t, "Sphere")
+	fname := path.Join(basepath, "Sphere", "s_test.mcfunction")
+	if err := os.WriteFile(fname, []byte("say old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	write := func() {
+		f, err := CreateFunctionFile(basepath, "Sphere", "s_test.mcfunction")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte("say new\n"))
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect := func(what string, expected string) {
+		data, err := os.ReadFile(fname)
+		if err != nil || string(data) != expected {
+			t.Errorf("%v: expected %q, got %q, %v", what, expected, data, err)
+		}
+		tmps, _ := filepath.Glob(path.Join(basepath, "Sphere", ".*.tmp"))
+		if what != "staged" && len(tmps) > 0 {
+			t.Errorf("%v: expected no temporary files, got %v", what, tmps)
+		}
+	}
+
+	StageFiles()
+	write()
+	expect("staged", "say old\n")
+	DiscardFiles()
+	expect("discarded", "say old\n")
+
+	StageFiles()
+	write()
+	if err := CommitFiles(); err != nil {
+		t.Fatal(err)
+	}
+	expect("committed", "say new\n")
+}
+
+// Test that the generator tags only list the build functions, with lower case ids, and that
+// a load tag that is now empty is removed
+func TestFunctionTags(t *testing.T) {
+	defer func(o mcfdFunctionInputStruct) { functionOptions = o }(functionOptions)
+	functionOptions = mcfdFunctionInputStruct{FunctionChunkSize: maxCommandChainLength,
+		version: 20}
+	functionTags = make(map[string][]string)
+	generatedFunctions = make(map[string][]string)
+	basepath := testBasepath(t, "falls")
+	for _, name := range []string{"waterfall_NWE_10_7", "waterfall_NWE_10_7_rm",
+		"waterfall_NWE_10_7_cfw"} {
+		f, err := CreateFunctionFile(basepath, "Falls", name+".mcfunction")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte("say " + name + "\n"))
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	root := path.Dir(path.Dir(basepath))
+	load := path.Join(root, "minecraft", "tags", "functions", "load.json")
+	if err := os.MkdirAll(path.Dir(load), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(load, []byte(`{"values": ["mcfd:setup"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	input := path.Join(root, "tags.input")
+	if err := os.WriteFile(input, []byte("FunctionTagLoad = []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateFunctionTagsDriver(input, basepath); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path.Join(root, "mcfd", "tags", "functions", "falls.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  \"replace\": false,\n  \"values\": [\n" +
+		"    \"mcfd:falls/waterfall_nwe_10_7\"\n  ]\n}\n"
+	if string(data) != expected {
+		t.Errorf("expected %q, got %q", expected, data)
+	}
+	// The tag lists the function file by its path
+	if !fileExists(path.Join(basepath, "falls", "waterfall_nwe_10_7.mcfunction")) {
+		t.Errorf("expected the function in the tag to be written in lower case")
+	}
+	if len(generatedFunctions["falls"]) != 3 {
+		t.Errorf("expected 3 generated functions, got %v", generatedFunctions["falls"])
+	}
+	if _, err := os.Stat(load); !os.IsNotExist(err) {
+		t.Errorf("expected the empty load tag to be removed, got %v", err)
+	}
+}
+
+// Test that a function longer than FunctionChunkSize is only split when its parts are
+// scheduled, and that no part runs more than FunctionChunkSize commands with the schedule of
+// the next part
+func TestFunctionChunks(t *testing.T) {
+	defer func(o mcfdFunctionInputStruct) { functionOptions = o }(functionOptions)
+	basepath := testBasepath(t, "Sphere")
+	write := func(n int) error {
+		f, err := CreateFunctionFile(basepath, "Sphere", "s_test.mcfunction")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 1; i <= n; i++ {
+			fmt.Fprintf(f, "say %d\n", i)
+		}
+		return f.Close()
+	}
+	commands := func(filename string) []string {
+		data, err := os.ReadFile(path.Join(basepath, "Sphere", filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.SplitAfter(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+
+	functionOptions = mcfdFunctionInputStruct{FunctionChunkSize: 3}
+	if err := write(3); err != nil {
+		t.Fatalf("expected FunctionChunkSize commands to fit in one function, got %v", err)
+	}
+	if err := write(4); err == nil {
+		t.Errorf("expected an error for a function split without FunctionChunkSchedule")
+	}
+
+	functionOptions.FunctionChunkSchedule = true
+	if err := write(4); err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []string{"s_test_001.mcfunction", "s_test_002.mcfunction"} {
+		if c := commands(part); len(c) != 3 {
+			t.Errorf("expected %v to have 3 commands, got %q", part, c)
+		}
+	}
+	expected := []string{
+		"execute at @e[type=minecraft:marker,tag=mcfd_s_test,limit=1] run say 3\n",
+		"execute at @e[type=minecraft:marker,tag=mcfd_s_test,limit=1] run say 4\n",
+		"kill @e[type=minecraft:marker,tag=mcfd_s_test,limit=1]"}
+	if c := commands("s_test_002.mcfunction"); strings.Join(c, "") != strings.Join(expected, "") {
+		t.Errorf("expected %q, got %q", expected, c)
+	}
+	if fileExists(path.Join(basepath, "Sphere", "s_test_003.mcfunction")) {
+		t.Errorf("expected 2 parts for 4 commands")
+	}
+}
+
+// Test that the files of a split function and the ids it schedules are resource locations,
+// which must be lower case from Minecraft 1.13 on
+func TestFunctionChunkIDs(t *testing.T) {
+	defer func(o mcfdFunctionInputStruct) { functionOptions = o }(functionOptions)
+	functionOptions = mcfdFunctionInputStruct{FunctionChunkSize: 3, FunctionChunkSchedule: true,
+		version: 17}
+	basepath := testBasepath(t, "sphere")
+	f, err := CreateFunctionFile(basepath, "Sphere", "s_Glass_10.mcfunction")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("say 1\nsay 2\nsay 3\nsay 4\n"))
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	valid := regexp.MustCompile(`^[a-z0-9_./-]+$`)
+	files, err := filepath.Glob(path.Join(basepath, "*", "*.mcfunction"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) < 3 {
+		t.Fatalf("expected the function and its parts, got %v", files)
+	}
+	nids := 0
+	for _, fname := range files {
+		if rel := strings.TrimPrefix(fname, basepath+"/"); !valid.MatchString(rel) {
+			t.Errorf("function file %v is not a resource location", rel)
+		}
+		data, err := os.ReadFile(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			for i := 0; i+1 < len(fields); i++ {
+				if fields[i] != "function" {
+					continue
+				}
+				nids++
+				ns, p, _ := strings.Cut(fields[i+1], ":")
+				if !valid.MatchString(ns) || !valid.MatchString(p) {
+					t.Errorf("%v: function id %v is not a resource location", fname, fields[i+1])
+				}
+			}
+		}
+	}
+	if nids == 0 {
+		t.Errorf("expected the parts to be scheduled by their ids")
+	}
+}
+
+// Test that a broken import file is a problem of the input file, found before anything runs
+func TestImportBrokenFile(t *testing.T) {
+	dir := t.TempDir()
+	nbt := path.Join(dir, "broken.nbt")
+	if err := os.WriteFile(nbt, []byte{10, 0, 0, 7, 0, 1, 'a', 0xff, 0xff, 0xff, 0xfe, 0},
+		0644); err != nil {
+		t.Fatal(err)
+	}
+	fname := path.Join(dir, "import.input")
+	if err := os.WriteFile(fname, []byte("[[import]]\nfile = \""+nbt+"\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	selected, err := selectGenerators("import")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ValidateInput(fname, selected)
+	inputErr, ok := err.(*InputError)
+	if !ok || len(inputErr.Problems) != 1 || inputErr.Problems[0].Key != "import[0].file" {
+		t.Fatalf("expected a problem with import[0].file, got %v", err)
+	}
+}
+
+// Test that a build with blocks 1.12 does not have needs a later FunctionVersion
+func TestImportLegacyBlocks(t *testing.T) {
+	defer func(o mcfdFunctionInputStruct) { functionOptions = o }(functionOptions)
+	dir := t.TempDir()
+	build := path.Join(dir, "stairs.mcfunction")
+	if err := os.WriteFile(build, []byte("setblock ~0 ~0 ~-2 minecraft:polished_diorite\n"+
+		"setblock ~1 ~0 ~-2 minecraft:oak_stairs[facing=east]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	selected, err := selectGenerators("import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range []string{"", "FunctionVersion = \"1.13\"\n"} {
+		fname := path.Join(dir, "import.input")
+		if err := os.WriteFile(fname, []byte(version+"[[import]]\nfile = \""+build+"\"\n"),
+			0644); err != nil {
+			t.Fatal(err)
+		}
+		err = ValidateInput(fname, selected)
+		if version != "" && err != nil {
+			t.Errorf("expected no problems for 1.13, got %v", err)
+		}
+		inputErr, ok := err.(*InputError)
+		if version == "" && (!ok || len(inputErr.Problems) != 1 ||
+			inputErr.Problems[0].Key != "import[0].file") {
+			t.Errorf("expected a problem with import[0].file for 1.12, got %v", err)
+		}
+	}
+}
+
+// Test that a function keeps its backup slot when the functions are written again
+func TestSnapshotSlots(t *testing.T) {
+	defer func(o mcfdSnapshotInputStruct) { snapshotOptions = o }(snapshotOptions)
+	snapshotOptions = mcfdSnapshotInputStruct{SnapshotUndo: true,
+		SnapshotOrigin: []int{1000, 0, 0}}
+	snapshotSlots = nil
+	basepath := testBasepath(t, "Sphere")
+	snapshot := func(filename string, lines ...string) string {
+		backup, err := SnapshotFunction(basepath, "Sphere", filename, lines)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return backup[2]
+	}
+
+	snapshot("s_a.mcfunction", "fill ~0 ~0 ~0 ~4 ~0 ~0 minecraft:stone\n")
+	snapshot("s_b.mcfunction", "setblock ~0 ~0 ~0 minecraft:stone\n")
+	if err := WriteSnapshotSlots(basepath); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only s_b is written in the next run, it must still use the slot after s_a
+	snapshotSlots = nil
+	expected := "clone ~0 ~0 ~0 ~0 ~0 ~0 1006 0 0\n"
+	clone := snapshot("s_b.mcfunction", "setblock ~0 ~0 ~0 minecraft:stone\n")
+	if clone != expected {
+		t.Errorf("expected %q, got %q", expected, clone)
+	}
+}
+
+// testWorkDir changes to a temporary directory for the rest of the test, with the stlFiles
+// directory the falls and spheres write their STL files to.
+func testWorkDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(path.Join(dir, "stlFiles"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// Test that a sphere is round with its interior inside, and that a hollow sphere is empty
+func TestSphere(t *testing.T) {
+	testWorkDir(t)
+	basepath := testBasepath(t, "Sphere")
+	if err := CreateSphere(basepath, "s_glass_lava_5.mcfunction", 5, "glass", "lava"); err != nil {
+		t.Fatalf("CreateSphere: %v", err)
+	}
+	w := runFunction(t, basepath, "Sphere", "s_glass_lava_5.mcfunction", "north")
+
+	// The center is radius blocks east of the player and radius + 2 south
+	lo, hi := w.Model().Bounds()
+	if lo != at(0, -5, 2) || hi != at(10, 5, 12) {
+		t.Errorf("expected the sphere from %v to %v, got %v to %v", at(0, -5, 2), at(10, 5, 12),
+			lo, hi)
+	}
+	if b := mcshapes.BlockName(w.Block(at(5, 0, 7))); b != "minecraft:lava" {
+		t.Errorf("expected lava in the center, got %v", b)
+	}
+	if b := mcshapes.BlockName(w.Block(at(5, 0, 2))); b != "minecraft:glass" {
+		t.Errorf("expected glass on the outside, got %v", b)
+	}
+	if b := w.Block(at(0, 5, 2)); b != "" {
+		t.Errorf("expected nothing at the corner of the box around the sphere, got %v", b)
+	}
+
+	if err := CreateSphere(basepath, "s_glass_5.mcfunction", 5, "glass", "none"); err != nil {
+		t.Fatalf("CreateSphere: %v", err)
+	}
+	hollow := runFunction(t, basepath, "Sphere", "s_glass_5.mcfunction", "north")
+	if b := hollow.Block(at(5, 0, 7)); b != "" {
+		t.Errorf("expected nothing in the center of a hollow sphere, got %v", b)
+	}
+	if counts := hollow.Model().Counts(); len(counts) != 1 || counts["minecraft:glass"] == 0 {
+		t.Errorf("expected only glass in a hollow sphere, got %v", counts)
+	}
+}
+
+// Test a walkway facing east, and that its _rm function leaves dirt under air
+func TestWalkway(t *testing.T) {
+	basepath := testBasepath(t, "Walkway")
+	if err := CreateWalkway(basepath, "ww_E_10.mcfunction", "east", 10); err != nil {
+		t.Fatalf("CreateWalkway: %v", err)
+	}
+	w := runFunction(t, basepath, "Walkway", "ww_E_10.mcfunction", "east")
+
+	// Facing east the walkway runs from 1 to 10 blocks east of the player, the gold blocks
+	// under the player with rails 3 blocks to each side.
+	for x := 1; x <= 10; x++ {
+		if b := mcshapes.BlockName(w.Block(at(x, -1, 0))); b != "minecraft:gold_block" {
+			t.Fatalf("x %d: expected a gold block under the walkway, got %v", x, b)
+		}
+	}
+	if b := w.Block(at(11, -1, 0)); b != "" {
+		t.Errorf("expected nothing past the end of the walkway, got %v", b)
+	}
+	for _, z := range []int{-3, 3} {
+		if b := mcshapes.BlockName(w.Block(at(5, 0, z))); b != "minecraft:powered_rail" {
+			t.Errorf("z %d: expected a golden rail, got %v", z, b)
+		}
+	}
+	if b := mcshapes.BlockName(w.Block(at(5, 1, 0))); b != "minecraft:air" {
+		t.Errorf("expected air in the walkway, got %v", b)
+	}
+	if b := mcshapes.BlockName(w.Block(at(5, 8, 0))); b != "minecraft:sea_lantern" {
+		t.Errorf("expected sea lanterns on the roof, got %v", b)
+	}
+
+	if err := RmWalkway(basepath, "ww_E_10_rm.mcfunction", "east", 10); err != nil {
+		t.Fatalf("RmWalkway: %v", err)
+	}
+	if err := w.RunFunction(FunctionID(basepath, "Walkway", "ww_E_10_rm")); err != nil {
+		t.Fatal(err)
+	}
+	for _, xyz := range w.Model().Positions() {
+		b := mcshapes.BlockName(w.Block(xyz))
+		if xyz.Y == testPlayer.Y-1 && b != "minecraft:dirt" {
+			t.Fatalf("expected dirt under the removed walkway at %v, got %v", xyz, b)
+		}
+		if xyz.Y >= testPlayer.Y && b != "minecraft:air" {
+			t.Fatalf("the _rm function does not clear %v at %v", b, xyz)
+		}
+	}
+}
+
+// Test that a volume facing west is filled in front of the player with the block
+func TestClearVol(t *testing.T) {
+	basepath := testBasepath(t, "ClearVol")
+	err := CreateClearVol(basepath, "cv_W_11_5_3_dirt.mcfunction", "west", 3, 11, 5, "dirt")
+	if err != nil {
+		t.Fatalf("CreateClearVol: %v", err)
+	}
+	w := runFunction(t, basepath, "ClearVol", "cv_W_11_5_3_dirt.mcfunction", "west")
+
+	// Facing west the depth is along -X from 2 blocks in front of the player, the width is
+	// centered on the player
+	lo, hi := w.Model().Bounds()
+	if lo != at(-6, 0, -5) || hi != at(-2, 2, 5) {
+		t.Errorf("expected the volume from %v to %v, got %v to %v", at(-6, 0, -5), at(-2, 2, 5),
+			lo, hi)
+	}
+	if counts := w.Model().Counts(); counts["minecraft:dirt"] != 11*5*3 || len(counts) != 1 {
+		t.Errorf("expected %d dirt blocks, got %v", 11*5*3, counts)
+	}
+}
+
+// Test the falls driver, the functions it writes for every direction and that the _rm and
+// _cfw functions clear the falls
+func TestBuildFalls(t *testing.T) {
+	testWorkDir(t)
+	basepath := testBasepath(t, "Falls")
+	fname := path.Join(t.TempDir(), "falls.input")
+	input := "[[falls]]\nwidth = 5\nheight = 4\nflow = \"lava\"\n"
+	if err := os.WriteFile(fname, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := BuildFalls(fname, basepath); err != nil {
+		t.Fatalf("BuildFalls: %v", err)
+	}
+	files, err := filepath.Glob(path.Join(basepath, "Falls", "lavafall_*.mcfunction"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 8*3 {
+		t.Errorf("expected a build, _rm and _cfw function for 8 directions, got %d functions",
+			len(files))
+	}
+
+	// Facing south the falls run west from the player, the flow is at the back
+	w := runFunction(t, basepath, "Falls", "lavafall_SEW_5_4.mcfunction", "south")
+	if b := mcshapes.BlockName(w.Block(at(-1, 4, 5))); b != "minecraft:lava" {
+		t.Errorf("expected lava at the top of the falls, got %v", b)
+	}
+	if b := mcshapes.BlockName(w.Block(at(0, 0, 2))); b != "minecraft:sandstone" {
+		t.Errorf("expected a sandstone basin, got %v", b)
+	}
+
+	for _, rm := range []string{"lavafall_SEW_5_4_rm", "lavafall_SEW_5_4_cfw"} {
+		if err := w.RunFunction(FunctionID(basepath, "Falls", rm)); err != nil {
+			t.Fatal(err)
+		}
+		for _, xyz := range w.Model().Positions() {
+			if b := w.Block(xyz); xyz.Y >= testPlayer.Y && !mcshapes.IsAir(b) {
+				t.Fatalf("%v does not clear %v at %v", rm, b, xyz)
+			}
+		}
+	}
+}
+
+// Test the stack sizes of the items in a bill of materials
+func TestBOMItems(t *testing.T) {
+	items := BOMItems(map[string]int{"minecraft:water": 3, "minecraft:oak_sign": 20,
+		"minecraft:stone": 70, "minecraft:red_bed": 2})
+	expected := map[string]int{"minecraft:water_bucket": 1, "minecraft:oak_sign": 16,
+		"minecraft:stone": 64, "minecraft:red_bed": 1}
+	if len(items) != len(expected) {
+		t.Fatalf("expected %d items, got %v", len(expected), items)
+	}
+	for _, item := range items {
+		if size := expected[item.Item]; item.StackSize != size ||
+			item.Stacks*size+item.Remainder != item.Count {
+			t.Errorf("expected %v in stacks of %d, got %+v", item.Item, size, item)
+		}
+	}
+}
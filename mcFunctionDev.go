@@ -1,76 +1,163 @@
 package main
 
 import (
-	//"flag"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path"
-
-	"github.com/BurntSushi/toml"
-	//mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+	"strings"
 )
 
 // mcFunctionPath struct for reading various things from the init file
 // Note that fields must start with a capital letter!!!!!!
 // Example:
-//    MCSavesDir - this is what TOML uses below to reference the user input.
+//    MCSavesDir - this is what TOML uses in ReadInitFile to reference the user input.
 //    mc_saves_dir - this is what appears in the init file
+//
+// The worlds of the profiles, see ReadInitFile, have the same fields.
 type mcFunctionPath struct {
 	Title          string
-	MCSavesDir     string `toml:"mc_saves_dir"`
-	MCFunctionsDir string `toml:"mc_world_functions_dir"`
+	MCSavesDir     string                    `toml:"mc_saves_dir"`
+	MCFunctionsDir string                    `toml:"mc_world_functions_dir"`
+	MCNamespace    string                    `toml:"mc_namespace"`
+	DefaultProfile string                    `toml:"default_profile"`
+	Profiles       map[string]mcFunctionPath `toml:"profile"`
+}
+
+// The commands, see the usage below.
+var commands = map[string]func(args []string) error{
+	"build":     BuildCommand,
+	"list":      ListCommand,
+	"validate":  ValidateCommand,
+	"clean":     CleanCommand,
+	"init":      InitCommand,
+	"preview":   PreviewCommand,
+	"diff":      DiffCommand,
+	"lint":      LintCommand,
+	"layers":    LayersCommand,
+	"blueprint": BlueprintCommand,
 }
 
+const usage = `usage: mcFunctionDev [command] [flags] [arguments]
+
+Commands that run the generators in the input file:
+    build      write the functions into the world, the default without a command
+    list       list the functions the generators make
+    validate   check the input file and the functions it makes
+    clean      remove the functions of the generators from the world
+    init       show the init file and its profiles, or write a starter init file
+
+Commands that work on function files:
+    preview    print a function one layer at a time in the terminal
+    diff       compare the blocks two sets of functions build
+    lint       check the commands of function files
+    layers     draw the layers of a function as images
+    blueprint  draw plans, elevations and sections of a function
+
+Run mcFunctionDev <command> -h for the flags of a command.
+
+Exit status:
+    0   success
+    1   the command failed
+    2   unknown command
+    3   the input file has problems, nothing was written
+`
+
 func main() {
 	// mcFunctionDev uses two control files, init and input.
 	//    init file - sets things that do not change often
 	//    input file - controls what mcFunctionDev does when executed
-
 	//
 	// The TOML package is used to read and parse both the init file
 	// and the input file.
 	//    github.com/BurntSushi/toml
 	//
-
-	// Read and extract information from the init file.
-	//
-	// Right now, the only information in the init file is the path to
-	// the Minecraft functions directory on this system.  The
-	// output function files are written directly to the game directory
-	// which saves time and hassle of copying files.  The path is
-	// split into two strings just because it is typically a long path.
-	gopath := os.Getenv("GOPATH")
-	initfile := gopath + "/mcFunctionDev.init"
-	//fmt.Println("initfile = " + initfile)
-	var mcwpath mcFunctionPath
-	if _, err := toml.DecodeFile(initfile, &mcwpath); err != nil {
-		fmt.Println(err)
+	// Without a command, or with only flags, the generators are built as always.
+	cmd, args := "build", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+	if cmd == "help" {
+		fmt.Print(usage)
 		return
 	}
-	//fmt.Printf("Title: %s\n", mcwpath.Title)
-	//fmt.Printf("mc_saves_dir: %s\n", mcwpath.MCSavesDir)
-	//fmt.Println("mc_world_functions_dir = " + mcwpath.MCFunctionsDir)
+	run, ok := commands[cmd]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		log.Printf("unknown command %v", cmd)
+		os.Exit(2)
+	}
+	err := run(args)
+	var inputErr *InputError
+	switch {
+	case errors.As(err, &inputErr):
+		fmt.Fprintln(os.Stderr, inputErr)
+		os.Exit(3)
+	case err != nil:
+		log.Fatalln(err)
+	}
+}
 
-	// Keep this for now as an example of how to get and process
-	// execution line arguments.
-	//flag.StringVar(&mcSavesDir, "s", "~", "Minecraft saves directory")
-	//flag.StringVar(&mcWorldFuncDir, "w", "mc", "Minecraft functions directory")
-	//flag.Parse()
+// runDrivers checks the input file and runs the selected generators, writing the function
+// files in basepath. Nothing is written when the input file has problems, see ValidateInput,
+// and the functions in basepath are only replaced when all the generators succeed, see
+// StageFiles.
+func runDrivers(inputFile string, basepath string, selected []generator) error {
+	if err := ValidateInput(inputFile, selected); err != nil {
+		return err
+	}
+	// Options for writing the function files, used by all the drivers.
+	err := ReadFunctionOptions(inputFile)
+	if err != nil {
+		return err
+	}
+	err = ReadExportOptions(inputFile)
+	if err != nil {
+		return err
+	}
+	err = ReadSnapshotOptions(inputFile)
+	if err != nil {
+		return err
+	}
+	err = ReadBOMOptions(inputFile)
+	if err != nil {
+		return err
+	}
+	err = ReadPreviewOptions(inputFile)
+	if err != nil {
+		return err
+	}
 
-	inputFile := "all.input"
-	basepath := path.Join(mcwpath.MCSavesDir, mcwpath.MCFunctionsDir)
+	// The directories of the generators, lower case for 1.13 and later, see resourceName
+	for _, g := range selected {
+		if err := os.MkdirAll(path.Join(basepath, resourceName(g.dir)), 0755); err != nil {
+			return err
+		}
+	}
 
-	//fmt.Println("basepath = " + basepath)
-	err := BuildFalls(inputFile, basepath)
-	if err != nil {
-		log.Fatalln(err)
+	StageFiles()
+	for _, g := range selected {
+		if err := g.run(inputFile, basepath); err != nil {
+			DiscardFiles()
+			return fmt.Errorf("%v: %v, no functions were changed", g.name, err)
+		}
 	}
 
-	CreateClearVolDriver(inputFile, basepath)
-	CreateMWallDriver(inputFile, basepath)
-	CreateSign7Driver(inputFile, basepath)
-	CreateSphereDriver(inputFile, basepath)
-	CreateWalkwayDriver(inputFile, basepath)
-}
+	// The bills of materials are for the functions written by all the drivers above.
+	CreateBOMDriver()
 
+	// The backup slots of the undo functions, see SnapshotFunction.
+	if err := WriteSnapshotSlots(basepath); err != nil {
+		DiscardFiles()
+		return fmt.Errorf("%v, no functions were changed", err)
+	}
+
+	// The function tags list the functions written by all the drivers above so this
+	// must come last. Only the tags of the selected generators are written.
+	if err := CreateFunctionTagsDriver(inputFile, basepath); err != nil {
+		DiscardFiles()
+		return fmt.Errorf("%v, no functions were changed", err)
+	}
+	return CommitFiles()
+}
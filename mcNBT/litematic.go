@@ -0,0 +1,120 @@
+package mcnbt
+
+import (
+	"io"
+	"time"
+
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+)
+
+// Litematica files
+//
+// The Litematica mod shows a .litematic file in the game as a hologram, a guide for
+// building by hand in survival. The file is a gzip compressed compound:
+//    Version               6
+//    SubVersion            1
+//    MinecraftDataVersion  version of Minecraft the file was written for
+//    Metadata              name, author, sizes and block counts
+//    Regions               one or more named regions, here only one
+// Each region has
+//    Position           {x, y, z} of the region from the placement origin
+//    Size               {x, y, z}
+//    BlockStatePalette  list of block states, {Name, Properties}, air is always first
+//    BlockStates        palette indexes packed into longs
+//    TileEntities, Entities, PendingBlockTicks, PendingFluidTicks   always empty here
+// The blocks are in the order x + z*Size.x + y*Size.x*Size.z. Each index takes the same
+// number of bits, at least 2, and an index can be split across two longs.
+//
+// The region position is set so the object is where the function would build it when the
+// placement origin is at the player.
+
+// Versions of the Litematica format written
+const (
+	litematicVersion    = 6
+	litematicSubVersion = 1
+)
+
+// WriteLitematic writes a model as a Litematica file with one region.
+func WriteLitematic(w io.Writer, m *mcshapes.Model, name string, dataVersion int) error {
+	lo, hi := m.Bounds()
+	sx, sy, sz := hi.X-lo.X+1, hi.Y-lo.Y+1, hi.Z-lo.Z+1
+	volume := sx * sy * sz
+
+	// Litematica needs air as the first entry of the palette.
+	pal := newPalette()
+	pal.index("minecraft:air")
+
+	indexes := make([]int32, 0, volume)
+	nblocks := 0
+	for y := lo.Y; y <= hi.Y; y++ {
+		for z := lo.Z; z <= hi.Z; z++ {
+			for x := lo.X; x <= hi.X; x++ {
+				block := m.Block(mcshapes.XYZ{X: x, Y: y, Z: z})
+				if block == "" {
+					block = "minecraft:air"
+				}
+				if !mcshapes.IsAir(block) {
+					nblocks++
+				}
+				indexes = append(indexes, pal.index(block))
+			}
+		}
+	}
+
+	bits := 2
+	for 1<<uint(bits) < len(pal.states) {
+		bits++
+	}
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	size := Compound{{"x", int32(sx)}, {"y", int32(sy)}, {"z", int32(sz)}}
+	region := Compound{
+		{"Position", Compound{{"x", int32(lo.X)}, {"y", int32(lo.Y)}, {"z", int32(lo.Z)}}},
+		{"Size", size},
+		{"BlockStatePalette", pal.list()},
+		{"BlockStates", packBits(indexes, bits)},
+		{"TileEntities", List{}},
+		{"Entities", List{}},
+		{"PendingBlockTicks", List{}},
+		{"PendingFluidTicks", List{}},
+	}
+	root := Compound{
+		{"Version", int32(litematicVersion)},
+		{"SubVersion", int32(litematicSubVersion)},
+		{"MinecraftDataVersion", int32(dataVersion)},
+		{"Metadata", Compound{
+			{"Name", name},
+			{"Author", "mcFunctionDev"},
+			{"Description", ""},
+			{"RegionCount", int32(1)},
+			{"TotalBlocks", int32(nblocks)},
+			{"TotalVolume", int32(volume)},
+			{"EnclosingSize", size},
+			{"TimeCreated", now},
+			{"TimeModified", now},
+		}},
+		{"Regions", Compound{{name, region}}},
+	}
+	return WriteGzip(w, "", root)
+}
+
+// packBits packs values of the given number of bits into longs, lowest bits first.
+// A value that does not fit in what is left of a long continues in the next long.
+func packBits(values []int32, bits int) []int64 {
+	packed := make([]uint64, (len(values)*bits+63)/64)
+	mask := uint64(1)<<uint(bits) - 1
+	for i, v := range values {
+		start := i * bits
+		word, offset := start/64, uint(start%64)
+		packed[word] |= (uint64(v) & mask) << offset
+		if int(offset)+bits > 64 {
+			packed[word+1] |= (uint64(v) & mask) >> (64 - offset)
+		}
+	}
+
+	longs := make([]int64, len(packed))
+	for i, p := range packed {
+		longs[i] = int64(p)
+	}
+	return longs
+}
@@ -0,0 +1,352 @@
+package mcnbt
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// NBT (Named Binary Tag) is the binary format Minecraft uses for structures, and that other
+// tools use for schematics. Every value is a tag with a type, a compound tag holds named tags
+// the way a struct does and a list tag holds unnamed tags all of the same type. Files are
+// normally gzip compressed.
+//
+// Tags are kept in ordinary Go values:
+//    int8 Byte, int16 Short, int32 Int, int64 Long, float32 Float, float64 Double,
+//    []byte ByteArray, string String, List, Compound, []int32 IntArray, []int64 LongArray
+
+// Tag types
+const (
+	TagEnd byte = iota
+	TagByte
+	TagShort
+	TagInt
+	TagLong
+	TagFloat
+	TagDouble
+	TagByteArray
+	TagString
+	TagList
+	TagCompound
+	TagIntArray
+	TagLongArray
+)
+
+// Entry is one named tag in a compound
+type Entry struct {
+	Name  string
+	Value interface{}
+}
+
+// Compound is a compound tag. The order of the entries is kept when writing.
+type Compound []Entry
+
+// List is a list tag, all the values must be the same type
+type List []interface{}
+
+// Get returns the value of the named tag in a compound, nil if it is not there
+func (c Compound) Get(name string) interface{} {
+	for _, e := range c {
+		if e.Name == name {
+			return e.Value
+		}
+	}
+	return nil
+}
+
+// TagType returns the tag type of a value
+func TagType(v interface{}) (byte, error) {
+	switch v.(type) {
+	case int8:
+		return TagByte, nil
+	case int16:
+		return TagShort, nil
+	case int32:
+		return TagInt, nil
+	case int64:
+		return TagLong, nil
+	case float32:
+		return TagFloat, nil
+	case float64:
+		return TagDouble, nil
+	case []byte:
+		return TagByteArray, nil
+	case string:
+		return TagString, nil
+	case List:
+		return TagList, nil
+	case Compound:
+		return TagCompound, nil
+	case []int32:
+		return TagIntArray, nil
+	case []int64:
+		return TagLongArray, nil
+	}
+	return TagEnd, fmt.Errorf("nbt: no tag type for %T", v)
+}
+
+// Write writes an uncompressed NBT file with a named root compound
+func Write(w io.Writer, name string, root Compound) error {
+	e := encoder{w: w}
+	e.byte(TagCompound)
+	e.string(name)
+	e.payload(root)
+	return e.err
+}
+
+// WriteGzip writes a gzip compressed NBT file with a named root compound
+func WriteGzip(w io.Writer, name string, root Compound) error {
+	zw := gzip.NewWriter(w)
+	if err := Write(zw, name, root); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// encoder writes tags, keeping the first error
+type encoder struct {
+	w   io.Writer
+	err error
+}
+
+func (e *encoder) write(v interface{}) {
+	if e.err == nil {
+		e.err = binary.Write(e.w, binary.BigEndian, v)
+	}
+}
+
+func (e *encoder) byte(b byte) {
+	e.write(b)
+}
+
+func (e *encoder) string(s string) {
+	if len(s) > 0xffff {
+		e.err = fmt.Errorf("nbt: string too long, %d bytes", len(s))
+		return
+	}
+	e.write(uint16(len(s)))
+	e.write([]byte(s))
+}
+
+func (e *encoder) payload(v interface{}) {
+	if e.err != nil {
+		return
+	}
+	switch t := v.(type) {
+	case int8, int16, int32, int64, float32, float64:
+		e.write(t)
+	case []byte:
+		e.write(int32(len(t)))
+		e.write(t)
+	case string:
+		e.string(t)
+	case List:
+		etype := TagEnd
+		if len(t) > 0 {
+			var err error
+			if etype, err = TagType(t[0]); err != nil {
+				e.err = err
+				return
+			}
+		}
+		e.byte(etype)
+		e.write(int32(len(t)))
+		for _, x := range t {
+			if xt, err := TagType(x); err != nil || xt != etype {
+				e.err = fmt.Errorf("nbt: list of %v holds a %T", etype, x)
+				return
+			}
+			e.payload(x)
+		}
+	case Compound:
+		for _, entry := range t {
+			typ, err := TagType(entry.Value)
+			if err != nil {
+				e.err = err
+				return
+			}
+			e.byte(typ)
+			e.string(entry.Name)
+			e.payload(entry.Value)
+		}
+		e.byte(TagEnd)
+	case []int32:
+		e.write(int32(len(t)))
+		e.write(t)
+	case []int64:
+		e.write(int32(len(t)))
+		e.write(t)
+	default:
+		e.err = fmt.Errorf("nbt: cannot write %T", v)
+	}
+}
+
+// Read reads an NBT file with a root compound, gzip compressed or not. It returns the name
+// of the root compound and the compound.
+func Read(r io.Reader) (string, Compound, error) {
+	br := bufio.NewReader(r)
+	var in io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return "", nil, err
+		}
+		defer zr.Close()
+		in = zr
+	}
+	// The whole file is read first, so the lengths in it can be checked against what is left
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return "", nil, err
+	}
+
+	d := decoder{r: bytes.NewReader(data)}
+	if typ := d.byte(); typ != TagCompound && d.err == nil {
+		return "", nil, fmt.Errorf("nbt: root is tag type %d, not a compound", typ)
+	}
+	name := d.string()
+	root, _ := d.payload(TagCompound, 0).(Compound)
+	if d.err != nil {
+		return "", nil, d.err
+	}
+	return name, root, nil
+}
+
+// Nested compounds and lists deeper than this are treated as a broken file.
+const maxDepth = 512
+
+// decoder reads tags, keeping the first error
+type decoder struct {
+	r   *bytes.Reader
+	err error
+}
+
+func (d *decoder) read(v interface{}) {
+	if d.err == nil {
+		d.err = binary.Read(d.r, binary.BigEndian, v)
+	}
+}
+
+func (d *decoder) byte() byte {
+	var b byte
+	d.read(&b)
+	return b
+}
+
+// length reads the length of an array or list with items of at least size bytes. A broken
+// file may have any length, so a negative length or one longer than the rest of the file is
+// an error and 0 is returned, nothing is allocated for it.
+func (d *decoder) length(size int) int {
+	var n int32
+	d.read(&n)
+	switch {
+	case d.err != nil:
+		return 0
+	case n < 0:
+		d.err = fmt.Errorf("nbt: negative length %d", n)
+		return 0
+	case int64(n)*int64(size) > int64(d.r.Len()):
+		d.err = fmt.Errorf("nbt: length %d is longer than the rest of the file", n)
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) string() string {
+	var n uint16
+	d.read(&n)
+	b := make([]byte, n)
+	d.read(b)
+	return string(b)
+}
+
+func (d *decoder) payload(typ byte, depth int) interface{} {
+	if d.err != nil {
+		return nil
+	}
+	if depth > maxDepth {
+		d.err = fmt.Errorf("nbt: tags nested too deep")
+		return nil
+	}
+
+	switch typ {
+	case TagByte:
+		var v int8
+		d.read(&v)
+		return v
+	case TagShort:
+		var v int16
+		d.read(&v)
+		return v
+	case TagInt:
+		var v int32
+		d.read(&v)
+		return v
+	case TagLong:
+		var v int64
+		d.read(&v)
+		return v
+	case TagFloat:
+		var v float32
+		d.read(&v)
+		return v
+	case TagDouble:
+		var v float64
+		d.read(&v)
+		return v
+	case TagByteArray:
+		v := make([]byte, d.length(1))
+		d.read(v)
+		return v
+	case TagString:
+		return d.string()
+	case TagList:
+		etype := d.byte()
+		// Every item is at least one byte, even an empty compound
+		n := d.length(1)
+		l := List{}
+		for i := 0; i < n && d.err == nil; i++ {
+			l = append(l, d.payload(etype, depth+1))
+		}
+		return l
+	case TagCompound:
+		c := Compound{}
+		for d.err == nil {
+			t := d.byte()
+			if t == TagEnd {
+				break
+			}
+			name := d.string()
+			c = append(c, Entry{name, d.payload(t, depth+1)})
+		}
+		return c
+	case TagIntArray:
+		v := make([]int32, d.length(4))
+		d.read(v)
+		return v
+	case TagLongArray:
+		v := make([]int64, d.length(8))
+		d.read(v)
+		return v
+	}
+	d.err = fmt.Errorf("nbt: unknown tag type %d", typ)
+	return nil
+}
+
+// Int returns a whole number tag of any size as an int, 0 if it is not a number
+func Int(v interface{}) int {
+	switch t := v.(type) {
+	case int8:
+		return int(t)
+	case int16:
+		return int(t)
+	case int32:
+		return int(t)
+	case int64:
+		return int(t)
+	}
+	return 0
+}
@@ -0,0 +1,198 @@
+package mcnbt
+
+import (
+	"bytes"
+	"testing"
+
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+)
+
+// Test the bytes written for a small compound
+func TestWrite(t *testing.T) {
+	expected := []byte{
+		10, 0, 2, 'h', 'i', // root compound "hi"
+		3, 0, 1, 'a', 0, 0, 0, 7, // int "a" = 7
+		8, 0, 1, 'b', 0, 2, 'o', 'k', // string "b" = "ok"
+		9, 0, 1, 'c', 1, 0, 0, 0, 2, 1, 2, // list "c" of two bytes
+		9, 0, 1, 'd', 0, 0, 0, 0, 0, // empty list "d"
+		0, // end of root
+	}
+	root := Compound{
+		{"a", int32(7)},
+		{"b", "ok"},
+		{"c", List{int8(1), int8(2)}},
+		{"d", List{}},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, "hi", root); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Errorf("expected %v, got %v", expected, buf.Bytes())
+	}
+
+	if err := Write(&buf, "", Compound{{"e", List{int8(1), "x"}}}); err == nil {
+		t.Errorf("expected an error for a list of mixed types")
+	}
+}
+
+// Test that broken lengths are errors and nothing is allocated for them
+func TestReadBadLength(t *testing.T) {
+	tests := map[string][]byte{
+		"negative": {10, 0, 0, 7, 0, 1, 'a', 0xff, 0xff, 0xff, 0xfe, 0},
+		"too long": {10, 0, 0, 11, 0, 1, 'a', 0x7f, 0xff, 0xff, 0xff, 0, 0, 0, 1, 0},
+		"list":     {10, 0, 0, 9, 0, 1, 'a', 10, 0x10, 0, 0, 0, 0, 0},
+	}
+	for name, data := range tests {
+		if _, _, err := Read(bytes.NewReader(data)); err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
+
+// Test the varints of the schematic block data
+func TestAppendVarint(t *testing.T) {
+	tests := []struct {
+		v        int32
+		expected []byte
+	}{
+		{0, []byte{0}},
+		{127, []byte{127}},
+		{128, []byte{0x80, 1}},
+		{300, []byte{0xac, 2}},
+	}
+	for _, tc := range tests {
+		if b := appendVarint(nil, tc.v); !bytes.Equal(b, tc.expected) {
+			t.Errorf("%d: expected %v, got %v", tc.v, tc.expected, b)
+		}
+	}
+}
+
+// Test packing the Litematica block states, a value is split across two longs
+func TestPackBits(t *testing.T) {
+	values := make([]int32, 22)
+	values[0] = 1
+	values[21] = 5 // bits 63 to 65
+	packed := packBits(values, 3)
+
+	if len(packed) != 2 {
+		t.Fatalf("expected 2 longs, got %d", len(packed))
+	}
+	if uint64(packed[0]) != 1|1<<63 {
+		t.Errorf("expected first long %x, got %x", uint64(1|1<<63), uint64(packed[0]))
+	}
+	if packed[1] != 2 {
+		t.Errorf("expected second long 2, got %d", packed[1])
+	}
+}
+
+// Test writing and reading back structures and schematics
+func TestStructureRoundTrip(t *testing.T) {
+	m := mcshapes.NewModel()
+	m.SetBlock(mcshapes.XYZ{X: 0, Y: 0, Z: -2}, "minecraft:stone 4")
+	m.SetBlock(mcshapes.XYZ{X: 1, Y: 2, Z: -3}, "minecraft:oak_stairs[facing=east]")
+	m.SetBlock(mcshapes.XYZ{X: 200, Y: 0, Z: -2}, "minecraft:glass")
+
+	var buf bytes.Buffer
+	if err := WriteStructure(&buf, m, DefaultDataVersion); err != nil {
+		t.Fatalf("WriteStructure: %v", err)
+	}
+	s, err := ReadStructure(&buf)
+	if err != nil {
+		t.Fatalf("ReadStructure: %v", err)
+	}
+	// Structures start at 0,0,0
+	if b := s.Block(mcshapes.XYZ{X: 1, Y: 2, Z: 0}); b != "minecraft:oak_stairs[facing=east]" {
+		t.Errorf("expected oak_stairs at 1,2,0, got '%v'", b)
+	}
+	if s.Len() != 3 {
+		t.Errorf("expected 3 blocks, got %d", s.Len())
+	}
+
+	for _, version := range []int{2, 3} {
+		buf.Reset()
+		if err := WriteSchematic(&buf, m, DefaultDataVersion, version); err != nil {
+			t.Fatalf("WriteSchematic %d: %v", version, err)
+		}
+		s, err := ReadSchematic(&buf)
+		if err != nil {
+			t.Fatalf("ReadSchematic %d: %v", version, err)
+		}
+		// Schematics keep the positions and fill the rest of the box with air
+		if b := s.Block(mcshapes.XYZ{X: 0, Y: 0, Z: -2}); b != "minecraft:polished_diorite" {
+			t.Errorf("version %d: expected polished_diorite at 0,0,-2, got '%v'", version, b)
+		}
+		if b := s.Block(mcshapes.XYZ{X: 100, Y: 1, Z: -3}); b != "minecraft:air" {
+			t.Errorf("version %d: expected air at 100,1,-3, got '%v'", version, b)
+		}
+	}
+}
+
+// Test writing a Litematica file and reading the blocks of its region back
+func TestLitematicRoundTrip(t *testing.T) {
+	m := mcshapes.NewModel()
+	m.SetBlock(mcshapes.XYZ{X: 0, Y: 0, Z: -2}, "minecraft:stone 4")
+	m.SetBlock(mcshapes.XYZ{X: 1, Y: 2, Z: -3}, "minecraft:oak_stairs[facing=east]")
+	m.SetBlock(mcshapes.XYZ{X: 40, Y: 0, Z: -2}, "minecraft:glass")
+
+	var buf bytes.Buffer
+	if err := WriteLitematic(&buf, m, "test", DefaultDataVersion); err != nil {
+		t.Fatalf("WriteLitematic: %v", err)
+	}
+	_, root, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if v := Int(root.Get("Version")); v != litematicVersion {
+		t.Errorf("expected version %d, got %d", litematicVersion, v)
+	}
+	regions, _ := root.Get("Regions").(Compound)
+	region, ok := regions.Get("test").(Compound)
+	if !ok {
+		t.Fatalf("expected a region named test, got %v", regions)
+	}
+	pos, _ := region.Get("Position").(Compound)
+	size, _ := region.Get("Size").(Compound)
+	palette, _ := region.Get("BlockStatePalette").(List)
+	states, _ := region.Get("BlockStates").([]int64)
+	if len(palette) == 0 || stateFromCompound(palette[0].(Compound)) != "minecraft:air" {
+		t.Fatalf("expected air first in the palette, got %v", palette)
+	}
+
+	// Unpack the palette indexes, x + z*Size.x + y*Size.x*Size.z
+	bits := 2
+	for 1<<uint(bits) < len(palette) {
+		bits++
+	}
+	sx, sy, sz := Int(size.Get("x")), Int(size.Get("y")), Int(size.Get("z"))
+	r := mcshapes.NewModel()
+	for i := 0; i < sx*sy*sz; i++ {
+		start := i * bits
+		word, offset := start/64, uint(start%64)
+		v := uint64(states[word]) >> offset
+		if int(offset)+bits > 64 {
+			v |= uint64(states[word+1]) << (64 - offset)
+		}
+		block := stateFromCompound(palette[v&(1<<uint(bits)-1)].(Compound))
+		if block == "minecraft:air" {
+			continue
+		}
+		r.SetBlock(mcshapes.XYZ{X: Int(pos.Get("x")) + i%sx, Y: Int(pos.Get("y")) + i/(sx*sz),
+			Z: Int(pos.Get("z")) + i/sx%sz}, block)
+	}
+
+	expected := map[mcshapes.XYZ]string{
+		{X: 0, Y: 0, Z: -2}:  "minecraft:polished_diorite",
+		{X: 1, Y: 2, Z: -3}:  "minecraft:oak_stairs[facing=east]",
+		{X: 40, Y: 0, Z: -2}: "minecraft:glass",
+	}
+	if r.Len() != len(expected) {
+		t.Errorf("expected %d blocks, got %d", len(expected), r.Len())
+	}
+	for xyz, block := range expected {
+		if b := r.Block(xyz); b != block {
+			t.Errorf("expected %v at %v, got '%v'", block, xyz, b)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package mcnbt
+
+import (
+	"fmt"
+	"io"
+
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+)
+
+// Sponge schematic files
+//
+// Sponge schematics (.schem) are read by WorldEdit and most other editors. Version 2 has
+// everything in the root compound "Schematic", version 3 puts a "Schematic" compound in an
+// unnamed root and moves the blocks into a "Blocks" compound:
+//    Version          2 or 3
+//    DataVersion      version of Minecraft the file was written for
+//    Width, Height, Length   size in X, Y, Z
+//    Offset           [x, y, z]
+//    Palette          block state -> index            (v3: Blocks.Palette)
+//    BlockData        index of every block, varints   (v3: Blocks.Data)
+//    BlockEntities    always empty here               (v3: Blocks.BlockEntities)
+// The blocks are in the order x + z*Width + y*Width*Length. Positions with nothing placed
+// are written as air.
+//
+// The offsets are set so WorldEdit pastes the object where the function would build it,
+// relative to the player.
+
+// WriteSchematic writes a model as a Sponge schematic, version 2 or 3.
+func WriteSchematic(w io.Writer, m *mcshapes.Model, dataVersion int, version int) error {
+	lo, hi := m.Bounds()
+	width, height, length := hi.X-lo.X+1, hi.Y-lo.Y+1, hi.Z-lo.Z+1
+	if width > 0xffff || height > 0xffff || length > 0xffff {
+		return fmt.Errorf("schematic too large, %dx%dx%d", width, height, length)
+	}
+
+	pal := newPalette()
+	var data []byte
+	for y := lo.Y; y <= hi.Y; y++ {
+		for z := lo.Z; z <= hi.Z; z++ {
+			for x := lo.X; x <= hi.X; x++ {
+				block := m.Block(mcshapes.XYZ{X: x, Y: y, Z: z})
+				if block == "" {
+					block = "minecraft:air"
+				}
+				data = appendVarint(data, pal.index(block))
+			}
+		}
+	}
+
+	palette := Compound{}
+	for i, state := range pal.states {
+		palette = append(palette, Entry{state, int32(i)})
+	}
+
+	switch version {
+	case 2:
+		root := Compound{
+			{"Version", int32(2)},
+			{"DataVersion", int32(dataVersion)},
+			{"Width", int16(width)},
+			{"Height", int16(height)},
+			{"Length", int16(length)},
+			{"Offset", []int32{0, 0, 0}},
+			{"Metadata", Compound{
+				{"WEOffsetX", int32(lo.X)},
+				{"WEOffsetY", int32(lo.Y)},
+				{"WEOffsetZ", int32(lo.Z)},
+			}},
+			{"PaletteMax", int32(len(pal.states))},
+			{"Palette", palette},
+			{"BlockData", data},
+			{"BlockEntities", List{}},
+		}
+		return WriteGzip(w, "Schematic", root)
+
+	case 3:
+		root := Compound{
+			{"Schematic", Compound{
+				{"Version", int32(3)},
+				{"DataVersion", int32(dataVersion)},
+				{"Width", int16(width)},
+				{"Height", int16(height)},
+				{"Length", int16(length)},
+				{"Offset", []int32{int32(lo.X), int32(lo.Y), int32(lo.Z)}},
+				{"Blocks", Compound{
+					{"Palette", palette},
+					{"Data", data},
+					{"BlockEntities", List{}},
+				}},
+			}},
+		}
+		return WriteGzip(w, "", root)
+	}
+
+	return fmt.Errorf("unknown schematic version %d", version)
+}
+
+// appendVarint appends a value in the variable length format of the schematic block data,
+// 7 bits per byte with the high bit set on all but the last byte.
+func appendVarint(b []byte, v int32) []byte {
+	u := uint32(v)
+	for u >= 0x80 {
+		b = append(b, byte(u)|0x80)
+		u >>= 7
+	}
+	return append(b, byte(u))
+}
+
+// ReadSchematic reads a Sponge schematic, version 2 or 3, into a model. The blocks are at
+// their positions relative to where the schematic was copied, the same place WorldEdit
+// would paste them.
+func ReadSchematic(r io.Reader) (*mcshapes.Model, error) {
+	_, root, err := Read(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Version 3 has the schematic inside an unnamed root.
+	schem := root
+	if c, ok := root.Get("Schematic").(Compound); ok {
+		schem = c
+	}
+
+	var palette Compound
+	var data []byte
+	var off [3]int
+	switch version := Int(schem.Get("Version")); version {
+	case 1, 2:
+		palette, _ = schem.Get("Palette").(Compound)
+		data, _ = schem.Get("BlockData").([]byte)
+		if meta, ok := schem.Get("Metadata").(Compound); ok {
+			off = [3]int{Int(meta.Get("WEOffsetX")), Int(meta.Get("WEOffsetY")),
+				Int(meta.Get("WEOffsetZ"))}
+		}
+	case 3:
+		blocks, _ := schem.Get("Blocks").(Compound)
+		palette, _ = blocks.Get("Palette").(Compound)
+		data, _ = blocks.Get("Data").([]byte)
+		if o, ok := schem.Get("Offset").([]int32); ok && len(o) == 3 {
+			off = [3]int{int(o[0]), int(o[1]), int(o[2])}
+		}
+	default:
+		return nil, fmt.Errorf("unknown schematic version %d", version)
+	}
+
+	states := make(map[int]string)
+	for _, e := range palette {
+		states[Int(e.Value)] = e.Name
+	}
+
+	// Sizes are unsigned shorts.
+	width := int(uint16(Int(schem.Get("Width"))))
+	height := int(uint16(Int(schem.Get("Height"))))
+	length := int(uint16(Int(schem.Get("Length"))))
+
+	m := mcshapes.NewModel()
+	i := 0
+	for y := 0; y < height; y++ {
+		for z := 0; z < length; z++ {
+			for x := 0; x < width; x++ {
+				var v int
+				v, data, err = readVarint(data)
+				if err != nil {
+					return nil, fmt.Errorf("schematic block %d: %v", i, err)
+				}
+				state, ok := states[v]
+				if !ok {
+					return nil, fmt.Errorf("schematic block %d: %d is not in the palette", i, v)
+				}
+				m.SetBlock(mcshapes.XYZ{X: off[0] + x, Y: off[1] + y, Z: off[2] + z}, state)
+				i++
+			}
+		}
+	}
+	return m, nil
+}
+
+// readVarint reads one value written by appendVarint and returns the rest of the data
+func readVarint(b []byte) (int, []byte, error) {
+	v := 0
+	for i := 0; i < len(b) && i < 5; i++ {
+		v |= int(b[i]&0x7f) << uint(7*i)
+		if b[i]&0x80 == 0 {
+			return v, b[i+1:], nil
+		}
+	}
+	return 0, nil, fmt.Errorf("block data is too short")
+}
@@ -0,0 +1,150 @@
+package mcnbt
+
+import (
+	"fmt"
+	"io"
+
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+)
+
+// Vanilla structure files
+//
+// Structure files (.nbt) are what the structure block saves and loads. They go in the
+// structures directory of a datapack,
+//    data/<namespace>/structures/<path>.nbt
+// and are placed in the game with a structure block or with
+//    /place template <namespace>:<path> ~ ~ ~ <rotation> <mirror>
+// so the game itself does the rotation and mirroring.
+//
+// The file is a gzip compressed compound:
+//    DataVersion  version of Minecraft the file was written for
+//    size         [x, y, z] size of the structure
+//    palette      list of block states, {Name, Properties}
+//    blocks       list of {state, pos} where state is an index into the palette and pos is
+//                 [x, y, z] from the lowest corner of the structure
+//    entities     list of entities, always empty here
+// Positions that are not in the blocks list are left as they are when the structure is
+// placed, the same as a structure void block.
+
+// DefaultDataVersion is the data version of Minecraft 1.20.1
+const DefaultDataVersion = 3465
+
+// WriteStructure writes a model as a vanilla structure file.
+// The lowest corner of the model is at 0,0,0 in the structure.
+func WriteStructure(w io.Writer, m *mcshapes.Model, dataVersion int) error {
+	lo, hi := m.Bounds()
+	pal := newPalette()
+
+	blocks := List{}
+	for _, xyz := range m.Positions() {
+		state := pal.index(m.Block(xyz))
+		pos := List{int32(xyz.X - lo.X), int32(xyz.Y - lo.Y), int32(xyz.Z - lo.Z)}
+		blocks = append(blocks, Compound{{"state", state}, {"pos", pos}})
+	}
+
+	root := Compound{
+		{"DataVersion", int32(dataVersion)},
+		{"size", List{int32(hi.X - lo.X + 1), int32(hi.Y - lo.Y + 1), int32(hi.Z - lo.Z + 1)}},
+		{"palette", pal.list()},
+		{"blocks", blocks},
+		{"entities", List{}},
+	}
+	return WriteGzip(w, "", root)
+}
+
+// palette numbers the distinct block states of a model in the order they are first used
+type palette struct {
+	states []string
+	ids    map[string]int32
+}
+
+func newPalette() *palette {
+	return &palette{ids: make(map[string]int32)}
+}
+
+// index returns the palette index for a block, adding it to the palette if needed.
+// Blocks written in the old and the new form for the same block state share one entry.
+func (p *palette) index(block string) int32 {
+	state := mcshapes.BlockString(block)
+	id, ok := p.ids[state]
+	if !ok {
+		id = int32(len(p.states))
+		p.ids[state] = id
+		p.states = append(p.states, state)
+	}
+	return id
+}
+
+// list returns the palette as a list of {Name, Properties} compounds
+func (p *palette) list() List {
+	l := List{}
+	for _, state := range p.states {
+		name, props := mcshapes.BlockState(state)
+		c := Compound{{"Name", name}}
+		if len(props) > 0 {
+			c = append(c, Entry{"Properties", propertyCompound(props)})
+		}
+		l = append(l, c)
+	}
+	return l
+}
+
+// propertyCompound returns block state properties as a compound of strings
+func propertyCompound(props map[string]string) Compound {
+	c := Compound{}
+	for _, k := range mcshapes.SortedKeys(props) {
+		c = append(c, Entry{k, props[k]})
+	}
+	return c
+}
+
+// ReadStructure reads a vanilla structure file into a model. The blocks are at their
+// positions in the structure, from 0,0,0.
+func ReadStructure(r io.Reader) (*mcshapes.Model, error) {
+	_, root, err := Read(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Structures with several palettes (shipwrecks, ...) use the first one.
+	palList, _ := root.Get("palette").(List)
+	if palettes, ok := root.Get("palettes").(List); ok && len(palettes) > 0 {
+		palList, _ = palettes[0].(List)
+	}
+	states := make([]string, len(palList))
+	for i, p := range palList {
+		c, _ := p.(Compound)
+		states[i] = stateFromCompound(c)
+	}
+
+	blocks, ok := root.Get("blocks").(List)
+	if !ok {
+		return nil, fmt.Errorf("structure has no blocks")
+	}
+	m := mcshapes.NewModel()
+	for _, b := range blocks {
+		c, _ := b.(Compound)
+		pos, _ := c.Get("pos").(List)
+		state := Int(c.Get("state"))
+		if len(pos) != 3 || state < 0 || state >= len(states) {
+			return nil, fmt.Errorf("structure block %v is not valid", c)
+		}
+		m.SetBlock(mcshapes.XYZ{X: Int(pos[0]), Y: Int(pos[1]), Z: Int(pos[2])}, states[state])
+	}
+	return m, nil
+}
+
+// stateFromCompound returns the block state of a palette compound {Name, Properties}
+// in the form name[key=value,...]
+func stateFromCompound(c Compound) string {
+	name, _ := c.Get("Name").(string)
+	props := make(map[string]string)
+	if pc, ok := c.Get("Properties").(Compound); ok {
+		for _, e := range pc {
+			if v, ok := e.Value.(string); ok {
+				props[e.Name] = v
+			}
+		}
+	}
+	return mcshapes.FormatBlockState(name, props)
+}
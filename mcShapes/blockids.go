@@ -0,0 +1,760 @@
+package mcshapes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Block ids
+//
+// The blocks of each version of Minecraft, for checking functions before they go in the game.
+// Versions are given by their minor number, 12 for 1.12 and 20 for 1.20.1. Minecraft 1.12
+// has the old block names with data values, see BlockState, 1.13 and later have the new block
+// names with block states.
+//
+// The new blocks are listed with the version they were added in and the keys of their block
+// states. A block listed with "*" may have any block state, its states are not checked.
+
+// ParseVersion returns the minor number of a Minecraft version, e.g. 20 for "1.20.1"
+func ParseVersion(version string) (int, error) {
+	parts := strings.Split(version, ".")
+	if len(parts) < 2 || parts[0] != "1" {
+		return 0, fmt.Errorf("version %q is not a Minecraft version, e.g. 1.20.1", version)
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil || minor < 12 {
+		return 0, fmt.Errorf("version %q is not supported, only 1.12 and later", version)
+	}
+	return minor, nil
+}
+
+// KnownBlock reports whether a block id, e.g. "minecraft:oak_planks", is in a version of
+// Minecraft. Blocks in other namespaces are from mods and are not known.
+func KnownBlock(id string, version int) bool {
+	name := strings.TrimPrefix(id, "minecraft:")
+	if version <= 12 {
+		return legacyBlocks[name]
+	}
+	b, ok := modernBlocks[name]
+	return ok && b.since <= version && (b.until == 0 || version < b.until)
+}
+
+// BlockStateKeys returns the block state keys of a block in 1.13 and later. It reports
+// false if the states of the block are not known, then any state is allowed.
+func BlockStateKeys(id string) ([]string, bool) {
+	b, ok := modernBlocks[strings.TrimPrefix(id, "minecraft:")]
+	if !ok || (len(b.states) == 1 && b.states[0] == "*") {
+		return nil, false
+	}
+	return b.states, true
+}
+
+// blockInfo is a block of 1.13 or later
+type blockInfo struct {
+	since  int
+	until  int
+	states []string
+}
+
+// Block states shared by families of blocks
+var (
+	statesStairs    = []string{"facing", "half", "shape", "waterlogged"}
+	statesSlab      = []string{"type", "waterlogged"}
+	statesWall      = []string{"east", "north", "south", "west", "up", "waterlogged"}
+	statesFence     = []string{"east", "north", "south", "west", "waterlogged"}
+	statesFenceGate = []string{"facing", "in_wall", "open", "powered"}
+	statesDoor      = []string{"facing", "half", "hinge", "open", "powered"}
+	statesTrapdoor  = []string{"facing", "half", "open", "powered", "waterlogged"}
+	statesButton    = []string{"face", "facing", "powered"}
+	statesPlate     = []string{"powered"}
+	statesSign      = []string{"rotation", "waterlogged"}
+	statesWallSign  = []string{"facing", "waterlogged"}
+	statesHanging   = []string{"attached", "rotation", "waterlogged"}
+	statesAxis      = []string{"axis"}
+	statesLeaves    = []string{"distance", "persistent", "waterlogged"}
+	statesSapling   = []string{"stage"}
+	statesFacing    = []string{"facing"}
+	statesRotation  = []string{"rotation"}
+	statesBed       = []string{"facing", "occupied", "part"}
+	statesCandle    = []string{"candles", "lit", "waterlogged"}
+	statesMushroom  = []string{"down", "east", "north", "south", "up", "west"}
+	statesCoral     = []string{"waterlogged"}
+	statesWallFan   = []string{"facing", "waterlogged"}
+	statesHalf      = []string{"half"}
+	statesAge       = []string{"age"}
+	statesSnowy     = []string{"snowy"}
+	statesRail      = []string{"powered", "shape", "waterlogged"}
+	statesLit       = []string{"facing", "lit"}
+)
+
+// Woods and the version they were added in. Crimson and warped are fungi with stems, bamboo
+// has no logs or trees.
+var blockWoods = []struct {
+	name  string
+	since int
+	tree  bool
+	log   string
+	wood  string
+}{
+	{"oak", 13, true, "log", "wood"},
+	{"spruce", 13, true, "log", "wood"},
+	{"birch", 13, true, "log", "wood"},
+	{"jungle", 13, true, "log", "wood"},
+	{"acacia", 13, true, "log", "wood"},
+	{"dark_oak", 13, true, "log", "wood"},
+	{"crimson", 16, false, "stem", "hyphae"},
+	{"warped", 16, false, "stem", "hyphae"},
+	{"mangrove", 19, true, "log", "wood"},
+	{"cherry", 20, true, "log", "wood"},
+	{"bamboo", 20, false, "", ""},
+}
+
+// Stones with stairs, slabs and walls, by the version the stairs, slab and wall were added.
+// The name is the prefix of the stairs, e.g. "stone_brick" for stone_brick_stairs.
+var blockStoneShapes = []struct {
+	name               string
+	stairs, slab, wall int
+}{
+	{"stone", 14, 13, 0},
+	{"cobblestone", 13, 13, 13},
+	{"mossy_cobblestone", 14, 14, 13},
+	{"stone_brick", 13, 13, 14},
+	{"mossy_stone_brick", 14, 14, 14},
+	{"brick", 13, 13, 14},
+	{"nether_brick", 13, 13, 14},
+	{"red_nether_brick", 14, 14, 14},
+	{"sandstone", 13, 13, 14},
+	{"smooth_sandstone", 14, 14, 0},
+	{"cut_sandstone", 0, 13, 0},
+	{"red_sandstone", 13, 13, 14},
+	{"smooth_red_sandstone", 14, 14, 0},
+	{"cut_red_sandstone", 0, 13, 0},
+	{"quartz", 13, 13, 0},
+	{"smooth_quartz", 14, 14, 0},
+	{"purpur", 13, 13, 0},
+	{"prismarine", 13, 13, 14},
+	{"prismarine_brick", 13, 13, 0},
+	{"dark_prismarine", 13, 13, 0},
+	{"petrified_oak", 0, 13, 0},
+	{"smooth_stone", 0, 14, 0},
+	{"granite", 14, 14, 14},
+	{"polished_granite", 14, 14, 0},
+	{"diorite", 14, 14, 14},
+	{"polished_diorite", 14, 14, 0},
+	{"andesite", 14, 14, 14},
+	{"polished_andesite", 14, 14, 0},
+	{"end_stone_brick", 14, 14, 14},
+	{"blackstone", 16, 16, 16},
+	{"polished_blackstone", 16, 16, 16},
+	{"polished_blackstone_brick", 16, 16, 16},
+	{"cobbled_deepslate", 17, 17, 17},
+	{"polished_deepslate", 17, 17, 17},
+	{"deepslate_brick", 17, 17, 17},
+	{"deepslate_tile", 17, 17, 17},
+	{"cut_copper", 17, 17, 0},
+	{"exposed_cut_copper", 17, 17, 0},
+	{"weathered_cut_copper", 17, 17, 0},
+	{"oxidized_cut_copper", 17, 17, 0},
+	{"waxed_cut_copper", 17, 17, 0},
+	{"waxed_exposed_cut_copper", 17, 17, 0},
+	{"waxed_weathered_cut_copper", 17, 17, 0},
+	{"waxed_oxidized_cut_copper", 17, 17, 0},
+	{"mud_brick", 19, 19, 19},
+	{"bamboo_mosaic", 20, 20, 0},
+}
+
+// Other blocks by the version they were added in, one block a line with the keys of its
+// block states.
+var blockLines = map[int]string{
+	13: `
+air
+cave_air
+void_air
+stone
+granite
+polished_granite
+diorite
+polished_diorite
+andesite
+polished_andesite
+grass_block snowy
+dirt
+coarse_dirt
+podzol snowy
+mycelium snowy
+cobblestone
+mossy_cobblestone
+bedrock
+sand
+red_sand
+gravel
+gold_ore
+iron_ore
+coal_ore
+lapis_ore
+diamond_ore
+emerald_ore
+redstone_ore lit
+nether_quartz_ore
+sponge
+wet_sponge
+glass
+lapis_block
+gold_block
+iron_block
+diamond_block
+emerald_block
+redstone_block
+coal_block
+quartz_block
+chiseled_quartz_block
+quartz_pillar axis
+sandstone
+chiseled_sandstone
+cut_sandstone
+red_sandstone
+chiseled_red_sandstone
+cut_red_sandstone
+smooth_stone
+smooth_sandstone
+smooth_red_sandstone
+smooth_quartz
+bricks
+stone_bricks
+mossy_stone_bricks
+cracked_stone_bricks
+chiseled_stone_bricks
+infested_stone
+infested_cobblestone
+infested_stone_bricks
+infested_mossy_stone_bricks
+infested_cracked_stone_bricks
+infested_chiseled_stone_bricks
+nether_bricks
+red_nether_bricks
+end_stone
+end_stone_bricks
+purpur_block
+purpur_pillar axis
+prismarine
+prismarine_bricks
+dark_prismarine
+sea_lantern
+tnt unstable
+bookshelf
+obsidian
+crafting_table
+ice
+packed_ice
+blue_ice
+frosted_ice age
+snow_block
+snow layers
+clay
+terracotta
+pumpkin
+carved_pumpkin facing
+jack_o_lantern facing
+melon
+netherrack
+soul_sand
+glowstone
+magma_block
+nether_wart_block
+bone_block axis
+hay_block axis
+slime_block
+dried_kelp_block
+spawner
+cobweb
+grass
+fern
+dead_bush
+tall_grass half
+large_fern half
+sunflower half
+lilac half
+rose_bush half
+peony half
+dandelion
+poppy
+blue_orchid
+allium
+azure_bluet
+red_tulip
+orange_tulip
+white_tulip
+pink_tulip
+oxeye_daisy
+brown_mushroom
+red_mushroom
+brown_mushroom_block down east north south up west
+red_mushroom_block down east north south up west
+mushroom_stem down east north south up west
+torch
+wall_torch facing
+redstone_torch lit
+redstone_wall_torch facing lit
+fire age east north south up west
+water level
+lava level
+bubble_column drag
+note_block instrument note powered
+jukebox has_record
+beacon
+conduit waterlogged
+enchanting_table
+end_portal
+end_portal_frame eye facing
+end_gateway
+nether_portal axis
+dragon_egg
+ladder facing waterlogged
+rail shape waterlogged
+powered_rail powered shape waterlogged
+detector_rail powered shape waterlogged
+activator_rail powered shape waterlogged
+lever face facing powered
+stone_button face facing powered
+stone_pressure_plate powered
+heavy_weighted_pressure_plate power
+light_weighted_pressure_plate power
+redstone_wire east north power south west
+redstone_lamp lit
+repeater delay facing locked powered
+comparator facing mode powered
+observer facing powered
+piston extended facing
+sticky_piston extended facing
+piston_head facing short type
+moving_piston facing type
+dispenser facing triggered
+dropper facing triggered
+hopper enabled facing
+chest facing type waterlogged
+trapped_chest facing type waterlogged
+ender_chest facing waterlogged
+furnace facing lit
+cactus age
+sugar_cane age
+kelp age
+kelp_plant
+seagrass
+tall_seagrass half
+sea_pickle pickles waterlogged
+turtle_egg eggs hatch
+farmland moisture
+wheat age
+carrots age
+potatoes age
+beetroots age
+melon_stem age
+pumpkin_stem age
+attached_melon_stem facing
+attached_pumpkin_stem facing
+nether_wart age
+cocoa age facing
+vine east north south up west
+lily_pad
+iron_bars east north south west waterlogged
+glass_pane east north south west waterlogged
+iron_door facing half hinge open powered
+iron_trapdoor facing half open powered waterlogged
+brewing_stand has_bottle_0 has_bottle_1 has_bottle_2
+cauldron *
+anvil facing
+chipped_anvil facing
+damaged_anvil facing
+daylight_detector inverted power
+tripwire_hook attached facing powered
+tripwire attached disarmed east north powered south west
+command_block conditional facing
+chain_command_block conditional facing
+repeating_command_block conditional facing
+structure_block mode
+structure_void
+barrier
+cake bites
+chorus_plant down east north south up west
+chorus_flower age
+end_rod facing
+skeleton_skull rotation
+skeleton_wall_skull facing
+wither_skeleton_skull rotation
+wither_skeleton_wall_skull facing
+zombie_head rotation
+zombie_wall_head facing
+player_head rotation
+player_wall_head facing
+creeper_head rotation
+creeper_wall_head facing
+dragon_head rotation
+dragon_wall_head facing
+flower_pot
+potted_dandelion
+potted_poppy
+potted_blue_orchid
+potted_allium
+potted_azure_bluet
+potted_red_tulip
+potted_orange_tulip
+potted_white_tulip
+potted_pink_tulip
+potted_oxeye_daisy
+potted_red_mushroom
+potted_brown_mushroom
+potted_dead_bush
+potted_fern
+potted_cactus
+shulker_box facing
+sign rotation waterlogged
+wall_sign facing waterlogged
+`,
+	14: `
+barrel facing open
+blast_furnace facing lit
+smoker facing lit
+cartography_table
+fletching_table
+smithing_table
+grindstone face facing
+lectern facing has_book powered
+loom facing
+stonecutter facing
+bell attachment facing powered
+lantern *
+campfire facing lit signal_fire waterlogged
+scaffolding bottom distance waterlogged
+sweet_berry_bush age
+bamboo age leaves stage
+bamboo_sapling
+potted_bamboo
+composter level
+jigsaw *
+cornflower
+lily_of_the_valley
+wither_rose
+potted_cornflower
+potted_lily_of_the_valley
+potted_wither_rose
+`,
+	15: `
+bee_nest facing honey_level
+beehive facing honey_level
+honey_block
+honeycomb_block
+`,
+	16: `
+crimson_nylium
+warped_nylium
+crimson_fungus
+warped_fungus
+crimson_roots
+warped_roots
+potted_crimson_fungus
+potted_warped_fungus
+potted_crimson_roots
+potted_warped_roots
+warped_wart_block
+nether_sprouts
+twisting_vines age
+twisting_vines_plant
+weeping_vines age
+weeping_vines_plant
+shroomlight
+ancient_debris
+netherite_block
+basalt axis
+polished_basalt axis
+soul_soil
+soul_fire
+soul_torch
+soul_wall_torch facing
+soul_lantern *
+soul_campfire facing lit signal_fire waterlogged
+blackstone
+polished_blackstone
+polished_blackstone_bricks
+cracked_polished_blackstone_bricks
+chiseled_polished_blackstone
+gilded_blackstone
+polished_blackstone_button face facing powered
+polished_blackstone_pressure_plate powered
+crying_obsidian
+respawn_anchor charges
+lodestone
+target power
+chain axis waterlogged
+nether_gold_ore
+quartz_bricks
+chiseled_nether_bricks
+cracked_nether_bricks
+`,
+	17: `
+copper_ore
+deepslate_copper_ore
+raw_copper_block
+raw_iron_block
+raw_gold_block
+copper_block
+exposed_copper
+weathered_copper
+oxidized_copper
+waxed_copper_block
+waxed_exposed_copper
+waxed_weathered_copper
+waxed_oxidized_copper
+cut_copper
+exposed_cut_copper
+weathered_cut_copper
+oxidized_cut_copper
+waxed_cut_copper
+waxed_exposed_cut_copper
+waxed_weathered_cut_copper
+waxed_oxidized_cut_copper
+deepslate axis
+cobbled_deepslate
+polished_deepslate
+deepslate_bricks
+cracked_deepslate_bricks
+deepslate_tiles
+cracked_deepslate_tiles
+chiseled_deepslate
+deepslate_coal_ore
+deepslate_iron_ore
+deepslate_gold_ore
+deepslate_lapis_ore
+deepslate_diamond_ore
+deepslate_emerald_ore
+deepslate_redstone_ore lit
+calcite
+tuff
+tinted_glass
+amethyst_block
+budding_amethyst
+amethyst_cluster facing waterlogged
+small_amethyst_bud facing waterlogged
+medium_amethyst_bud facing waterlogged
+large_amethyst_bud facing waterlogged
+dripstone_block
+pointed_dripstone thickness vertical_direction waterlogged
+moss_block
+moss_carpet
+azalea
+flowering_azalea
+potted_azalea_bush
+potted_flowering_azalea_bush
+azalea_leaves distance persistent waterlogged
+flowering_azalea_leaves distance persistent waterlogged
+rooted_dirt
+hanging_roots waterlogged
+spore_blossom
+big_dripleaf facing tilt waterlogged
+big_dripleaf_stem facing waterlogged
+small_dripleaf facing half waterlogged
+glow_lichen down east north south up waterlogged west
+cave_vines age berries
+cave_vines_plant berries
+powder_snow
+water_cauldron level
+lava_cauldron
+powder_snow_cauldron level
+lightning_rod facing powered waterlogged
+sculk_sensor power sculk_sensor_phase waterlogged
+smooth_basalt
+light level waterlogged
+candle candles lit waterlogged
+candle_cake lit
+`,
+	19: `
+mud
+packed_mud
+mud_bricks
+muddy_mangrove_roots axis
+mangrove_roots waterlogged
+mangrove_propagule age hanging stage waterlogged
+potted_mangrove_propagule
+sculk
+sculk_vein down east north south up waterlogged west
+sculk_catalyst bloom
+sculk_shrieker can_summon shrieking waterlogged
+reinforced_deepslate
+ochre_froglight axis
+verdant_froglight axis
+pearlescent_froglight axis
+frogspawn
+`,
+	20: `
+bamboo_block axis
+stripped_bamboo_block axis
+bamboo_mosaic
+chiseled_bookshelf *
+decorated_pot *
+pink_petals facing flower_amount
+torchflower
+potted_torchflower
+torchflower_crop age
+pitcher_plant half
+pitcher_crop age half
+suspicious_sand dusted
+suspicious_gravel dusted
+calibrated_sculk_sensor facing power sculk_sensor_phase waterlogged
+sniffer_egg hatch
+piglin_head rotation
+piglin_wall_head facing
+`,
+}
+
+// Blocks that were renamed after 1.13, they are not in the version with the new name.
+var blockRenames = []struct {
+	name  string
+	until int
+}{
+	{"sign", 14},
+	{"wall_sign", 14},
+}
+
+// Minecraft 1.12 block ids.
+var legacyBlockIds = `air stone grass dirt cobblestone planks sapling bedrock flowing_water water
+flowing_lava lava sand gravel gold_ore iron_ore coal_ore log leaves sponge glass lapis_ore
+lapis_block dispenser sandstone noteblock bed golden_rail detector_rail sticky_piston web
+tallgrass deadbush piston piston_head wool piston_extension yellow_flower red_flower
+brown_mushroom red_mushroom gold_block iron_block double_stone_slab stone_slab brick_block tnt
+bookshelf mossy_cobblestone obsidian torch fire mob_spawner oak_stairs chest redstone_wire
+diamond_ore diamond_block crafting_table wheat farmland furnace lit_furnace standing_sign
+wooden_door ladder rail stone_stairs wall_sign lever stone_pressure_plate iron_door
+wooden_pressure_plate redstone_ore lit_redstone_ore unlit_redstone_torch redstone_torch
+stone_button snow_layer ice snow cactus clay reeds jukebox fence pumpkin netherrack soul_sand
+glowstone portal lit_pumpkin cake unpowered_repeater powered_repeater stained_glass trapdoor
+monster_egg stonebrick brown_mushroom_block red_mushroom_block iron_bars glass_pane
+melon_block pumpkin_stem melon_stem vine fence_gate brick_stairs stone_brick_stairs mycelium
+waterlily nether_brick nether_brick_fence nether_brick_stairs nether_wart enchanting_table
+brewing_stand cauldron end_portal end_portal_frame end_stone dragon_egg redstone_lamp
+lit_redstone_lamp double_wooden_slab wooden_slab cocoa sandstone_stairs emerald_ore
+ender_chest tripwire_hook tripwire emerald_block spruce_stairs birch_stairs jungle_stairs
+command_block beacon cobblestone_wall flower_pot carrots potatoes wooden_button skull anvil
+trapped_chest light_weighted_pressure_plate heavy_weighted_pressure_plate
+unpowered_comparator powered_comparator daylight_detector redstone_block quartz_ore hopper
+quartz_block quartz_stairs activator_rail dropper stained_hardened_clay stained_glass_pane
+leaves2 log2 acacia_stairs dark_oak_stairs slime barrier iron_trapdoor prismarine
+sea_lantern hay_block carpet hardened_clay coal_block packed_ice double_plant
+standing_banner wall_banner daylight_detector_inverted red_sandstone red_sandstone_stairs
+double_stone_slab2 stone_slab2 spruce_fence_gate birch_fence_gate jungle_fence_gate
+dark_oak_fence_gate acacia_fence_gate spruce_fence birch_fence jungle_fence dark_oak_fence
+acacia_fence spruce_door birch_door jungle_door acacia_door dark_oak_door end_rod
+chorus_plant chorus_flower purpur_block purpur_pillar purpur_stairs purpur_double_slab
+purpur_slab end_bricks beetroots grass_path end_gateway repeating_command_block
+chain_command_block frosted_ice magma nether_wart_block red_nether_brick bone_block
+structure_void observer concrete concrete_powder structure_block`
+
+// The 1.12 colors, light gray was silver
+var legacyColors = []string{"white", "orange", "magenta", "light_blue", "yellow", "lime",
+	"pink", "gray", "silver", "cyan", "purple", "blue", "brown", "green", "red", "black"}
+
+var (
+	modernBlocks = make(map[string]blockInfo)
+	legacyBlocks = make(map[string]bool)
+)
+
+func init() {
+	add := func(name string, since int, states []string) {
+		modernBlocks[name] = blockInfo{since: since, states: states}
+	}
+	later := func(a int, b int) int {
+		if a > b {
+			return a
+		}
+		return b
+	}
+
+	for since, lines := range blockLines {
+		for _, line := range strings.Split(lines, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) > 0 {
+				add(fields[0], since, fields[1:])
+			}
+		}
+	}
+
+	for _, w := range blockWoods {
+		add(w.name+"_planks", w.since, nil)
+		add(w.name+"_stairs", w.since, statesStairs)
+		add(w.name+"_slab", w.since, statesSlab)
+		add(w.name+"_fence", w.since, statesFence)
+		add(w.name+"_fence_gate", w.since, statesFenceGate)
+		add(w.name+"_door", w.since, statesDoor)
+		add(w.name+"_trapdoor", w.since, statesTrapdoor)
+		add(w.name+"_button", w.since, statesButton)
+		add(w.name+"_pressure_plate", w.since, statesPlate)
+		add(w.name+"_sign", later(w.since, 14), statesSign)
+		add(w.name+"_wall_sign", later(w.since, 14), statesWallSign)
+		add(w.name+"_hanging_sign", 20, statesHanging)
+		add(w.name+"_wall_hanging_sign", 20, statesWallSign)
+		if w.log != "" {
+			add(w.name+"_"+w.log, w.since, statesAxis)
+			add(w.name+"_"+w.wood, w.since, statesAxis)
+			add("stripped_"+w.name+"_"+w.log, w.since, statesAxis)
+			add("stripped_"+w.name+"_"+w.wood, w.since, statesAxis)
+		}
+		if w.tree {
+			add(w.name+"_leaves", w.since, statesLeaves)
+			if w.name != "mangrove" {
+				add(w.name+"_sapling", w.since, statesSapling)
+				add("potted_"+w.name+"_sapling", w.since, nil)
+			}
+		}
+	}
+
+	for _, s := range blockStoneShapes {
+		if s.stairs > 0 {
+			add(s.name+"_stairs", s.stairs, statesStairs)
+		}
+		if s.slab > 0 {
+			add(s.name+"_slab", s.slab, statesSlab)
+		}
+		if s.wall > 0 {
+			add(s.name+"_wall", s.wall, statesWall)
+		}
+	}
+
+	for _, c := range blockColors {
+		add(c+"_wool", 13, nil)
+		add(c+"_carpet", 13, nil)
+		add(c+"_concrete", 13, nil)
+		add(c+"_concrete_powder", 13, nil)
+		add(c+"_terracotta", 13, nil)
+		add(c+"_glazed_terracotta", 13, statesFacing)
+		add(c+"_stained_glass", 13, nil)
+		add(c+"_stained_glass_pane", 13, statesFence)
+		add(c+"_shulker_box", 13, statesFacing)
+		add(c+"_bed", 13, statesBed)
+		add(c+"_banner", 13, statesRotation)
+		add(c+"_wall_banner", 13, statesFacing)
+		add(c+"_candle", 17, statesCandle)
+		add(c+"_candle_cake", 17, []string{"lit"})
+	}
+
+	for _, c := range []string{"tube", "brain", "bubble", "fire", "horn"} {
+		for _, dead := range []string{"", "dead_"} {
+			add(dead+c+"_coral_block", 13, nil)
+			add(dead+c+"_coral", 13, statesCoral)
+			add(dead+c+"_coral_fan", 13, statesCoral)
+			add(dead+c+"_coral_wall_fan", 13, statesWallFan)
+		}
+	}
+
+	for _, r := range blockRenames {
+		b := modernBlocks[r.name]
+		b.until = r.until
+		modernBlocks[r.name] = b
+	}
+
+	for _, id := range strings.Fields(legacyBlockIds) {
+		legacyBlocks[id] = true
+	}
+	for _, c := range legacyColors {
+		legacyBlocks[c+"_shulker_box"] = true
+		legacyBlocks[c+"_glazed_terracotta"] = true
+	}
+}
@@ -0,0 +1,83 @@
+package mcshapes
+
+import (
+	"testing"
+)
+
+// Test that blocks are known in the versions they are in
+func TestKnownBlock(t *testing.T) {
+	tests := []struct {
+		id      string
+		version int
+		known   bool
+	}{
+		{"minecraft:stone", 12, true},
+		{"monster_egg", 12, true},
+		{"minecraft:silver_glazed_terracotta", 12, true},
+		{"minecraft:oak_planks", 12, false},
+		{"minecraft:oak_planks", 13, true},
+		{"minecraft:light_gray_wool", 20, true},
+		{"minecraft:sign", 13, true},
+		{"minecraft:sign", 14, false},
+		{"minecraft:oak_sign", 14, true},
+		{"minecraft:cherry_log", 19, false},
+		{"minecraft:cherry_log", 20, true},
+		{"minecraft:deepslate_tile_wall", 17, true},
+		{"minecraft:granite_wall", 13, false},
+		{"minecraft:monster_egg", 13, false},
+	}
+	for _, test := range tests {
+		if known := KnownBlock(test.id, test.version); known != test.known {
+			t.Errorf("KnownBlock(%v, %d) = %v, expected %v", test.id, test.version, known,
+				test.known)
+		}
+	}
+
+	keys, ok := BlockStateKeys("minecraft:oak_stairs")
+	if !ok || len(keys) != 4 {
+		t.Errorf("expected the 4 states of stairs, got %v", keys)
+	}
+	if _, ok := BlockStateKeys("minecraft:chiseled_bookshelf"); ok {
+		t.Errorf("expected any states for a chiseled bookshelf")
+	}
+}
+
+// Test reading Minecraft versions
+func TestParseVersion(t *testing.T) {
+	for version, expected := range map[string]int{"1.12": 12, "1.20.1": 20, "1.18.2": 18} {
+		if v, err := ParseVersion(version); err != nil || v != expected {
+			t.Errorf("ParseVersion(%v) = %v, %v, expected %v", version, v, err, expected)
+		}
+	}
+	for _, version := range []string{"1.8", "20", "2.0", "1.x"} {
+		if _, err := ParseVersion(version); err == nil {
+			t.Errorf("expected an error for version %v", version)
+		}
+	}
+}
+
+// Test converting blocks back to their 1.12 ids
+func TestLegacyBlock(t *testing.T) {
+	tests := []struct {
+		block, legacy string
+		ok            bool
+	}{
+		{"minecraft:polished_diorite", "minecraft:stone 4", true},
+		{"minecraft:stone 4", "minecraft:stone 4", true},
+		{"minecraft:light_gray_wool", "minecraft:wool 8", true},
+		{"minecraft:powered_rail", "minecraft:golden_rail", true},
+		{"minecraft:grass_block[snowy=false]", "minecraft:grass", true},
+		{"minecraft:spruce_log[axis=z]", "minecraft:log 9", true},
+		{"minecraft:glass", "minecraft:glass", true},
+		{"minecraft:oak_stairs[facing=east,half=bottom,shape=straight]", "", false},
+		{"minecraft:deepslate", "", false},
+		{"mymod:block", "", false},
+	}
+	for _, test := range tests {
+		legacy, ok := LegacyBlock(test.block)
+		if legacy != test.legacy || ok != test.ok {
+			t.Errorf("LegacyBlock(%v) = %v, %v, expected %v, %v", test.block, legacy, ok,
+				test.legacy, test.ok)
+		}
+	}
+}
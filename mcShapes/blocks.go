@@ -0,0 +1,255 @@
+package mcshapes
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Block names
+//
+// The functions written by mcFunctionDev use the Minecraft 1.12 block names, some with a
+// data value after the name, for example
+//    minecraft:stone 4        polished diorite
+//    minecraft:monster_egg 2  infested stone bricks
+// Minecraft 1.13 replaced the data values with block names and block states, for example
+//    minecraft:polished_diorite
+//    minecraft:oak_stairs[facing=east,half=bottom]
+// The file formats used by other tools (structures, schematics, ...) only know the new names,
+// so both forms are converted to a name and a set of block state properties here.
+
+// Colors in the order of their 1.12 data values, for wool, stained glass, ...
+var blockColors = []string{"white", "orange", "magenta", "light_blue", "yellow", "lime",
+	"pink", "gray", "light_gray", "cyan", "purple", "blue", "brown", "green", "red", "black"}
+
+// Blocks that had a data value in 1.12, the new names are in order of the data value.
+var legacyDataBlocks = map[string][]string{
+	"stone": {"stone", "granite", "polished_granite", "diorite", "polished_diorite",
+		"andesite", "polished_andesite"},
+	"dirt":      {"dirt", "coarse_dirt", "podzol"},
+	"planks":    {"oak_planks", "spruce_planks", "birch_planks", "jungle_planks", "acacia_planks", "dark_oak_planks"},
+	"log":       {"oak_log", "spruce_log", "birch_log", "jungle_log"},
+	"log2":      {"acacia_log", "dark_oak_log"},
+	"sandstone": {"sandstone", "chiseled_sandstone", "cut_sandstone"},
+	"stonebrick": {"stone_bricks", "mossy_stone_bricks", "cracked_stone_bricks",
+		"chiseled_stone_bricks"},
+	"monster_egg": {"infested_stone", "infested_cobblestone", "infested_stone_bricks",
+		"infested_mossy_stone_bricks", "infested_cracked_stone_bricks",
+		"infested_chiseled_stone_bricks"},
+}
+
+// Blocks with a color as the data value in 1.12, and the suffix of the new name.
+var legacyColorBlocks = map[string]string{
+	"wool":                  "_wool",
+	"carpet":                "_carpet",
+	"stained_glass":         "_stained_glass",
+	"stained_glass_pane":    "_stained_glass_pane",
+	"stained_hardened_clay": "_terracotta",
+	"concrete":              "_concrete",
+	"concrete_powder":       "_concrete_powder",
+}
+
+// Blocks that were renamed in 1.13.
+var legacyNames = map[string]string{
+	"flowing_water":         "water",
+	"flowing_lava":          "lava",
+	"fence":                 "oak_fence",
+	"golden_rail":           "powered_rail",
+	"grass":                 "grass_block",
+	"hardened_clay":         "terracotta",
+	"lit_pumpkin":           "jack_o_lantern",
+	"web":                   "cobweb",
+	"brick_block":           "bricks",
+	"quartz_ore":            "nether_quartz_ore",
+	"red_flower":            "poppy",
+	"yellow_flower":         "dandelion",
+	"snow_layer":            "snow",
+	"noteblock":             "note_block",
+	"melon_block":           "melon",
+	"nether_brick":          "nether_bricks",
+	"end_bricks":            "end_stone_bricks",
+	"mob_spawner":           "spawner",
+	"wooden_door":           "oak_door",
+	"trapdoor":              "oak_trapdoor",
+	"wooden_button":         "oak_button",
+	"fence_gate":            "oak_fence_gate",
+	"wooden_pressure_plate": "oak_pressure_plate",
+}
+
+// BlockState converts a block as written in a function, old or new form, to a namespaced
+// block name and its block state properties. The properties are nil if there are none.
+func BlockState(surface string) (string, map[string]string) {
+	surface = strings.TrimSpace(surface)
+
+	// New form, name[key=value,...]
+	var props map[string]string
+	if i := strings.Index(surface, "["); i >= 0 && strings.HasSuffix(surface, "]") {
+		props = make(map[string]string)
+		for _, kv := range strings.Split(surface[i+1:len(surface)-1], ",") {
+			if k, v, ok := strings.Cut(kv, "="); ok {
+				props[strings.TrimSpace(k)] = strings.TrimSpace(v)
+			}
+		}
+		surface = surface[:i]
+	}
+
+	// Old form, name data
+	data := 0
+	if fields := strings.Fields(surface); len(fields) > 1 {
+		surface = fields[0]
+		if d, err := strconv.Atoi(fields[1]); err == nil {
+			data = d
+		}
+	}
+
+	ns, name := "minecraft:", surface
+	if i := strings.Index(surface, ":"); i >= 0 {
+		ns, name = surface[:i+1], surface[i+1:]
+	}
+	if ns != "minecraft:" {
+		return surface, props
+	}
+
+	if names, ok := legacyDataBlocks[name]; ok {
+		// For logs only the low two bits are the wood type, the others are the direction.
+		d := data & 7
+		if name == "log" || name == "log2" {
+			d = data & 3
+		}
+		name = names[0]
+		if d < len(names) {
+			name = names[d]
+		}
+	} else if suffix, ok := legacyColorBlocks[name]; ok {
+		name = blockColors[data&15] + suffix
+	} else if newName, ok := legacyNames[name]; ok {
+		name = newName
+	}
+
+	return ns + name, props
+}
+
+// LegacyBlock returns a block in the 1.12 form, the name with a data value if it has one,
+// e.g. "minecraft:stone 4" for "minecraft:polished_diorite". Blocks that are already a 1.12
+// block are returned as they are. It reports false for blocks 1.12 does not have and for
+// block states a 1.12 function can not place, e.g. the direction of stairs, only the axis of
+// logs and the states that are the same as the plain block are converted.
+func LegacyBlock(block string) (string, bool) {
+	if !strings.Contains(block, "[") && KnownBlock(strings.Fields(block + " ")[0], 12) {
+		return block, true
+	}
+	name, props := BlockState(block)
+	if !strings.HasPrefix(name, "minecraft:") {
+		return "", false
+	}
+	name = strings.TrimPrefix(name, "minecraft:")
+
+	old, data := "", 0
+	for o, names := range legacyDataBlocks {
+		for d, n := range names {
+			if n == name {
+				old, data = o, d
+			}
+		}
+	}
+	for o, suffix := range legacyColorBlocks {
+		for d, color := range blockColors {
+			if color+suffix == name {
+				old, data = o, d
+			}
+		}
+	}
+	for o, n := range legacyNames {
+		if n == name {
+			old = o
+		}
+	}
+	if old == "" {
+		if !KnownBlock(name, 12) {
+			return "", false
+		}
+		old = name
+	}
+
+	for k, v := range props {
+		switch {
+		case k == "axis" && (old == "log" || old == "log2") && v == "x":
+			data |= 4
+		case k == "axis" && (old == "log" || old == "log2") && v == "z":
+			data |= 8
+		case k == "axis" && v == "y", k == "snowy" && v == "false",
+			k == "waterlogged" && v == "false":
+		default:
+			return "", false
+		}
+	}
+	if data == 0 {
+		return "minecraft:" + old, true
+	}
+	return "minecraft:" + old + " " + strconv.Itoa(data), true
+}
+
+// BlockString returns a block in the new form, name[key=value,...] with the keys sorted, so
+// the old and new forms of the same block can be compared, e.g. "minecraft:polished_diorite"
+// for "minecraft:stone 4".
+func BlockString(surface string) string {
+	return FormatBlockState(BlockState(surface))
+}
+
+// FormatBlockState returns a block name and its properties in the form name[key=value,...]
+// with the keys sorted, see BlockString.
+func FormatBlockState(name string, props map[string]string) string {
+	if len(props) == 0 {
+		return name
+	}
+	keys := SortedKeys(props)
+	for i, k := range keys {
+		keys[i] = k + "=" + props[k]
+	}
+	return name + "[" + strings.Join(keys, ",") + "]"
+}
+
+// SortedKeys returns the keys of block state properties, or any other map of strings, in
+// order
+func SortedKeys(props map[string]string) []string {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// BlockName returns the namespaced block name without any block state properties,
+// e.g. "minecraft:polished_diorite" for "minecraft:stone 4".
+func BlockName(surface string) string {
+	name, _ := BlockState(surface)
+	return name
+}
+
+// IsAir reports whether a block is one of the air blocks.
+func IsAir(surface string) bool {
+	switch BlockName(surface) {
+	case "minecraft:air", "minecraft:cave_air", "minecraft:void_air":
+		return true
+	}
+	return false
+}
+
+// IsSecondHalf reports whether a block is the upper half of a door or a tall plant, or the head
+// of a bed. These are placed together with their lower half or foot, from one item.
+func IsSecondHalf(surface string) bool {
+	_, props := BlockState(surface)
+	if props["half"] == "upper" || props["part"] == "head" {
+		return true
+	}
+	// In the old form the upper half and the head have a data value of 8 or more
+	fields := strings.Fields(surface)
+	if len(fields) < 2 || strings.Contains(surface, "[") {
+		return false
+	}
+	name := strings.TrimPrefix(fields[0], "minecraft:")
+	d, err := strconv.Atoi(fields[1])
+	return err == nil && d&8 != 0 &&
+		(strings.HasSuffix(name, "_door") || name == "bed" || name == "double_plant")
+}
@@ -3,6 +3,7 @@ package mcshapes
 import (
 	"fmt"
 	"io"
+	"strings"
 )
 
 // Box is a 3D rectangle of blocks (also in 1D, 2D)
@@ -56,6 +57,103 @@ func At(xyz XYZ) BoxOption {
 	}
 }
 
+// Corners returns the XYZ coordinates of the two opposite corners of the box
+func (b *Box) Corners() (XYZ, XYZ) {
+	return b.corner1, b.corner2
+}
+
+// Surface returns the block type of the box
+func (b *Box) Surface() string {
+	return b.surface
+}
+
+// Layers splits the box into boxes that are one block tall, one for each Y level,
+// going from the bottom to the top.
+func (b *Box) Layers() []*Box {
+	ylo, yhi := b.corner1.Y, b.corner2.Y
+	if ylo > yhi {
+		ylo, yhi = yhi, ylo
+	}
+	layers := make([]*Box, 0, yhi-ylo+1)
+	for y := ylo; y <= yhi; y++ {
+		layers = append(layers, NewBox(
+			WithSurface(b.surface),
+			WithCorner1(XYZ{X: b.corner1.X, Y: y, Z: b.corner1.Z}),
+			WithCorner2(XYZ{X: b.corner2.X, Y: y, Z: b.corner2.Z})))
+	}
+	return layers
+}
+
+// MaxFillBlocks is the most blocks one fill or clone command can change, the
+// commandModificationBlockLimit gamerule.
+const MaxFillBlocks = 32768
+
+// Split splits the box into boxes of the same block with at most limit blocks each, so each
+// is one fill or clone command. The box is cut into layers first, then the layers into rows.
+func (b *Box) Split(limit int) []*Box {
+	lo, hi := minMax(b.corner1, b.corner2)
+	dx, dy, dz := hi.X-lo.X+1, hi.Y-lo.Y+1, hi.Z-lo.Z+1
+	if dx*dy*dz <= limit {
+		return []*Box{b}
+	}
+
+	// Blocks along X and Z and layers along Y for each box
+	nx, nz, ny := dx, dz, limit/(dx*dz)
+	if ny == 0 {
+		ny = 1
+		nz = limit / dx
+		if nz == 0 {
+			nz = 1
+			nx = limit
+		}
+	}
+	if ny > dy {
+		ny = dy
+	}
+
+	var boxes []*Box
+	for y := lo.Y; y <= hi.Y; y += ny {
+		for z := lo.Z; z <= hi.Z; z += nz {
+			for x := lo.X; x <= hi.X; x += nx {
+				c2 := XYZ{X: x + nx - 1, Y: y + ny - 1, Z: z + nz - 1}
+				if c2.X > hi.X {
+					c2.X = hi.X
+				}
+				if c2.Y > hi.Y {
+					c2.Y = hi.Y
+				}
+				if c2.Z > hi.Z {
+					c2.Z = hi.Z
+				}
+				boxes = append(boxes, NewBox(
+					WithSurface(b.surface),
+					WithCorner1(XYZ{X: x, Y: y, Z: z}),
+					WithCorner2(c2)))
+			}
+		}
+	}
+	return boxes
+}
+
+// ParseBox creates a box from a fill command as written by WriteShape, e.g.
+//    fill ~0 ~0 ~-2 ~99 ~0 ~-2 minecraft:sandstone
+// Fill commands that do not simply replace the whole box, hollow, keep, ..., are not
+// a box, use ParseCommand for those. A destroy fill places the same blocks but drops the old
+// ones as items, the box has no mode to write it back with, so it is not a box either.
+func ParseBox(command string) (*Box, error) {
+	if !strings.HasPrefix(strings.TrimSpace(command), "fill ") {
+		return nil, fmt.Errorf("not a fill command: %q", command)
+	}
+	c, err := ParseCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	if c.Mode != "replace" || c.Filter != "" {
+		return nil, fmt.Errorf("fill command does not fill the whole box: %q", command)
+	}
+	return c.Box, nil
+}
+
 // Orient box to new direction
 
 // By convention, the user will construct the object while facing
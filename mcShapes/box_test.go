@@ -81,3 +81,56 @@ func TestWestBox(t *testing.T) {
 		t.Errorf("expected '%v', got '%v'", expected, buf.String())
 	}
 }
+
+// Test reading back a fill command
+func TestParseBox(t *testing.T) {
+	cmd := "fill ~1 ~2 ~-3 ~4 ~5 ~6 minecraft:stone 4\n"
+	b, err := ParseBox(cmd)
+	if err != nil {
+		t.Fatalf("ParseBox: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := b.WriteShape(&buf); err != nil {
+		t.Errorf("WriteShape: %v", err)
+	}
+
+	if buf.String() != cmd {
+		t.Errorf("expected '%v', got '%v'", cmd, buf.String())
+	}
+
+	if _, err := ParseBox("setblock ~1 ~2 ~3 minecraft:glass"); err == nil {
+		t.Errorf("expected an error for a setblock command")
+	}
+	if _, err := ParseBox("fill ~1 ~2 ~3 ~4 ~5 ~6 minecraft:stone 4 hollow"); err == nil {
+		t.Errorf("expected an error for a hollow fill command")
+	}
+	if _, err := ParseBox("fill ~1 ~2 ~3 ~4 ~5 ~6 minecraft:glass outline"); err == nil {
+		t.Errorf("expected an error for an outline fill command")
+	}
+	if _, err := ParseBox("fill ~1 ~2 ~3 ~4 ~5 ~6 minecraft:glass destroy"); err == nil {
+		t.Errorf("expected an error for a destroy fill command, it would be written as replace")
+	}
+}
+
+// Test splitting a box into one block tall layers
+func TestBoxLayers(t *testing.T) {
+	expected := "fill ~1 ~2 ~3 ~4 ~2 ~6 testsurface\n" +
+		"fill ~1 ~3 ~3 ~4 ~3 ~6 testsurface\n" +
+		"fill ~1 ~4 ~3 ~4 ~4 ~6 testsurface\n"
+	b := NewBox(
+		WithSurface("testsurface"),
+		WithCorner1(XYZ{X: 1, Y: 4, Z: 3}),
+		WithCorner2(XYZ{X: 4, Y: 2, Z: 6}))
+
+	var buf bytes.Buffer
+	for _, l := range b.Layers() {
+		if err := l.WriteShape(&buf); err != nil {
+			t.Errorf("WriteShape: %v", err)
+		}
+	}
+
+	if buf.String() != expected {
+		t.Errorf("expected '%v', got '%v'", expected, buf.String())
+	}
+}
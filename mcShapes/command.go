@@ -0,0 +1,234 @@
+package mcshapes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Command is one fill or setblock command read back from a function file.
+// A setblock is a fill of a single voxel box.
+type Command struct {
+	// Box is the blocks filled and the block placed in them
+	Box *Box
+
+	// Mode is how the blocks already there are treated,
+	//    replace   everything is replaced, or only Filter blocks if Filter is set (default)
+	//    destroy   same as replace, the old blocks are dropped as items
+	//    keep      only air is replaced
+	//    hollow    the outside of the box is filled and the inside replaced with air
+	//    outline   the outside of the box is filled and the inside is left alone
+	Mode string
+
+	// Filter is the block replaced by "replace <filter>", "" for all blocks
+	Filter string
+}
+
+// ParseCommand reads a fill or setblock command with relative coordinates. Both the
+// Minecraft 1.12 and the 1.13 and later forms are understood, e.g.
+//    fill ~0 ~0 ~-2 ~99 ~0 ~-2 minecraft:stone 4
+//    fill ~ ~ ~-2 ~9 ~5 ~-2 minecraft:oak_planks hollow
+//    fill ~ ~ ~-2 ~9 ~5 ~-2 minecraft:glass replace minecraft:stone
+//    setblock ~1 ~0 ~-2 minecraft:oak_stairs[facing=east,half=top]
+// Block entity data, {...}, is not kept.
+func ParseCommand(command string) (*Command, error) {
+	return parseCommand(command, relativeXYZ)
+}
+
+// ParseCommandFunc reads a fill or setblock command like ParseCommand, with each set of 3
+// coordinates turned into a position by xyz. This reads commands with absolute or ^ local
+// coordinates.
+func ParseCommandFunc(command string, xyz func([]string) (XYZ, error)) (*Command, error) {
+	return parseCommand(command, xyz)
+}
+
+// parseCommand reads a fill or setblock command. Each set of 3 coordinates is turned into a
+// position by xyz.
+func parseCommand(command string, xyz func([]string) (XYZ, error)) (*Command, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+
+	ncoords := 0
+	switch fields[0] {
+	case "fill":
+		ncoords = 6
+	case "setblock":
+		ncoords = 3
+	default:
+		return nil, fmt.Errorf("not a fill or setblock command: %q", command)
+	}
+	if len(fields) < ncoords+2 {
+		return nil, fmt.Errorf("%v command is too short: %q", fields[0], command)
+	}
+
+	var c [2]XYZ
+	for i := 0; i < ncoords/3; i++ {
+		v, err := xyz(fields[3*i+1 : 3*i+4])
+		if err != nil {
+			return nil, fmt.Errorf("%v: %q", err, command)
+		}
+		c[i] = v
+	}
+	if ncoords == 3 {
+		c[1] = c[0]
+	}
+
+	// Everything after the coordinates is the block, an optional data value and the mode
+	rest := strings.Join(fields[ncoords+1:], " ")
+	surface, rest := parseBlock(rest)
+	if surface == "" {
+		return nil, fmt.Errorf("%v command has no block: %q", fields[0], command)
+	}
+
+	cmd := &Command{
+		Box: NewBox(
+			WithSurface(surface),
+			WithCorner1(c[0]),
+			WithCorner2(c[1])),
+		Mode: "replace",
+	}
+
+	if mode := strings.Fields(rest); len(mode) > 0 {
+		cmd.Mode = mode[0]
+		switch cmd.Mode {
+		case "replace", "destroy", "keep":
+		case "hollow", "outline":
+			if ncoords == 3 {
+				return nil, fmt.Errorf("setblock mode %q: %q", cmd.Mode, command)
+			}
+		default:
+			return nil, fmt.Errorf("unknown %v mode %q: %q", fields[0], cmd.Mode, command)
+		}
+		if cmd.Mode == "replace" && ncoords == 6 {
+			cmd.Filter, _ = parseBlock(strings.TrimPrefix(strings.TrimSpace(rest), "replace"))
+		}
+	}
+
+	return cmd, nil
+}
+
+// ModernCommand returns a Minecraft 1.12 fill or setblock command with its blocks in the
+// 1.13 and later form, see BlockString, e.g.
+//    fill ~0 ~0 ~-2 ~9 ~0 ~-2 minecraft:stone 4 replace minecraft:log 1
+//    fill ~0 ~0 ~-2 ~9 ~0 ~-2 minecraft:polished_diorite replace minecraft:spruce_log
+// Other commands, and commands that already have block states or block entity data, are
+// returned as they are.
+func ModernCommand(command string) string {
+	fields := strings.Fields(command)
+	ncoords := 0
+	switch {
+	case len(fields) == 0 || strings.ContainsAny(command, "[{"):
+		return command
+	case fields[0] == "fill":
+		ncoords = 6
+	case fields[0] == "setblock":
+		ncoords = 3
+	default:
+		return command
+	}
+	if len(fields) < ncoords+2 {
+		return command
+	}
+
+	// block [data] [mode [filter [data]]]
+	modern := func(blocks []string) ([]string, []string) {
+		n := 1
+		if len(blocks) > 1 {
+			if _, err := strconv.Atoi(blocks[1]); err == nil {
+				n = 2
+			}
+		}
+		return []string{BlockString(strings.Join(blocks[:n], " "))}, blocks[n:]
+	}
+	block, rest := modern(fields[ncoords+1:])
+	out := append(fields[:ncoords+1:ncoords+1], block...)
+	if len(rest) > 1 && rest[0] == "replace" {
+		var filter []string
+		filter, rest = modern(rest[1:])
+		out = append(append(out, "replace"), filter...)
+	}
+	out = append(out, rest...)
+
+	s := strings.Join(out, " ")
+	if strings.HasSuffix(command, "\n") {
+		s += "\n"
+	}
+	return s
+}
+
+// relativeXYZ reads 3 relative coordinates
+func relativeXYZ(fields []string) (XYZ, error) {
+	var c [3]int
+	for i := range c {
+		v, err := parseRelative(fields[i])
+		if err != nil {
+			return XYZ{}, err
+		}
+		c[i] = v
+	}
+	return XYZ{X: c[0], Y: c[1], Z: c[2]}, nil
+}
+
+// parseRelative reads one relative coordinate, "~" or "~<n>"
+func parseRelative(s string) (int, error) {
+	if !strings.HasPrefix(s, "~") {
+		return 0, fmt.Errorf("coordinate %q is not relative", s)
+	}
+	if s == "~" {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(s[1:])
+	if err != nil {
+		return 0, fmt.Errorf("coordinate %q: %v", s, err)
+	}
+	return v, nil
+}
+
+// parseBlock reads a block from the start of s and returns the block and what is after it.
+// The block is name[states], which may have spaces inside the brackets, or the old form
+// with a data value, name <data>. Block entity data {...} after the name is skipped.
+func parseBlock(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	depth := 0
+	end := len(s)
+	nbt := -1
+	for i, r := range s {
+		switch {
+		case r == '[' || r == '{':
+			if depth == 0 && r == '{' {
+				nbt = i
+			}
+			depth++
+		case r == ']' || r == '}':
+			depth--
+		case depth == 0 && unicode.IsSpace(r):
+			end = i
+		}
+		if end < len(s) {
+			break
+		}
+	}
+
+	name := s[:end]
+	if nbt >= 0 && nbt < end {
+		name = s[:nbt]
+	}
+	states := ""
+	if i := strings.Index(name, "["); i >= 0 {
+		states = strings.Join(strings.Fields(name[i:]), "")
+		name = name[:i]
+	}
+	rest := strings.TrimSpace(s[end:])
+
+	// Old form data value
+	if fields := strings.Fields(rest); len(fields) > 0 && states == "" {
+		if _, err := strconv.Atoi(fields[0]); err == nil {
+			name += " " + fields[0]
+			rest = strings.TrimSpace(strings.TrimPrefix(rest, fields[0]))
+		}
+	}
+	return name + states, rest
+}
@@ -0,0 +1,266 @@
+package mcshapes
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Model is the set of blocks placed by a list of commands, i.e. what is in the game
+// after running a function. Positions are relative to the player, the same as the
+// "~" coordinates of the commands.
+type Model struct {
+	blocks map[XYZ]string
+}
+
+// NewModel creates a new, empty, model
+func NewModel() *Model {
+	return &Model{blocks: make(map[XYZ]string)}
+}
+
+// SetBlock places one block, replacing whatever was there
+func (m *Model) SetBlock(xyz XYZ, block string) {
+	m.blocks[xyz] = block
+}
+
+// Block returns the block at a position, "" if nothing was placed there
+func (m *Model) Block(xyz XYZ) string {
+	return m.blocks[xyz]
+}
+
+// Fill places the blocks of a box, the same as the fill command. Like the game it does not
+// fill more than MaxFillBlocks blocks, a box that is too big is an error and nothing is placed.
+func (m *Model) Fill(b *Box) error {
+	c1, c2 := b.Corners()
+	if _, err := fillVolume(c1, c2); err != nil {
+		return err
+	}
+	lo, hi := minMax(c1, c2)
+	for y := lo.Y; y <= hi.Y; y++ {
+		for z := lo.Z; z <= hi.Z; z++ {
+			for x := lo.X; x <= hi.X; x++ {
+				m.blocks[XYZ{X: x, Y: y, Z: z}] = b.surface
+			}
+		}
+	}
+	return nil
+}
+
+// fillVolume returns the number of blocks in the box between two corners, or an error if it
+// is more than MaxFillBlocks, the most one fill or clone command can change.
+func fillVolume(c1 XYZ, c2 XYZ) (int, error) {
+	lo, hi := minMax(c1, c2)
+	volume := 1
+	for _, d := range []int{hi.X - lo.X + 1, hi.Y - lo.Y + 1, hi.Z - lo.Z + 1} {
+		// Checking each edge first keeps the product from overflowing
+		if d <= 0 || d > MaxFillBlocks {
+			return 0, fmt.Errorf("box %v %v has more than %d blocks", lo, hi, MaxFillBlocks)
+		}
+		volume *= d
+	}
+	if volume > MaxFillBlocks {
+		return 0, fmt.Errorf("box %v %v has %d blocks, more than %d", lo, hi, volume,
+			MaxFillBlocks)
+	}
+	return volume, nil
+}
+
+// Len is the number of blocks placed, including air
+func (m *Model) Len() int {
+	return len(m.blocks)
+}
+
+// Bounds returns the lowest and highest corners of the box around all the blocks.
+// For an empty model both are 0,0,0.
+func (m *Model) Bounds() (XYZ, XYZ) {
+	first := true
+	var lo, hi XYZ
+	for xyz := range m.blocks {
+		if first {
+			lo, hi = xyz, xyz
+			first = false
+			continue
+		}
+		lo, _ = minMax(lo, xyz)
+		_, hi = minMax(hi, xyz)
+	}
+	return lo, hi
+}
+
+// Positions returns the positions of all the blocks, sorted by Y then Z then X. This is
+// the order most block formats use.
+func (m *Model) Positions() []XYZ {
+	p := make([]XYZ, 0, len(m.blocks))
+	for xyz := range m.blocks {
+		p = append(p, xyz)
+	}
+	sort.Slice(p, func(i, j int) bool {
+		if p[i].Y != p[j].Y {
+			return p[i].Y < p[j].Y
+		}
+		if p[i].Z != p[j].Z {
+			return p[i].Z < p[j].Z
+		}
+		return p[i].X < p[j].X
+	})
+	return p
+}
+
+// Counts returns the number of each block placed, air is not counted. A position filled
+// more than once is only counted for the last block placed there. The old and new forms of
+// a block are the same block, the key is the new form without its block states, e.g.
+// "minecraft:oak_stairs". Doors, beds and tall plants are counted once, by their lower half
+// or foot, see IsSecondHalf.
+func (m *Model) Counts() map[string]int {
+	counts := make(map[string]int)
+	for _, b := range m.blocks {
+		if !IsAir(b) && !IsSecondHalf(b) {
+			counts[BlockName(b)]++
+		}
+	}
+	return counts
+}
+
+// HasBlocks reports whether anything other than air was placed
+func (m *Model) HasBlocks() bool {
+	for _, b := range m.blocks {
+		if !IsAir(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// Run places the blocks of one command. Fill and setblock commands, old or new form, are
+// run, see ParseCommand. Commands that are run by "execute ... run" are run at the player,
+// the functions written by mcFunctionDev only use execute to find the player position again.
+// Other commands, comments and empty lines do not place blocks and are skipped.
+func (m *Model) Run(command string) error {
+	command = strings.TrimSpace(command)
+	if strings.HasPrefix(command, "execute ") {
+		if i := strings.Index(command, " run "); i >= 0 {
+			command = strings.TrimSpace(command[i+len(" run "):])
+		}
+	}
+	fields := strings.Fields(command)
+	if len(fields) == 0 || (fields[0] != "fill" && fields[0] != "setblock") {
+		return nil
+	}
+
+	c, err := ParseCommand(command)
+	if err != nil {
+		return err
+	}
+	if err := m.Apply(c); err != nil {
+		return fmt.Errorf("%v: %q", err, command)
+	}
+	return nil
+}
+
+// Apply places the blocks of a fill or setblock command. Positions where nothing was placed
+// are taken to be air, the model does not know the terrain. A fill of more than MaxFillBlocks
+// blocks is an error, see Fill.
+func (m *Model) Apply(c *Command) error {
+	if (c.Mode == "replace" && c.Filter == "") || c.Mode == "destroy" {
+		return m.Fill(c.Box)
+	}
+
+	c1, c2 := c.Box.Corners()
+	if _, err := fillVolume(c1, c2); err != nil {
+		return err
+	}
+	lo, hi := minMax(c1, c2)
+	filter := BlockName(c.Filter)
+	for y := lo.Y; y <= hi.Y; y++ {
+		for z := lo.Z; z <= hi.Z; z++ {
+			for x := lo.X; x <= hi.X; x++ {
+				xyz := XYZ{X: x, Y: y, Z: z}
+				old, ok := m.blocks[xyz]
+				if !ok {
+					old = "minecraft:air"
+				}
+				outside := x == lo.X || x == hi.X || y == lo.Y || y == hi.Y ||
+					z == lo.Z || z == hi.Z
+				switch {
+				case c.Mode == "replace" && BlockName(old) == filter:
+					m.blocks[xyz] = c.Box.surface
+				case c.Mode == "keep" && IsAir(old):
+					m.blocks[xyz] = c.Box.surface
+				case c.Mode == "hollow" && !outside:
+					m.blocks[xyz] = "minecraft:air"
+				case (c.Mode == "hollow" || c.Mode == "outline") && outside:
+					m.blocks[xyz] = c.Box.surface
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Change is a difference between two models at one position. Old is "" if the first model
+// placed nothing there, New is "" if the second model placed nothing there.
+type Change struct {
+	XYZ XYZ
+	Old string
+	New string
+}
+
+// Diff returns the positions where model b places a different block than model a, sorted
+// the same as Positions. The old and new forms of a block are the same block.
+func Diff(a *Model, b *Model) []Change {
+	// Every position used by either model
+	both := NewModel()
+	for xyz := range a.blocks {
+		both.blocks[xyz] = ""
+	}
+	for xyz := range b.blocks {
+		both.blocks[xyz] = ""
+	}
+
+	var changes []Change
+	for _, xyz := range both.Positions() {
+		was, ok1 := a.blocks[xyz]
+		is, ok2 := b.blocks[xyz]
+		if ok1 && ok2 && BlockString(was) == BlockString(is) {
+			continue
+		}
+		changes = append(changes, Change{XYZ: xyz, Old: was, New: is})
+	}
+	return changes
+}
+
+// ReadModel creates a model by running the commands of a function file, see Run.
+// The functions written by WriteShapes are the same input mcrender uses to create STL files.
+func ReadModel(r io.Reader) (*Model, error) {
+	m := NewModel()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	n := 0
+	for scanner.Scan() {
+		n++
+		if err := m.Run(scanner.Text()); err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// minMax returns the lowest and highest corners of the box with corners a and b
+func minMax(a XYZ, b XYZ) (XYZ, XYZ) {
+	lo, hi := a, b
+	if lo.X > hi.X {
+		lo.X, hi.X = hi.X, lo.X
+	}
+	if lo.Y > hi.Y {
+		lo.Y, hi.Y = hi.Y, lo.Y
+	}
+	if lo.Z > hi.Z {
+		lo.Z, hi.Z = hi.Z, lo.Z
+	}
+	return lo, hi
+}
@@ -0,0 +1,181 @@
+package mcshapes
+
+import (
+	"strings"
+	"testing"
+)
+
+// Test running fill commands into a model, later fills replace earlier ones
+func TestReadModel(t *testing.T) {
+	cmds := "fill ~0 ~0 ~-2 ~2 ~1 ~-2 minecraft:sandstone\n" +
+		"fill ~1 ~1 ~-2 ~1 ~1 ~-2 minecraft:air\n"
+	m, err := ReadModel(strings.NewReader(cmds))
+	if err != nil {
+		t.Fatalf("ReadModel: %v", err)
+	}
+
+	if m.Len() != 6 {
+		t.Errorf("expected 6 blocks, got %d", m.Len())
+	}
+	if b := m.Block(XYZ{X: 1, Y: 1, Z: -2}); b != "minecraft:air" {
+		t.Errorf("expected air at 1,1,-2, got '%v'", b)
+	}
+	lo, hi := m.Bounds()
+	if lo != (XYZ{X: 0, Y: 0, Z: -2}) || hi != (XYZ{X: 2, Y: 1, Z: -2}) {
+		t.Errorf("expected bounds 0,0,-2 2,1,-2, got %v %v", lo, hi)
+	}
+	if !m.HasBlocks() {
+		t.Errorf("expected the model to have blocks")
+	}
+}
+
+// Test converting old and new block names
+func TestBlockState(t *testing.T) {
+	tests := []struct {
+		surface string
+		name    string
+	}{
+		{"minecraft:stone 4", "minecraft:polished_diorite"},
+		{"minecraft:monster_egg 2", "minecraft:infested_stone_bricks"},
+		{"minecraft:log 1", "minecraft:spruce_log"},
+		{"minecraft:stained_glass 5", "minecraft:lime_stained_glass"},
+		{"minecraft:flowing_water", "minecraft:water"},
+		{"sea_lantern", "minecraft:sea_lantern"},
+		{"minecraft:oak_stairs[facing=east]", "minecraft:oak_stairs"},
+	}
+	for _, tc := range tests {
+		if name := BlockName(tc.surface); name != tc.name {
+			t.Errorf("%v: expected '%v', got '%v'", tc.surface, tc.name, name)
+		}
+	}
+
+	_, props := BlockState("minecraft:oak_stairs[facing=east,half=top]")
+	if props["facing"] != "east" || props["half"] != "top" {
+		t.Errorf("expected facing=east,half=top, got %v", props)
+	}
+}
+
+// Test writing 1.12 commands with the blocks of 1.13 and later
+func TestModernCommand(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected string
+	}{
+		{"fill ~0 ~0 ~-2 ~9 ~0 ~-2 minecraft:stone 4\n",
+			"fill ~0 ~0 ~-2 ~9 ~0 ~-2 minecraft:polished_diorite\n"},
+		{"fill ~ ~ ~ ~1 ~1 ~1 minecraft:fence hollow",
+			"fill ~ ~ ~ ~1 ~1 ~1 minecraft:oak_fence hollow"},
+		{"fill ~ ~ ~ ~1 ~1 ~1 minecraft:air 0 replace minecraft:log 1",
+			"fill ~ ~ ~ ~1 ~1 ~1 minecraft:air replace minecraft:spruce_log"},
+		{"setblock ~1 ~0 ~-2 minecraft:stained_glass 5 keep\n",
+			"setblock ~1 ~0 ~-2 minecraft:lime_stained_glass keep\n"},
+		{"setblock ~1 ~0 ~-2 minecraft:oak_stairs[facing=east]\n",
+			"setblock ~1 ~0 ~-2 minecraft:oak_stairs[facing=east]\n"},
+		{"say fill ~ ~ ~ stone 4\n", "say fill ~ ~ ~ stone 4\n"},
+	}
+	for _, tc := range tests {
+		if s := ModernCommand(tc.command); s != tc.expected {
+			t.Errorf("%q: expected %q, got %q", tc.command, tc.expected, s)
+		}
+	}
+}
+
+// Test running setblock and the fill modes, old and new block names
+func TestModelRun(t *testing.T) {
+	cmds := "# a comment\n" +
+		"fill ~ ~ ~-2 ~2 ~2 ~-4 minecraft:stone 4 hollow\n" +
+		"setblock ~1 ~1 ~-3 minecraft:glass\n" +
+		"fill ~0 ~0 ~-2 ~2 ~0 ~-4 minecraft:dirt replace minecraft:polished_diorite\n" +
+		"fill ~0 ~0 ~-2 ~0 ~3 ~-2 minecraft:sand keep\n" +
+		"execute at @e[type=minecraft:marker,tag=mcfd_x,limit=1] run " +
+		"setblock ~2 ~3 ~-2 minecraft:oak_stairs[facing=east, half=top]{}\n" +
+		"kill @e[type=minecraft:marker]\n"
+	m, err := ReadModel(strings.NewReader(cmds))
+	if err != nil {
+		t.Fatalf("ReadModel: %v", err)
+	}
+
+	tests := []struct {
+		xyz   XYZ
+		block string
+	}{
+		{XYZ{X: 0, Y: 1, Z: -2}, "minecraft:stone 4"},
+		{XYZ{X: 1, Y: 1, Z: -3}, "minecraft:glass"},
+		{XYZ{X: 1, Y: 0, Z: -3}, "minecraft:dirt"},
+		{XYZ{X: 0, Y: 3, Z: -2}, "minecraft:sand"},
+		{XYZ{X: 0, Y: 2, Z: -2}, "minecraft:stone 4"},
+		{XYZ{X: 2, Y: 3, Z: -2}, "minecraft:oak_stairs[facing=east,half=top]"},
+	}
+	for _, tt := range tests {
+		if b := m.Block(tt.xyz); b != tt.block {
+			t.Errorf("expected '%v' at %v, got '%v'", tt.block, tt.xyz, b)
+		}
+	}
+
+	if _, err := ReadModel(strings.NewReader("fill ~ ~ ~ ~1 ~1 ~1 stone sideways\n")); err == nil {
+		t.Errorf("expected an error for an unknown fill mode")
+	}
+
+	// Fills the game would not run are errors, not millions of blocks
+	for _, cmd := range []string{"fill ~0 ~0 ~0 ~31 ~31 ~32 minecraft:stone\n",
+		"fill ~0 ~0 ~0 ~99999999 ~0 ~0 minecraft:air hollow\n",
+		"fill ~-9223372036854775808 ~0 ~0 ~9223372036854775807 ~0 ~0 minecraft:stone\n"} {
+		if _, err := ReadModel(strings.NewReader(cmd)); err == nil {
+			t.Errorf("expected an error for %q", cmd)
+		}
+	}
+	if _, err := ReadModel(strings.NewReader("fill ~0 ~0 ~0 ~31 ~31 ~31 minecraft:stone\n")); err != nil {
+		t.Errorf("expected %d blocks to fill, got %v", MaxFillBlocks, err)
+	}
+}
+
+// Test the differences between two models
+func TestDiff(t *testing.T) {
+	a := NewModel()
+	a.SetBlock(XYZ{X: 0, Y: 0, Z: -2}, "minecraft:stone 4")
+	a.SetBlock(XYZ{X: 1, Y: 0, Z: -2}, "minecraft:dirt")
+	a.SetBlock(XYZ{X: 2, Y: 0, Z: -2}, "minecraft:glass")
+	b := NewModel()
+	b.SetBlock(XYZ{X: 0, Y: 0, Z: -2}, "minecraft:polished_diorite")
+	b.SetBlock(XYZ{X: 1, Y: 0, Z: -2}, "minecraft:sand")
+	b.SetBlock(XYZ{X: 3, Y: 0, Z: -2}, "minecraft:glass")
+
+	expected := []Change{
+		{XYZ{X: 1, Y: 0, Z: -2}, "minecraft:dirt", "minecraft:sand"},
+		{XYZ{X: 2, Y: 0, Z: -2}, "minecraft:glass", ""},
+		{XYZ{X: 3, Y: 0, Z: -2}, "", "minecraft:glass"},
+	}
+	changes := Diff(a, b)
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %v", len(expected), changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], changes[i])
+		}
+	}
+}
+
+// Test counting blocks, overlapping fills are only counted once
+func TestCounts(t *testing.T) {
+	cmds := "fill ~0 ~0 ~-2 ~4 ~0 ~-2 minecraft:stone 4\n" +
+		"fill ~2 ~0 ~-2 ~6 ~0 ~-2 minecraft:polished_diorite\n" +
+		"setblock ~3 ~0 ~-2 minecraft:oak_stairs[facing=east]\n" +
+		"setblock ~0 ~1 ~-2 minecraft:air\n" +
+		"setblock ~0 ~1 ~-4 minecraft:oak_door[half=lower]\n" +
+		"setblock ~0 ~2 ~-4 minecraft:oak_door[half=upper]\n" +
+		"setblock ~1 ~1 ~-4 minecraft:wooden_door 1\n" +
+		"setblock ~1 ~2 ~-4 minecraft:wooden_door 8\n" +
+		"setblock ~2 ~1 ~-4 minecraft:red_bed[part=foot]\n" +
+		"setblock ~2 ~1 ~-5 minecraft:red_bed[part=head]\n"
+	m, err := ReadModel(strings.NewReader(cmds))
+	if err != nil {
+		t.Fatalf("ReadModel: %v", err)
+	}
+	counts := m.Counts()
+	if len(counts) != 4 || counts["minecraft:polished_diorite"] != 6 ||
+		counts["minecraft:oak_stairs"] != 1 || counts["minecraft:oak_door"] != 2 ||
+		counts["minecraft:red_bed"] != 1 {
+		t.Errorf("expected 6 polished diorite, 1 stairs, 2 doors and 1 bed, got %v", counts)
+	}
+}
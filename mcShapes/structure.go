@@ -0,0 +1,289 @@
+package mcshapes
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Structure is a build made somewhere else, read from a structure or schematic file,
+// that is written out as fill commands the same as the generated shapes.
+// Unlike a Box or a Sphere the blocks of a Structure can be anything, so the fill
+// commands are found by merging neighboring blocks of the same type.
+type Structure struct {
+	blocks  map[XYZ]string
+	skipAir bool
+}
+
+// NewStructure creates a new structure
+func NewStructure(opts ...StructureOption) *Structure {
+	s := &Structure{
+		blocks: make(map[XYZ]string),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// StructureOption sets various options for NewStructure
+type StructureOption func(*Structure)
+
+// WithModel sets the blocks of the structure. The positions are relative to the player,
+// the same as for the other shapes.
+func WithModel(m *Model) StructureOption {
+	return func(s *Structure) {
+		for xyz, b := range m.blocks {
+			s.blocks[xyz] = b
+		}
+	}
+}
+
+// WithSkipAir leaves out the air blocks, so the structure is built over whatever is
+// already there instead of clearing its whole box first.
+func WithSkipAir(skip bool) StructureOption {
+	return func(s *Structure) { s.skipAir = skip }
+}
+
+// WithRemap replaces one type of block with another, e.g. oak_planks with spruce_planks.
+// Block states are kept, so stairs still face the same way.
+func WithRemap(from string, to string) StructureOption {
+	return func(s *Structure) { s.Remap(from, to) }
+}
+
+// Len is the number of blocks in the structure, including air
+func (s *Structure) Len() int {
+	return len(s.blocks)
+}
+
+// Bounds returns the lowest and highest corners of the box around the structure
+func (s *Structure) Bounds() (XYZ, XYZ) {
+	m := Model{blocks: s.blocks}
+	return m.Bounds()
+}
+
+// Remap replaces every block named from with the block to. The names are compared
+// without the block states, and either the old or new form of a name can be used.
+func (s *Structure) Remap(from string, to string) {
+	fromName := BlockName(from)
+	for xyz, b := range s.blocks {
+		if BlockName(b) != fromName {
+			continue
+		}
+		nb := to
+		if i := strings.Index(b, "["); i >= 0 && !strings.Contains(to, "[") {
+			nb += b[i:]
+		}
+		s.blocks[xyz] = nb
+	}
+}
+
+// Air returns a structure that removes this one, air everywhere it places a block
+func (s *Structure) Air() *Structure {
+	a := NewStructure()
+	for xyz, b := range s.blocks {
+		if !s.skipAir || !IsAir(b) {
+			a.blocks[xyz] = "minecraft:air"
+		}
+	}
+	return a
+}
+
+// Orient structure to new direction
+// The positions are rotated and reflected the same as Box.Orient does it, so a structure
+// built facing north can be placed facing any direction. The block states that point
+// somewhere are turned the same way, see OrientBlock, so stairs, doors, logs, rails, ...
+// face the way they were built relative to the rest of the structure.
+func (s *Structure) Orient(direction string) {
+	blocks := make(map[XYZ]string, len(s.blocks))
+	for xyz, b := range s.blocks {
+		v := NewBox(At(xyz))
+		v.Orient(direction)
+		blocks[v.corner1] = OrientBlock(b, direction)
+	}
+	s.blocks = blocks
+}
+
+// The horizontal directions of block states and their unit vectors
+var stateDirections = map[string]XYZ{
+	"north": {Z: -1}, "south": {Z: 1}, "east": {X: 1}, "west": {X: -1},
+}
+
+// The rotation block state of signs, banners and heads in 16ths of a turn clockwise from
+// south, for the horizontal directions
+var stateRotations = map[string]int{"south": 0, "west": 4, "north": 8, "east": 12}
+
+// orientDirection returns a horizontal direction turned the same way Box.Orient turns
+// positions. up, down and anything else are returned as they are.
+func orientDirection(d string, direction string) string {
+	v, ok := stateDirections[d]
+	if !ok {
+		return d
+	}
+	b := NewBox(At(v))
+	b.Orient(direction)
+	for name, u := range stateDirections {
+		if u == b.corner1 {
+			return name
+		}
+	}
+	return d
+}
+
+// OrientBlock returns a block in the new form, name[key=value,...], with its block states
+// turned to match a structure oriented in direction, see Structure.Orient,
+//    facing          north, east, south and west are turned
+//    axis            x and z are swapped by a quarter turn
+//    rotation        the 16 directions of signs, banners, ... are turned
+//    shape           the directions of rails are turned, a reflection swaps the left and
+//                    right of stairs
+//    hinge, type     a reflection swaps left and right, for doors and chests
+//    north, ...      the sides of fences, panes, walls, vines, ... are turned
+// Orientations only turn and mirror horizontally, so half (top and bottom, upper and lower)
+// and the up and down directions stay the same. Blocks in the old form with a data value
+// and blocks without block states are returned as they are.
+func OrientBlock(block string, direction string) string {
+	i := strings.Index(block, "[")
+	if i < 0 || direction == "north" {
+		return block
+	}
+	_, props := BlockState(block)
+	reflect := strings.HasSuffix(direction, "_refl")
+	quarter := orientDirection("east", direction) == "north" ||
+		orientDirection("east", direction) == "south"
+	swap := map[string]string{"left": "right", "right": "left"}
+
+	turned := make(map[string]string, len(props))
+	for k, v := range props {
+		switch k {
+		case "facing":
+			v = orientDirection(v, direction)
+		case "axis":
+			if quarter && v == "x" {
+				v = "z"
+			} else if quarter && v == "z" {
+				v = "x"
+			}
+		case "rotation":
+			if r, err := strconv.Atoi(v); err == nil {
+				south := stateRotations[orientDirection("south", direction)]
+				if reflect {
+					r = -r
+				}
+				v = strconv.Itoa(((south+r)%16 + 16) % 16)
+			}
+		case "shape":
+			v = orientShape(v, direction, reflect)
+		case "hinge", "type":
+			if reflect && swap[v] != "" {
+				v = swap[v]
+			}
+		case "north", "south", "east", "west":
+			k = orientDirection(k, direction)
+		}
+		turned[k] = v
+	}
+	return FormatBlockState(block[:i], turned)
+}
+
+// orientShape returns the shape block state of rails and stairs turned to direction.
+// Rails have directions in their shape, e.g. ascending_east and north_west, stairs have
+// inner_left, outer_right, ...
+func orientShape(shape string, direction string, reflect bool) string {
+	parts := strings.Split(shape, "_")
+	if len(parts) == 2 && (parts[1] == "left" || parts[1] == "right") {
+		if reflect {
+			parts[1] = map[string]string{"left": "right", "right": "left"}[parts[1]]
+		}
+		return strings.Join(parts, "_")
+	}
+	for i, p := range parts {
+		parts[i] = orientDirection(p, direction)
+	}
+	// Rails name north or south first, north_south and south_east but not east_south
+	if len(parts) == 2 && (parts[0] == "east" || parts[0] == "west") &&
+		(parts[1] == "north" || parts[1] == "south") {
+		parts[0], parts[1] = parts[1], parts[0]
+	}
+	return strings.Join(parts, "_")
+}
+
+// Boxes covers the structure with boxes of the same block, going from the bottom up. Each box
+// is first as long as possible along X, then as deep as possible along Z, then as tall as
+// possible along Y. The air boxes come first so they do not remove the blocks placed after.
+// Boxes of more than MaxFillBlocks blocks are split, see Box.Split.
+func (s *Structure) Boxes() []*Box {
+	var air, solid []*Box
+	done := make(map[XYZ]bool, len(s.blocks))
+	for _, p := range s.positions() {
+		b := s.blocks[p]
+		if done[p] || (s.skipAir && IsAir(b)) {
+			continue
+		}
+		same := func(q XYZ) bool {
+			qb, ok := s.blocks[q]
+			return ok && !done[q] && qb == b
+		}
+
+		x2 := p.X
+		for same(XYZ{X: x2 + 1, Y: p.Y, Z: p.Z}) {
+			x2++
+		}
+		z2 := p.Z
+	depth:
+		for {
+			for x := p.X; x <= x2; x++ {
+				if !same(XYZ{X: x, Y: p.Y, Z: z2 + 1}) {
+					break depth
+				}
+			}
+			z2++
+		}
+		y2 := p.Y
+	height:
+		for {
+			for z := p.Z; z <= z2; z++ {
+				for x := p.X; x <= x2; x++ {
+					if !same(XYZ{X: x, Y: y2 + 1, Z: z}) {
+						break height
+					}
+				}
+			}
+			y2++
+		}
+		for y := p.Y; y <= y2; y++ {
+			for z := p.Z; z <= z2; z++ {
+				for x := p.X; x <= x2; x++ {
+					done[XYZ{X: x, Y: y, Z: z}] = true
+				}
+			}
+		}
+
+		box := NewBox(WithSurface(b), WithCorner1(p), WithCorner2(XYZ{X: x2, Y: y2, Z: z2}))
+		if IsAir(b) {
+			air = append(air, box.Split(MaxFillBlocks)...)
+		} else {
+			solid = append(solid, box.Split(MaxFillBlocks)...)
+		}
+	}
+	return append(air, solid...)
+}
+
+// WriteShape satisfies ObjectWriter interface
+// Every box of Boxes is one fill command.
+func (s *Structure) WriteShape(w io.Writer) error {
+	for _, b := range s.Boxes() {
+		if err := b.WriteShape(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// positions returns the positions of all the blocks, sorted the same as Model.Positions
+func (s *Structure) positions() []XYZ {
+	m := Model{blocks: s.blocks}
+	return m.Positions()
+}
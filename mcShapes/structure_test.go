@@ -0,0 +1,117 @@
+package mcshapes
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// Test merging the blocks of a structure into fill commands
+func TestStructureWriteShape(t *testing.T) {
+	m := NewModel()
+	if err := m.Fill(NewBox(WithSurface("minecraft:stone"), WithCorner1(XYZ{X: 0, Y: 0, Z: -2}),
+		WithCorner2(XYZ{X: 3, Y: 1, Z: -4}))); err != nil {
+		t.Fatal(err)
+	}
+	m.SetBlock(XYZ{X: 1, Y: 1, Z: -3}, "minecraft:air")
+	m.SetBlock(XYZ{X: 0, Y: 2, Z: -2}, "minecraft:oak_stairs[facing=east]")
+
+	s := NewStructure(WithModel(m), WithRemap("minecraft:stone 0", "minecraft:sandstone"))
+	var buf bytes.Buffer
+	if err := s.WriteShape(&buf); err != nil {
+		t.Fatalf("WriteShape: %v", err)
+	}
+
+	// The fill commands must build the same thing
+	r, err := ReadModel(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("ReadModel: %v", err)
+	}
+	if r.Len() != m.Len() {
+		t.Errorf("expected %d blocks, got %d", m.Len(), r.Len())
+	}
+	if b := r.Block(XYZ{X: 3, Y: 0, Z: -4}); b != "minecraft:sandstone" {
+		t.Errorf("expected sandstone at 3,0,-4, got '%v'", b)
+	}
+	if b := r.Block(XYZ{X: 1, Y: 1, Z: -3}); b != "minecraft:air" {
+		t.Errorf("expected air at 1,1,-3, got '%v'", b)
+	}
+
+	// Air, the bottom layer, four around the air in the middle layer and the stairs
+	if n := strings.Count(buf.String(), "\n"); n != 7 {
+		t.Errorf("expected 7 fill commands, got %d\n%v", n, buf.String())
+	}
+}
+
+// Test that the boxes of a large structure are split to fit in fill commands
+func TestStructureSplit(t *testing.T) {
+	// More than one fill can place, so it is placed in two halves
+	m := NewModel()
+	for _, z := range []int{-11, -31} {
+		if err := m.Fill(NewBox(WithSurface("minecraft:stone"), WithCorner1(XYZ{X: 0, Y: 0, Z: z}),
+			WithCorner2(XYZ{X: 39, Y: 39, Z: z - 19}))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, s := range []*Structure{NewStructure(WithModel(m)), NewStructure(WithModel(m)).Air()} {
+		volume := 0
+		for _, b := range s.Boxes() {
+			c1, c2 := b.Corners()
+			lo, hi := minMax(c1, c2)
+			n := (hi.X - lo.X + 1) * (hi.Y - lo.Y + 1) * (hi.Z - lo.Z + 1)
+			if n > MaxFillBlocks {
+				t.Errorf("box %v %v has %d blocks, more than %d", lo, hi, n, MaxFillBlocks)
+			}
+			volume += n
+		}
+		if volume != 64000 {
+			t.Errorf("expected the boxes to have 64000 blocks, got %d", volume)
+		}
+	}
+}
+
+// Test orienting a structure the same as a box
+func TestStructureOrient(t *testing.T) {
+	m := NewModel()
+	m.SetBlock(XYZ{X: 2, Y: 0, Z: -3}, "minecraft:glass")
+	s := NewStructure(WithModel(m), WithSkipAir(true))
+	s.Orient("east")
+
+	b := NewBox(At(XYZ{X: 2, Y: 0, Z: -3}))
+	b.Orient("east")
+	c1, _ := b.Corners()
+	lo, _ := s.Bounds()
+	if lo != c1 {
+		t.Errorf("expected the block at %v, got %v", c1, lo)
+	}
+}
+
+// Test turning the block states with the structure
+func TestOrientBlock(t *testing.T) {
+	tests := []struct {
+		block, direction, want string
+	}{
+		{"minecraft:oak_stairs[facing=north,half=top,shape=inner_left]", "east",
+			"minecraft:oak_stairs[facing=east,half=top,shape=inner_left]"},
+		{"minecraft:oak_stairs[facing=east,half=bottom,shape=outer_left]", "north_refl",
+			"minecraft:oak_stairs[facing=west,half=bottom,shape=outer_right]"},
+		{"minecraft:oak_log[axis=x]", "west", "minecraft:oak_log[axis=z]"},
+		{"minecraft:oak_log[axis=x]", "south", "minecraft:oak_log[axis=x]"},
+		{"minecraft:oak_sign[rotation=0]", "east", "minecraft:oak_sign[rotation=4]"},
+		{"minecraft:oak_sign[rotation=4]", "north_refl", "minecraft:oak_sign[rotation=12]"},
+		{"minecraft:rail[shape=north_east]", "east", "minecraft:rail[shape=south_east]"},
+		{"minecraft:rail[shape=ascending_north]", "south", "minecraft:rail[shape=ascending_south]"},
+		{"minecraft:oak_door[facing=south,half=lower,hinge=left]", "south_refl",
+			"minecraft:oak_door[facing=north,half=lower,hinge=right]"},
+		{"minecraft:oak_fence[east=true,north=false,south=false,west=false]", "east",
+			"minecraft:oak_fence[east=false,north=false,south=true,west=false]"},
+		{"minecraft:piston[facing=up]", "west", "minecraft:piston[facing=up]"},
+		{"minecraft:stone", "east", "minecraft:stone"},
+	}
+	for _, tt := range tests {
+		if got := OrientBlock(tt.block, tt.direction); got != tt.want {
+			t.Errorf("OrientBlock(%q, %q) = %q, want %q", tt.block, tt.direction, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,583 @@
+package mcshapes
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// World is a small in memory Minecraft world for testing functions. Commands are run the
+// way the game runs them, at a player standing at a position and facing a direction, and
+// the blocks end up at absolute positions. Only what the functions of mcFunctionDev need is
+// understood,
+//    fill, setblock     see ParseCommand, with absolute, ~ relative or ^ local coordinates
+//    clone              replace or masked, normal, force or move
+//    execute            positioned, rotated, at, as, align, anchored and run
+//    function           run at once, with the function found by Load
+//    schedule function  run after the function that scheduled it, in game tick order
+//    summon, kill       markers and other entities, so execute at @e[tag=...] works
+// Other commands, e.g. say or gamerule, do not change blocks and are skipped.
+// The world starts empty, positions where nothing was placed are air.
+type World struct {
+	model    *Model
+	player   XYZ
+	yaw      float64
+	pitch    float64
+	entities []*entity
+	queue    []scheduled
+	tick     int
+
+	// Load returns the commands of a function, e.g. "mcfd:Falls/waterfall_NWE_10_7".
+	// Function calls are an error if it is not set.
+	Load func(id string) ([]string, error)
+}
+
+// entity is a summoned entity, only its position and tags are kept
+type entity struct {
+	etype string
+	pos   XYZ
+	tags  []string
+}
+
+// scheduled is a function waiting to be run by schedule function
+type scheduled struct {
+	id   string
+	tick int
+}
+
+// context is where and how a command is run, changed by execute
+type context struct {
+	pos   XYZ
+	yaw   float64
+	pitch float64
+}
+
+// Most functions run by one command, to stop a function calling itself forever.
+const maxFunctionDepth = 512
+
+// NewWorld creates a new, empty, world
+func NewWorld(opts ...WorldOption) *World {
+	w := &World{
+		model: NewModel(),
+		//default facing is north, the direction the objects are built in
+		yaw: 180,
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w
+}
+
+// WorldOption sets various options for NewWorld
+type WorldOption func(*World)
+
+// WithPlayer sets the position of the player running the commands
+func WithPlayer(xyz XYZ) WorldOption {
+	return func(w *World) { w.player = xyz }
+}
+
+// WithFacing sets the direction the player is facing
+// The direction is expected to be one of:
+//   north, south, east, or west
+func WithFacing(direction string) WorldOption {
+	return func(w *World) {
+		switch direction {
+		case "south":
+			w.yaw = 0
+		case "west":
+			w.yaw = 90
+		case "north":
+			w.yaw = 180
+		case "east":
+			w.yaw = -90
+		}
+	}
+}
+
+// WithRotation sets the yaw and pitch of the player, in degrees the same as the game
+func WithRotation(yaw float64, pitch float64) WorldOption {
+	return func(w *World) { w.yaw, w.pitch = yaw, pitch }
+}
+
+// Block returns the block at an absolute position, "" if nothing was placed there
+func (w *World) Block(xyz XYZ) string {
+	return w.model.Block(xyz)
+}
+
+// Model returns the blocks of the world at their absolute positions
+func (w *World) Model() *Model {
+	return w.model
+}
+
+// Run runs one command as the player, then any functions it scheduled
+func (w *World) Run(command string) error {
+	err := w.run(command, w.playerContext(), 0)
+	if err != nil {
+		return err
+	}
+	return w.runScheduled()
+}
+
+// RunLines runs the commands of a function as the player, then any functions they
+// scheduled. Errors give the line of the command.
+func (w *World) RunLines(lines []string) error {
+	err := w.runLines(lines, w.playerContext(), 0)
+	if err != nil {
+		return err
+	}
+	return w.runScheduled()
+}
+
+// RunFunction runs a function found by Load as the player, the same as /function
+func (w *World) RunFunction(id string) error {
+	return w.Run("function " + id)
+}
+
+// playerContext is the context of a command typed by the player
+func (w *World) playerContext() context {
+	return context{pos: w.player, yaw: w.yaw, pitch: w.pitch}
+}
+
+// runLines runs the commands of a function in a context
+func (w *World) runLines(lines []string, ctx context, depth int) error {
+	for n, line := range lines {
+		if err := w.run(line, ctx, depth); err != nil {
+			return fmt.Errorf("line %d: %v", n+1, err)
+		}
+	}
+	return nil
+}
+
+// runScheduled runs the scheduled functions, in the order of the game tick they are
+// scheduled for. They are run by the server, at 0,0,0.
+func (w *World) runScheduled() error {
+	for len(w.queue) > 0 {
+		sort.SliceStable(w.queue, func(i, j int) bool { return w.queue[i].tick < w.queue[j].tick })
+		s := w.queue[0]
+		w.queue = w.queue[1:]
+		w.tick = s.tick
+		if err := w.runFunction(s.id, context{}, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runFunction runs the function id in a context
+func (w *World) runFunction(id string, ctx context, depth int) error {
+	if depth >= maxFunctionDepth {
+		return fmt.Errorf("function %v: more than %d functions deep", id, maxFunctionDepth)
+	}
+	if w.Load == nil {
+		return fmt.Errorf("function %v: the world can not load functions", id)
+	}
+	lines, err := w.Load(id)
+	if err != nil {
+		return fmt.Errorf("function %v: %v", id, err)
+	}
+	if err := w.runLines(lines, ctx, depth+1); err != nil {
+		return fmt.Errorf("function %v %v", id, err)
+	}
+	return nil
+}
+
+// run runs one command in a context
+func (w *World) run(command string, ctx context, depth int) error {
+	fields := strings.Fields(command)
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+		return nil
+	}
+	fields[0] = strings.TrimPrefix(fields[0], "/")
+
+	switch fields[0] {
+	case "fill", "setblock":
+		c, err := parseCommand(strings.Join(fields, " "), func(f []string) (XYZ, error) {
+			return ctx.resolve(f)
+		})
+		if err != nil {
+			return err
+		}
+		if err := w.model.Apply(c); err != nil {
+			return fmt.Errorf("%v: %q", err, command)
+		}
+
+	case "clone":
+		return w.clone(fields, ctx)
+
+	case "execute":
+		return w.execute(fields[1:], ctx, depth)
+
+	case "function":
+		if len(fields) < 2 {
+			return fmt.Errorf("function command is too short: %q", command)
+		}
+		if strings.HasPrefix(fields[1], "#") {
+			return fmt.Errorf("function tags are not supported: %q", command)
+		}
+		return w.runFunction(fields[1], ctx, depth)
+
+	case "schedule":
+		if len(fields) < 4 || fields[1] != "function" {
+			return fmt.Errorf("schedule command is not supported: %q", command)
+		}
+		ticks, err := parseTicks(fields[3])
+		if err != nil {
+			return fmt.Errorf("%v: %q", err, command)
+		}
+		w.queue = append(w.queue, scheduled{id: fields[2], tick: w.tick + ticks})
+
+	case "summon":
+		if len(fields) < 2 {
+			return fmt.Errorf("summon command is too short: %q", command)
+		}
+		e := &entity{etype: namespaced(fields[1]), pos: ctx.pos}
+		if len(fields) >= 5 {
+			pos, err := ctx.resolve(fields[2:5])
+			if err != nil {
+				return fmt.Errorf("%v: %q", err, command)
+			}
+			e.pos = pos
+		}
+		e.tags = summonTags(command)
+		w.entities = append(w.entities, e)
+
+	case "kill":
+		if len(fields) < 2 {
+			return nil
+		}
+		sel, err := parseSelector(fields[1])
+		if err != nil {
+			return fmt.Errorf("%v: %q", err, command)
+		}
+		var left []*entity
+		for _, e := range w.entities {
+			if !sel.matches(e) {
+				left = append(left, e)
+			}
+		}
+		w.entities = left
+	}
+	return nil
+}
+
+// execute runs the execute subcommands in fields, changing the context, until run
+func (w *World) execute(fields []string, ctx context, depth int) error {
+	for len(fields) > 0 {
+		switch fields[0] {
+		case "run":
+			return w.run(strings.Join(fields[1:], " "), ctx, depth)
+
+		case "positioned":
+			if len(fields) < 4 || fields[1] == "as" || fields[1] == "over" {
+				return fmt.Errorf("execute positioned %v is not supported", fields[1:])
+			}
+			pos, err := ctx.resolve(fields[1:4])
+			if err != nil {
+				return err
+			}
+			ctx.pos = pos
+			fields = fields[4:]
+
+		case "rotated":
+			if len(fields) < 3 || fields[1] == "as" {
+				return fmt.Errorf("execute rotated %v is not supported", fields[1:])
+			}
+			yaw, err := parseAngle(fields[1], ctx.yaw)
+			if err != nil {
+				return err
+			}
+			pitch, err := parseAngle(fields[2], ctx.pitch)
+			if err != nil {
+				return err
+			}
+			ctx.yaw, ctx.pitch = yaw, pitch
+			fields = fields[3:]
+
+		case "at", "as":
+			if len(fields) < 2 {
+				return fmt.Errorf("execute %v needs a target", fields[0])
+			}
+			targets, err := w.targets(fields[1], ctx)
+			if err != nil {
+				return err
+			}
+			for _, t := range targets {
+				tctx := ctx
+				if fields[0] == "at" {
+					tctx.pos = t
+				}
+				if err := w.execute(fields[2:], tctx, depth); err != nil {
+					return err
+				}
+			}
+			return nil
+
+		case "align", "anchored":
+			// Positions are always whole blocks at the feet
+			if len(fields) < 2 {
+				return fmt.Errorf("execute %v is too short", fields[0])
+			}
+			fields = fields[2:]
+
+		default:
+			return fmt.Errorf("execute %v is not supported", fields[0])
+		}
+	}
+	return nil
+}
+
+// targets returns the positions of the entities picked by a target selector. The player is
+// @s, @p, @a or @r, and is always at the player position.
+func (w *World) targets(s string, ctx context) ([]XYZ, error) {
+	switch {
+	case s == "@s":
+		return []XYZ{ctx.pos}, nil
+	case strings.HasPrefix(s, "@p"), strings.HasPrefix(s, "@a"), strings.HasPrefix(s, "@r"):
+		return []XYZ{w.player}, nil
+	}
+	sel, err := parseSelector(s)
+	if err != nil {
+		return nil, err
+	}
+	var targets []XYZ
+	for _, e := range w.entities {
+		if sel.matches(e) && (sel.limit == 0 || len(targets) < sel.limit) {
+			targets = append(targets, e.pos)
+		}
+	}
+	return targets, nil
+}
+
+// clone runs a clone command
+func (w *World) clone(fields []string, ctx context) error {
+	if len(fields) < 10 {
+		return fmt.Errorf("clone command is too short: %v", fields)
+	}
+	var c [3]XYZ
+	for i := range c {
+		xyz, err := ctx.resolve(fields[3*i+1 : 3*i+4])
+		if err != nil {
+			return err
+		}
+		c[i] = xyz
+	}
+	mask, mode := "replace", "normal"
+	if len(fields) > 10 {
+		mask = fields[10]
+	}
+	if len(fields) > 11 {
+		mode = fields[11]
+	}
+	if mask != "replace" && mask != "masked" {
+		return fmt.Errorf("clone %v is not supported", mask)
+	}
+	if mode != "normal" && mode != "force" && mode != "move" {
+		return fmt.Errorf("clone mode %v is not supported", mode)
+	}
+
+	// Copy the source first, the source and destination can overlap.
+	if _, err := fillVolume(c[0], c[1]); err != nil {
+		return err
+	}
+	lo, hi := minMax(c[0], c[1])
+	src := make(map[XYZ]string)
+	for y := lo.Y; y <= hi.Y; y++ {
+		for z := lo.Z; z <= hi.Z; z++ {
+			for x := lo.X; x <= hi.X; x++ {
+				xyz := XYZ{X: x, Y: y, Z: z}
+				if b, ok := w.model.blocks[xyz]; ok {
+					src[xyz] = b
+				} else {
+					src[xyz] = "minecraft:air"
+				}
+			}
+		}
+	}
+	if mode == "move" {
+		for xyz := range src {
+			w.model.blocks[xyz] = "minecraft:air"
+		}
+	}
+	for xyz, b := range src {
+		if mask == "masked" && IsAir(b) {
+			continue
+		}
+		w.model.blocks[XYZ{X: c[2].X + xyz.X - lo.X, Y: c[2].Y + xyz.Y - lo.Y,
+			Z: c[2].Z + xyz.Z - lo.Z}] = b
+	}
+	return nil
+}
+
+// resolve turns 3 coordinates, absolute, ~ relative or ^ local, into a position
+func (ctx context) resolve(fields []string) (XYZ, error) {
+	if len(fields) != 3 {
+		return XYZ{}, fmt.Errorf("expected 3 coordinates, got %v", fields)
+	}
+
+	// Local coordinates are left, up and forward from where the context is looking.
+	if strings.HasPrefix(fields[0], "^") {
+		var v [3]float64
+		for i, f := range fields {
+			if !strings.HasPrefix(f, "^") {
+				return XYZ{}, fmt.Errorf("cannot mix ^ and other coordinates: %v", fields)
+			}
+			if f != "^" {
+				d, err := strconv.ParseFloat(f[1:], 64)
+				if err != nil {
+					return XYZ{}, fmt.Errorf("coordinate %q: %v", f, err)
+				}
+				v[i] = d
+			}
+		}
+		yaw := ctx.yaw * math.Pi / 180
+		pitch := ctx.pitch * math.Pi / 180
+		left := [3]float64{math.Cos(yaw), 0, math.Sin(yaw)}
+		up := [3]float64{-math.Sin(yaw) * math.Sin(pitch), math.Cos(pitch),
+			math.Cos(yaw) * math.Sin(pitch)}
+		forward := [3]float64{-math.Sin(yaw) * math.Cos(pitch), -math.Sin(pitch),
+			math.Cos(yaw) * math.Cos(pitch)}
+		var d [3]int
+		for i := range d {
+			d[i] = int(math.Round(v[0]*left[i] + v[1]*up[i] + v[2]*forward[i]))
+		}
+		return XYZ{X: ctx.pos.X + d[0], Y: ctx.pos.Y + d[1], Z: ctx.pos.Z + d[2]}, nil
+	}
+
+	base := [3]int{ctx.pos.X, ctx.pos.Y, ctx.pos.Z}
+	var c [3]int
+	for i, f := range fields {
+		switch {
+		case strings.HasPrefix(f, "^"):
+			return XYZ{}, fmt.Errorf("cannot mix ^ and other coordinates: %v", fields)
+		case strings.HasPrefix(f, "~"):
+			v, err := parseRelative(f)
+			if err != nil {
+				return XYZ{}, err
+			}
+			c[i] = base[i] + v
+		default:
+			v, err := strconv.Atoi(f)
+			if err != nil {
+				return XYZ{}, fmt.Errorf("coordinate %q: %v", f, err)
+			}
+			c[i] = v
+		}
+	}
+	return XYZ{X: c[0], Y: c[1], Z: c[2]}, nil
+}
+
+// parseAngle reads an absolute or ~ relative angle for execute rotated
+func parseAngle(s string, current float64) (float64, error) {
+	if strings.HasPrefix(s, "~") {
+		if s == "~" {
+			return current, nil
+		}
+		d, err := strconv.ParseFloat(s[1:], 64)
+		return current + d, err
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// parseTicks reads the delay of schedule function, e.g. 1t, 2s or 1d
+func parseTicks(s string) (int, error) {
+	unit := 1
+	switch {
+	case strings.HasSuffix(s, "t"):
+		s = strings.TrimSuffix(s, "t")
+	case strings.HasSuffix(s, "s"):
+		s, unit = strings.TrimSuffix(s, "s"), 20
+	case strings.HasSuffix(s, "d"):
+		s, unit = strings.TrimSuffix(s, "d"), 24000
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("schedule time %q: %v", s, err)
+	}
+	return int(v * float64(unit)), nil
+}
+
+// The tags of a summoned entity, {Tags:["a","b"]}
+var summonTagsRe = regexp.MustCompile(`Tags:\[([^\]]*)\]`)
+
+// summonTags returns the tags given to an entity by a summon command
+func summonTags(command string) []string {
+	m := summonTagsRe.FindStringSubmatch(command)
+	if m == nil {
+		return nil
+	}
+	var tags []string
+	for _, t := range strings.Split(m[1], ",") {
+		if t = strings.Trim(strings.TrimSpace(t), `"`); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// selector is an @e target selector, only type, tag and limit are used
+type selector struct {
+	etype string
+	tags  []string
+	limit int
+}
+
+// parseSelector reads an @e target selector, e.g. @e[type=minecraft:marker,tag=x,limit=1]
+func parseSelector(s string) (selector, error) {
+	var sel selector
+	if !strings.HasPrefix(s, "@e") {
+		return sel, fmt.Errorf("target %q is not supported", s)
+	}
+	args := strings.TrimPrefix(s, "@e")
+	if args == "" {
+		return sel, nil
+	}
+	if !strings.HasPrefix(args, "[") || !strings.HasSuffix(args, "]") {
+		return sel, fmt.Errorf("target %q is not valid", s)
+	}
+	for _, kv := range strings.Split(args[1:len(args)-1], ",") {
+		k, v, _ := strings.Cut(kv, "=")
+		switch k {
+		case "type":
+			sel.etype = namespaced(v)
+		case "tag":
+			sel.tags = append(sel.tags, v)
+		case "limit":
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return sel, fmt.Errorf("target %q: %v", s, err)
+			}
+			sel.limit = n
+		}
+	}
+	return sel, nil
+}
+
+// matches reports whether an entity is picked by the selector
+func (sel selector) matches(e *entity) bool {
+	if sel.etype != "" && sel.etype != e.etype {
+		return false
+	}
+	for _, t := range sel.tags {
+		found := false
+		for _, et := range e.tags {
+			found = found || et == t
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// namespaced adds the minecraft namespace to an id without one
+func namespaced(id string) string {
+	if strings.Contains(id, ":") {
+		return id
+	}
+	return "minecraft:" + id
+}
@@ -0,0 +1,86 @@
+package mcshapes
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Test running relative and local coordinates for a player facing east
+func TestWorldFacing(t *testing.T) {
+	w := NewWorld(WithPlayer(XYZ{X: 100, Y: 64, Z: 100}), WithFacing("east"))
+	err := w.RunLines([]string{
+		"setblock ~1 ~0 ~-2 minecraft:stone",
+		"setblock ^ ^ ^2 minecraft:glass",
+		"setblock ^1 ^ ^ minecraft:dirt",
+		"execute positioned ~ ~10 ~ rotated 0 0 run setblock ^ ^ ^3 minecraft:sand",
+	})
+	if err != nil {
+		t.Fatalf("RunLines: %v", err)
+	}
+
+	tests := []struct {
+		xyz   XYZ
+		block string
+	}{
+		{XYZ{X: 101, Y: 64, Z: 98}, "minecraft:stone"},
+		// Facing east forward is +X and left is -Z
+		{XYZ{X: 102, Y: 64, Z: 100}, "minecraft:glass"},
+		{XYZ{X: 100, Y: 64, Z: 99}, "minecraft:dirt"},
+		// Rotated to face south
+		{XYZ{X: 100, Y: 74, Z: 103}, "minecraft:sand"},
+	}
+	for _, tt := range tests {
+		if b := w.Block(tt.xyz); b != tt.block {
+			t.Errorf("expected '%v' at %v, got '%v'", tt.block, tt.xyz, b)
+		}
+	}
+}
+
+// Test clone and the scheduled functions with a marker, the way split functions are built
+func TestWorldScheduled(t *testing.T) {
+	functions := map[string][]string{
+		"mcfd:Test/build": {
+			"summon minecraft:marker ~ ~ ~ {Tags:[\"mcfd_build\"]}",
+			"schedule function mcfd:Test/build_001 1t",
+		},
+		"mcfd:Test/build_001": {
+			"execute at @e[type=minecraft:marker,tag=mcfd_build,limit=1] run " +
+				"fill ~0 ~0 ~-2 ~2 ~0 ~-2 minecraft:stone",
+			"kill @e[type=minecraft:marker,tag=mcfd_build,limit=1]",
+		},
+	}
+	w := NewWorld(WithPlayer(XYZ{X: 10, Y: 5, Z: 10}))
+	w.Load = func(id string) ([]string, error) {
+		f, ok := functions[id]
+		if !ok {
+			return nil, fmt.Errorf("unknown function")
+		}
+		return f, nil
+	}
+
+	if err := w.RunFunction("mcfd:Test/build"); err != nil {
+		t.Fatalf("RunFunction: %v", err)
+	}
+	if b := w.Block(XYZ{X: 12, Y: 5, Z: 8}); b != "minecraft:stone" {
+		t.Errorf("expected stone at 12,5,8, got '%v'", b)
+	}
+
+	if err := w.Run("clone 10 5 8 12 5 8 10 6 8 masked move"); err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	if b := w.Block(XYZ{X: 11, Y: 6, Z: 8}); b != "minecraft:stone" {
+		t.Errorf("expected stone at 11,6,8, got '%v'", b)
+	}
+	if b := w.Block(XYZ{X: 11, Y: 5, Z: 8}); !IsAir(b) {
+		t.Errorf("expected air at 11,5,8 after the move, got '%v'", b)
+	}
+
+	// The marker is gone, so the part does nothing
+	w.Model().SetBlock(XYZ{X: 12, Y: 5, Z: 8}, "minecraft:dirt")
+	if err := w.RunFunction("mcfd:Test/build_001"); err != nil {
+		t.Fatalf("RunFunction: %v", err)
+	}
+	if b := w.Block(XYZ{X: 12, Y: 5, Z: 8}); b != "minecraft:dirt" {
+		t.Errorf("expected dirt at 12,5,8, got '%v'", b)
+	}
+}
@@ -0,0 +1,159 @@
+package mcview
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image/color"
+	"strings"
+
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+)
+
+// Block colors
+//
+// Every block is drawn in one color, close to how it looks in the game. The blocks used by
+// the generators have their own colors, the colored blocks (wool, concrete, glass, ...) get
+// the color of their dye, and any other block a color made up from its name so it is at
+// least different from its neighbors.
+
+// Palette is the color of each block, by the block name without block states, e.g.
+// "minecraft:polished_diorite". The old block names are looked up by their new name.
+type Palette map[string]color.RGBA
+
+// Colors of the blocks used by the generators and other common blocks
+var defaultColors = map[string]color.RGBA{
+	"minecraft:stone":                 {125, 125, 125, 255},
+	"minecraft:granite":               {149, 103, 85, 255},
+	"minecraft:polished_granite":      {154, 106, 89, 255},
+	"minecraft:diorite":               {188, 188, 188, 255},
+	"minecraft:polished_diorite":      {192, 193, 194, 255},
+	"minecraft:andesite":              {136, 136, 136, 255},
+	"minecraft:polished_andesite":     {132, 134, 133, 255},
+	"minecraft:cobblestone":           {127, 127, 127, 255},
+	"minecraft:mossy_cobblestone":     {110, 118, 94, 255},
+	"minecraft:stone_bricks":          {122, 121, 122, 255},
+	"minecraft:mossy_stone_bricks":    {115, 121, 105, 255},
+	"minecraft:infested_stone_bricks": {122, 121, 122, 255},
+	"minecraft:bricks":                {150, 97, 83, 255},
+	"minecraft:sandstone":             {216, 203, 155, 255},
+	"minecraft:red_sandstone":         {186, 99, 29, 255},
+	"minecraft:sand":                  {219, 207, 163, 255},
+	"minecraft:gravel":                {131, 127, 126, 255},
+	"minecraft:dirt":                  {134, 96, 67, 255},
+	"minecraft:grass_block":           {95, 159, 53, 255},
+	"minecraft:clay":                  {160, 166, 179, 255},
+	"minecraft:glass":                 {200, 230, 235, 255},
+	"minecraft:ice":                   {145, 183, 253, 255},
+	"minecraft:packed_ice":            {141, 180, 250, 255},
+	"minecraft:snow_block":            {249, 254, 254, 255},
+	"minecraft:water":                 {63, 118, 228, 255},
+	"minecraft:lava":                  {207, 92, 20, 255},
+	"minecraft:obsidian":              {15, 10, 24, 255},
+	"minecraft:netherrack":            {97, 38, 38, 255},
+	"minecraft:glowstone":             {171, 131, 84, 255},
+	"minecraft:sea_lantern":           {172, 199, 190, 255},
+	"minecraft:gold_block":            {246, 208, 61, 255},
+	"minecraft:iron_block":            {220, 220, 220, 255},
+	"minecraft:diamond_block":         {98, 237, 228, 255},
+	"minecraft:emerald_block":         {42, 203, 87, 255},
+	"minecraft:lapis_block":           {30, 67, 140, 255},
+	"minecraft:redstone_block":        {175, 24, 5, 255},
+	"minecraft:coal_block":            {16, 15, 15, 255},
+	"minecraft:quartz_block":          {235, 229, 222, 255},
+	"minecraft:purpur_block":          {169, 125, 169, 255},
+	"minecraft:prismarine":            {99, 156, 151, 255},
+	"minecraft:bookshelf":             {117, 94, 59, 255},
+	"minecraft:torch":                 {255, 216, 0, 255},
+	"minecraft:wall_torch":            {255, 216, 0, 255},
+	"minecraft:powered_rail":          {154, 110, 72, 255},
+	"minecraft:rail":                  {125, 111, 88, 255},
+	"minecraft:redstone_lamp":         {142, 101, 60, 255},
+	"minecraft:bedrock":               {85, 85, 85, 255},
+}
+
+// Colors of the woods, for planks, logs, fences, stairs, ...
+var woodColors = map[string]color.RGBA{
+	"oak":      {162, 130, 78, 255},
+	"spruce":   {114, 84, 48, 255},
+	"birch":    {192, 175, 121, 255},
+	"jungle":   {160, 115, 80, 255},
+	"acacia":   {168, 90, 50, 255},
+	"dark_oak": {66, 43, 20, 255},
+	"mangrove": {117, 54, 48, 255},
+	"cherry":   {226, 178, 172, 255},
+	"bamboo":   {193, 173, 80, 255},
+	"crimson":  {101, 48, 70, 255},
+	"warped":   {43, 104, 99, 255},
+}
+
+// Colors of the dyes, for wool, concrete, stained glass, ...
+var dyeColors = map[string]color.RGBA{
+	"white":      {233, 236, 236, 255},
+	"orange":     {240, 118, 19, 255},
+	"magenta":    {189, 68, 179, 255},
+	"light_blue": {58, 175, 217, 255},
+	"yellow":     {248, 197, 39, 255},
+	"lime":       {112, 185, 25, 255},
+	"pink":       {237, 141, 172, 255},
+	"gray":       {62, 68, 71, 255},
+	"light_gray": {142, 142, 134, 255},
+	"cyan":       {21, 137, 145, 255},
+	"purple":     {121, 42, 172, 255},
+	"blue":       {53, 57, 157, 255},
+	"brown":      {114, 71, 40, 255},
+	"green":      {84, 109, 27, 255},
+	"red":        {161, 39, 34, 255},
+	"black":      {20, 21, 25, 255},
+}
+
+// DefaultPalette returns a palette with the colors of the blocks used by the generators.
+// Blocks that are not in it are still drawn, see Color.
+func DefaultPalette() Palette {
+	p := make(Palette)
+	for name, c := range defaultColors {
+		p[name] = c
+	}
+	return p
+}
+
+// SetColor sets the color of a block, c is "#rrggbb" or "rrggbb", e.g. "#3f76e4".
+// The old names of blocks are set by their new name.
+func (p Palette) SetColor(block string, c string) error {
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(strings.TrimPrefix(c, "#"), "%02x%02x%02x", &r, &g, &b); err != nil ||
+		len(strings.TrimPrefix(c, "#")) != 6 {
+		return fmt.Errorf("color %q of %v is not #rrggbb", c, block)
+	}
+	p[mcshapes.BlockName(block)] = color.RGBA{R: r, G: g, B: b, A: 255}
+	return nil
+}
+
+// Color returns the color of a block, old or new form, with or without block states.
+func (p Palette) Color(block string) color.RGBA {
+	name := mcshapes.BlockName(block)
+	if c, ok := p[name]; ok {
+		return c
+	}
+
+	// Families of blocks, by the dye or the wood at the start of the name
+	short := strings.TrimPrefix(name, "minecraft:")
+	short = strings.TrimPrefix(short, "stripped_")
+	for _, family := range []map[string]color.RGBA{dyeColors, woodColors} {
+		best := ""
+		for prefix := range family {
+			if strings.HasPrefix(short, prefix+"_") && len(prefix) > len(best) {
+				best = prefix
+			}
+		}
+		if best != "" {
+			return family[best]
+		}
+	}
+
+	// Some other block, a muted color from the name
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	v := h.Sum32()
+	return color.RGBA{R: uint8(64 + v%160), G: uint8(64 + (v>>8)%160),
+		B: uint8(64 + (v>>16)%160), A: 255}
+}
@@ -0,0 +1,199 @@
+package mcview
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+)
+
+// glTF
+//
+// The mesh is written as binary glTF 2.0, a .glb file, with everything in the one file. Web
+// viewers, Blender, Windows 3D Viewer and most game engines read it. There is one primitive
+// for each material, the colors are plain base colors without textures.
+
+// The parts of a glTF file that are written, see the glTF 2.0 specification
+type gltfFile struct {
+	Asset       gltfAsset        `json:"asset"`
+	Scene       int              `json:"scene"`
+	Scenes      []gltfScene      `json:"scenes"`
+	Nodes       []gltfNode       `json:"nodes"`
+	Meshes      []gltfMesh       `json:"meshes"`
+	Materials   []gltfMaterial   `json:"materials"`
+	Accessors   []gltfAccessor   `json:"accessors"`
+	BufferViews []gltfBufferView `json:"bufferViews"`
+	Buffers     []gltfBuffer     `json:"buffers"`
+}
+
+type gltfAsset struct {
+	Version   string `json:"version"`
+	Generator string `json:"generator"`
+}
+
+type gltfScene struct {
+	Nodes []int `json:"nodes"`
+}
+
+type gltfNode struct {
+	Name string `json:"name"`
+	Mesh int    `json:"mesh"`
+}
+
+type gltfMesh struct {
+	Name       string          `json:"name"`
+	Primitives []gltfPrimitive `json:"primitives"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    int            `json:"indices"`
+	Material   int            `json:"material"`
+}
+
+type gltfMaterial struct {
+	Name                 string  `json:"name"`
+	PbrMetallicRoughness gltfPBR `json:"pbrMetallicRoughness"`
+	AlphaMode            string  `json:"alphaMode,omitempty"`
+}
+
+type gltfPBR struct {
+	BaseColorFactor [4]float64 `json:"baseColorFactor"`
+	MetallicFactor  float64    `json:"metallicFactor"`
+	RoughnessFactor float64    `json:"roughnessFactor"`
+}
+
+type gltfAccessor struct {
+	BufferView    int       `json:"bufferView"`
+	ComponentType int       `json:"componentType"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float64 `json:"min,omitempty"`
+	Max           []float64 `json:"max,omitempty"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	Target     int `json:"target"`
+}
+
+type gltfBuffer struct {
+	ByteLength int `json:"byteLength"`
+}
+
+// glTF constants
+const (
+	gltfFloat        = 5126
+	gltfUnsignedInt  = 5125
+	gltfArrayBuffer  = 34962
+	gltfElementArray = 34963
+	glbMagic         = 0x46546C67 // "glTF"
+	glbChunkJSON     = 0x4E4F534A // "JSON"
+	glbChunkBIN      = 0x004E4942 // "BIN"
+)
+
+// WriteGLB writes the mesh as a binary glTF file, name is the name of the mesh.
+func (me *Mesh) WriteGLB(w io.Writer, name string) error {
+	g := gltfFile{
+		Asset:  gltfAsset{Version: "2.0", Generator: "mcFunctionDev"},
+		Scenes: []gltfScene{{Nodes: []int{0}}},
+		Nodes:  []gltfNode{{Name: name, Mesh: 0}},
+		Meshes: []gltfMesh{{Name: name, Primitives: []gltfPrimitive{}}},
+	}
+	var bin bytes.Buffer
+
+	// addView adds data to the buffer and a buffer view of it, all the data is 4 byte values
+	addView := func(data interface{}, target int) int {
+		offset := bin.Len()
+		binary.Write(&bin, binary.LittleEndian, data)
+		g.BufferViews = append(g.BufferViews, gltfBufferView{ByteOffset: offset,
+			ByteLength: bin.Len() - offset, Target: target})
+		return len(g.BufferViews) - 1
+	}
+
+	for _, material := range me.materials {
+		faces := me.faces[material]
+		positions := make([]float32, 0, 12*len(faces))
+		normals := make([]float32, 0, 12*len(faces))
+		indices := make([]uint32, 0, 6*len(faces))
+		lo := []float64{math.Inf(1), math.Inf(1), math.Inf(1)}
+		hi := []float64{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+		for _, f := range faces {
+			n := uint32(len(positions) / 3)
+			indices = append(indices, n, n+1, n+2, n, n+2, n+3)
+			for _, c := range f.corners {
+				for i, v := range c {
+					positions = append(positions, float32(v))
+					normals = append(normals, float32(faceNormals[f.normal][i]))
+					lo[i], hi[i] = math.Min(lo[i], float64(v)), math.Max(hi[i], float64(v))
+				}
+			}
+		}
+
+		g.Accessors = append(g.Accessors,
+			gltfAccessor{BufferView: addView(positions, gltfArrayBuffer),
+				ComponentType: gltfFloat, Count: len(positions) / 3, Type: "VEC3",
+				Min: lo, Max: hi},
+			gltfAccessor{BufferView: addView(normals, gltfArrayBuffer),
+				ComponentType: gltfFloat, Count: len(normals) / 3, Type: "VEC3"},
+			gltfAccessor{BufferView: addView(indices, gltfElementArray),
+				ComponentType: gltfUnsignedInt, Count: len(indices), Type: "SCALAR"})
+		a := len(g.Accessors)
+		g.Meshes[0].Primitives = append(g.Meshes[0].Primitives, gltfPrimitive{
+			Attributes: map[string]int{"POSITION": a - 3, "NORMAL": a - 2},
+			Indices:    a - 1,
+			Material:   len(g.Materials),
+		})
+
+		// glTF colors are linear, the palette is sRGB
+		c := me.palette.Color(material)
+		m := gltfMaterial{Name: materialName(material), PbrMetallicRoughness: gltfPBR{
+			BaseColorFactor: [4]float64{linear(c.R), linear(c.G), linear(c.B), 1},
+			RoughnessFactor: 1,
+		}}
+		if Translucent(material) {
+			m.PbrMetallicRoughness.BaseColorFactor[3] = alphaTranslucent
+			m.AlphaMode = "BLEND"
+		}
+		g.Materials = append(g.Materials, m)
+	}
+	g.Buffers = []gltfBuffer{{ByteLength: bin.Len()}}
+
+	js, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+	// Chunks are padded to 4 bytes, JSON with spaces
+	for len(js)%4 != 0 {
+		js = append(js, ' ')
+	}
+
+	header := []uint32{glbMagic, 2, uint32(12 + 8 + len(js) + 8 + bin.Len()),
+		uint32(len(js)), glbChunkJSON}
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	if _, err := w.Write(js); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, []uint32{uint32(bin.Len()),
+		glbChunkBIN}); err != nil {
+		return err
+	}
+	_, err = w.Write(bin.Bytes())
+	return err
+}
+
+// linear converts an sRGB color value to linear, 0 to 1
+func linear(v uint8) float64 {
+	c := float64(v) / 255
+	if c <= 0.04045 {
+		c = c / 12.92
+	} else {
+		c = math.Pow((c+0.055)/1.055, 2.4)
+	}
+	return math.Round(c*10000) / 10000
+}
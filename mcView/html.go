@@ -0,0 +1,250 @@
+package mcview
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+)
+
+// HTML viewer
+//
+// A web page with the blocks of a model in 3D, for build proposals. Everything is in the
+// one file, the blocks and the script, so it opens in any browser without a network and
+// without installing a viewer. The page shows
+//    drag          turns the blocks around, up and down
+//    mouse wheel   zooms in and out
+//    Y slider      hides the layers above Y, to see inside
+//    mouse over    the block and its relative coordinates, e.g. "glass ~2 ~1 ~-2"
+// The blocks are drawn as cubes in their colors with the painter's algorithm on a 2D canvas,
+// no WebGL, so it works everywhere. Translucent blocks are half transparent.
+
+// htmlMaterial is a block type on the page
+type htmlMaterial struct {
+	Name        string  `json:"n"`
+	Color       string  `json:"c"`
+	Alpha       float64 `json:"a"`
+	Translucent bool    `json:"t"`
+}
+
+// htmlData is the data of the page, the blocks are x, y, z, material for each block
+type htmlData struct {
+	Name      string         `json:"name"`
+	Materials []htmlMaterial `json:"materials"`
+	Blocks    []int          `json:"blocks"`
+}
+
+// WriteHTML writes a web page with a 3D view of the blocks of a model, name is the title.
+func WriteHTML(w io.Writer, m *mcshapes.Model, name string, palette Palette) error {
+	data := htmlData{Name: name, Materials: []htmlMaterial{}, Blocks: []int{}}
+	index := make(map[string]int)
+	positions := m.Positions()
+	sort.Slice(positions, func(i, j int) bool {
+		a, b := positions[i], positions[j]
+		if a.Y != b.Y {
+			return a.Y < b.Y
+		}
+		if a.Z != b.Z {
+			return a.Z < b.Z
+		}
+		return a.X < b.X
+	})
+	for _, p := range positions {
+		b := m.Block(p)
+		if b == "" || mcshapes.IsAir(b) {
+			continue
+		}
+		b = mcshapes.BlockString(b)
+		i, ok := index[b]
+		if !ok {
+			c := palette.Color(b)
+			mat := htmlMaterial{Name: strings.TrimPrefix(b, "minecraft:"),
+				Color: fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B), Alpha: 1}
+			if Translucent(b) {
+				mat.Alpha, mat.Translucent = alphaTranslucent, true
+			}
+			i = len(data.Materials)
+			index[b] = i
+			data.Materials = append(data.Materials, mat)
+		}
+		data.Blocks = append(data.Blocks, p.X, p.Y, p.Z, i)
+	}
+
+	// json.Marshal escapes <, > and &, so the data can not end the script
+	js, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, htmlPage, html.EscapeString(name), js)
+	return bw.Flush()
+}
+
+// The page, with the title and the data
+const htmlPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>%[1]v</title>
+<style>
+html, body { margin: 0; height: 100%%; font: 14px sans-serif; background: #f4f4f4; overflow: hidden; }
+#bar { display: flex; gap: 16px; align-items: center; padding: 6px 10px; background: #fff;
+  border-bottom: 1px solid #ccc; height: 28px; }
+#bar b { white-space: nowrap; }
+#info { font-family: monospace; min-width: 24em; }
+#help { color: #777; margin-left: auto; white-space: nowrap; }
+canvas { display: block; cursor: grab; touch-action: none; }
+</style>
+</head>
+<body>
+<div id="bar">
+<b>%[1]v</b>
+<label>Y &le; <span id="layerv"></span> <input id="layer" type="range" step="1"></label>
+<span id="info"></span>
+<span id="help">drag to turn, wheel to zoom</span>
+</div>
+<canvas id="c"></canvas>
+<script>
+const D = %[2]s;
+(function() {
+  const cv = document.getElementById("c"), ctx = cv.getContext("2d");
+  const info = document.getElementById("info");
+  const slider = document.getElementById("layer"), layerv = document.getElementById("layerv");
+  const B = D.blocks, M = D.materials, n = B.length / 4;
+  const key = (x, y, z) => x + "," + y + "," + z;
+  const at = new Map();
+  const lo = [Infinity, Infinity, Infinity], hi = [-Infinity, -Infinity, -Infinity];
+  for (let i = 0; i < n; i++) {
+    at.set(key(B[4*i], B[4*i+1], B[4*i+2]), i);
+    for (let k = 0; k < 3; k++) {
+      lo[k] = Math.min(lo[k], B[4*i+k]);
+      hi[k] = Math.max(hi[k], B[4*i+k]);
+    }
+  }
+  if (n == 0) { lo.fill(0); hi.fill(0); }
+  const center = [0, 1, 2].map(k => (lo[k] + hi[k] + 1) / 2);
+  const size = Math.max(hi[0]-lo[0]+1, hi[1]-lo[1]+1, hi[2]-lo[2]+1);
+  slider.min = lo[1]; slider.max = hi[1]; slider.value = hi[1];
+  let yaw = Math.PI / 4, pitch = 0.6, zoom = 1, maxY = hi[1];
+
+  // The faces of a cube, normal, corners counter clockwise from outside, and shade
+  const F = [
+    [[1, 0, 0], [[1,0,0], [1,1,0], [1,1,1], [1,0,1]], 0.8],
+    [[-1, 0, 0], [[0,0,0], [0,0,1], [0,1,1], [0,1,0]], 0.8],
+    [[0, 1, 0], [[0,1,0], [0,1,1], [1,1,1], [1,1,0]], 1.0],
+    [[0, -1, 0], [[0,0,0], [1,0,0], [1,0,1], [0,0,1]], 0.5],
+    [[0, 0, 1], [[0,0,1], [1,0,1], [1,1,1], [0,1,1]], 0.65],
+    [[0, 0, -1], [[0,0,0], [0,1,0], [1,1,0], [1,0,0]], 0.65],
+  ];
+  const shade = (hex, f) => {
+    const v = parseInt(hex.slice(1), 16);
+    return [(v >> 16) & 255, (v >> 8) & 255, v & 255].map(c => Math.round(c * f));
+  };
+  const colors = M.map(m => F.map(f => shade(m.c, f[2])));
+
+  let polys = [];
+  function draw() {
+    cv.width = window.innerWidth;
+    cv.height = window.innerHeight - document.getElementById("bar").offsetHeight;
+    layerv.textContent = "~" + maxY;
+    const cy = Math.cos(yaw), sy = Math.sin(yaw), cp = Math.cos(pitch), sp = Math.sin(pitch);
+    const s = Math.min(cv.width, cv.height) / (size * 1.8) * zoom;
+    const turn = (x, y, z) => {
+      const x1 = x*cy - z*sy, z1 = x*sy + z*cy;
+      return [x1, y*cp - z1*sp, y*sp + z1*cp];
+    };
+    const project = (x, y, z) => {
+      const p = turn(x - center[0], y - center[1], z - center[2]);
+      return [cv.width/2 + p[0]*s, cv.height/2 - p[1]*s, p[2]];
+    };
+    const facing = F.map(f => turn(f[0][0], f[0][1], f[0][2])[2] > 1e-9);
+
+    polys = [];
+    for (let i = 0; i < n; i++) {
+      const x = B[4*i], y = B[4*i+1], z = B[4*i+2], m = B[4*i+3];
+      if (y > maxY) continue;
+      for (let f = 0; f < 6; f++) {
+        if (!facing[f]) continue;
+        const nb = at.get(key(x + F[f][0][0], y + F[f][0][1], z + F[f][0][2]));
+        if (nb !== undefined && B[4*nb+1] <= maxY &&
+            (B[4*nb+3] == m || !M[B[4*nb+3]].t)) continue;
+        const p = F[f][1].map(c => project(x + c[0], y + c[1], z + c[2]));
+        polys.push({p: p, d: (p[0][2] + p[1][2] + p[2][2] + p[3][2]) / 4, f: f, i: i});
+      }
+    }
+    polys.sort((a, b) => a.d - b.d);
+
+    ctx.fillStyle = "#f4f4f4";
+    ctx.fillRect(0, 0, cv.width, cv.height);
+    ctx.lineWidth = 1;
+    for (const q of polys) {
+      const m = B[4*q.i+3], c = colors[m][q.f];
+      ctx.globalAlpha = M[m].a;
+      ctx.beginPath();
+      ctx.moveTo(q.p[0][0], q.p[0][1]);
+      for (let k = 1; k < 4; k++) ctx.lineTo(q.p[k][0], q.p[k][1]);
+      ctx.closePath();
+      ctx.fillStyle = "rgb(" + c + ")";
+      ctx.fill();
+      if (s >= 6) {
+        ctx.strokeStyle = "rgb(" + c.map(v => Math.round(v * 0.75)) + ")";
+        ctx.stroke();
+      }
+    }
+    ctx.globalAlpha = 1;
+  }
+
+  // The block under the mouse, the nearest face the point is in
+  function pick(px, py) {
+    for (let j = polys.length - 1; j >= 0; j--) {
+      const p = polys[j].p;
+      let pos = 0, neg = 0;
+      for (let k = 0; k < 4; k++) {
+        const a = p[k], b = p[(k+1) %% 4];
+        const cross = (b[0]-a[0])*(py-a[1]) - (b[1]-a[1])*(px-a[0]);
+        if (cross > 0) pos++;
+        if (cross < 0) neg++;
+      }
+      if (pos == 0 || neg == 0) return polys[j].i;
+    }
+    return -1;
+  }
+
+  let drag = null;
+  cv.addEventListener("pointerdown", e => {
+    drag = [e.clientX, e.clientY];
+    cv.setPointerCapture(e.pointerId);
+    cv.style.cursor = "grabbing";
+  });
+  cv.addEventListener("pointerup", e => { drag = null; cv.style.cursor = "grab"; });
+  cv.addEventListener("pointermove", e => {
+    if (drag) {
+      yaw -= (e.clientX - drag[0]) * 0.01;
+      pitch = Math.max(-1.55, Math.min(1.55, pitch + (e.clientY - drag[1]) * 0.01));
+      drag = [e.clientX, e.clientY];
+      draw();
+      return;
+    }
+    const r = cv.getBoundingClientRect(), i = pick(e.clientX - r.left, e.clientY - r.top);
+    info.textContent = i < 0 ? "" :
+      M[B[4*i+3]].n + " ~" + B[4*i] + " ~" + B[4*i+1] + " ~" + B[4*i+2];
+  });
+  cv.addEventListener("wheel", e => {
+    e.preventDefault();
+    zoom = Math.max(0.1, Math.min(50, zoom * Math.exp(-e.deltaY * 0.001)));
+    draw();
+  }, {passive: false});
+  slider.addEventListener("input", () => { maxY = +slider.value; draw(); });
+  window.addEventListener("resize", draw);
+  draw();
+})();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,58 @@
+package mcview
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+)
+
+// Test the data and the page of the HTML viewer
+func TestHTML(t *testing.T) {
+	cmds := "fill ~0 ~0 ~-2 ~2 ~0 ~-2 minecraft:stone\n" +
+		"setblock ~1 ~1 ~-2 minecraft:glass\n" +
+		"setblock ~1 ~2 ~-2 minecraft:air\n"
+	m, err := mcshapes.ReadModel(strings.NewReader(cmds))
+	if err != nil {
+		t.Fatalf("ReadModel: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, m, "wall <1>", DefaultPalette()); err != nil {
+		t.Fatalf("WriteHTML: %v", err)
+	}
+	page := buf.String()
+
+	if !strings.Contains(page, "<title>wall &lt;1&gt;</title>") {
+		t.Errorf("expected the title escaped")
+	}
+	if strings.Contains(page, "http://") || strings.Contains(page, "https://") ||
+		strings.Contains(page, "src=") {
+		t.Errorf("expected a page that needs no network")
+	}
+
+	// The data is the blocks without air, bottom up
+	i := strings.Index(page, "const D = ")
+	j := strings.Index(page[i:], ";\n")
+	if i < 0 || j < 0 {
+		t.Fatalf("no data in the page")
+	}
+	var data htmlData
+	if err := json.Unmarshal([]byte(page[i+len("const D = "):i+j]), &data); err != nil {
+		t.Fatalf("data: %v", err)
+	}
+	want := []int{0, 0, -2, 0, 1, 0, -2, 0, 2, 0, -2, 0, 1, 1, -2, 1}
+	if len(data.Blocks) != len(want) {
+		t.Fatalf("expected blocks %v, got %v", want, data.Blocks)
+	}
+	for k := range want {
+		if data.Blocks[k] != want[k] {
+			t.Fatalf("expected blocks %v, got %v", want, data.Blocks)
+		}
+	}
+	if len(data.Materials) != 2 || data.Materials[0].Name != "stone" ||
+		data.Materials[0].Color != "#7d7d7d" || !data.Materials[1].Translucent {
+		t.Errorf("expected stone and translucent glass, got %+v", data.Materials)
+	}
+}
@@ -0,0 +1,194 @@
+package mcview
+
+import (
+	"sort"
+	"strings"
+
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+)
+
+// Meshes
+//
+// A mesh is the outside of a model as colored faces, for 3D viewers and modeling programs.
+// Unlike the STL files of mcrender every block type has its own material with the color of
+// the block, see Palette, so a sea lantern sphere with a glass shell or a lapis and gold sign
+// look the way they do in the game.
+//
+// Faces between two blocks are not in the mesh, they can not be seen. Translucent blocks,
+// glass, water and ice, only hide the faces of the same block, the stone behind a glass wall
+// is still there. The faces of the same block that lie in one plane are merged into as few
+// rectangles as possible, so a 10 x 10 wall of stone has 6 faces and not 600.
+//
+// The mesh is in blocks, 1 unit is 1 block, with the player at 0,0,0. X is east, Y is up
+// and Z is south, the same as the game and the Y up axes of OBJ and glTF.
+
+// Mesh is the visible faces of a model, by material
+type Mesh struct {
+	palette   Palette
+	solids    bool
+	materials []string
+	faces     map[string][]face
+}
+
+// MeshOption is an option for NewMesh
+type MeshOption func(*Mesh)
+
+// face is a rectangle, the corners are counter clockwise seen from outside the block
+type face struct {
+	corners [4][3]int
+	normal  int // index into faceNormals
+}
+
+// The normals of the faces, -X, +X, -Y, +Y, -Z, +Z
+var faceNormals = [6][3]int{{-1, 0, 0}, {1, 0, 0}, {0, -1, 0}, {0, 1, 0}, {0, 0, -1}, {0, 0, 1}}
+
+// Alpha of the translucent blocks
+const alphaTranslucent = 0.5
+
+// NewMesh creates the mesh of a model
+func NewMesh(m *mcshapes.Model, opts ...MeshOption) *Mesh {
+	me := &Mesh{palette: DefaultPalette(), faces: make(map[string][]face)}
+	for _, opt := range opts {
+		opt(me)
+	}
+
+	// Material of every block that is not air
+	blocks := make(map[mcshapes.XYZ]string)
+	for _, xyz := range m.Positions() {
+		if b := m.Block(xyz); b != "" && !mcshapes.IsAir(b) {
+			blocks[xyz] = mcshapes.BlockName(b)
+		}
+	}
+	if len(blocks) == 0 {
+		return me
+	}
+	lo, hi := bounds(blocks)
+	get := func(p [3]int) string {
+		return blocks[mcshapes.XYZ{X: p[0], Y: p[1], Z: p[2]}]
+	}
+	los, his := [3]int{lo.X, lo.Y, lo.Z}, [3]int{hi.X, hi.Y, hi.Z}
+
+	// Each of the 6 sides, one slice of blocks at a time
+	for d := 0; d < 3; d++ {
+		u, v := (d+1)%3, (d+2)%3
+		nu, nv := his[u]-los[u]+1, his[v]-los[v]+1
+		for _, s := range []int{-1, 1} {
+			normal := 2*d + (s+1)/2
+			mask := make([]string, nu*nv)
+			for k := los[d]; k <= his[d]; k++ {
+				// The materials of the faces that can be seen in this slice
+				for j := 0; j < nv; j++ {
+					for i := 0; i < nu; i++ {
+						var p [3]int
+						p[d], p[u], p[v] = k, los[u]+i, los[v]+j
+						b := get(p)
+						mask[i+j*nu] = ""
+						if b == "" {
+							continue
+						}
+						p[d] += s
+						if n := get(p); n != "" && (n == b || !Translucent(n) && !me.solids) {
+							continue
+						}
+						mask[i+j*nu] = b
+					}
+				}
+				me.merge(mask, nu, nv, func(i, j, w, h int) face {
+					f := face{normal: normal}
+					uv := [4][2]int{{i, j}, {i + w, j}, {i + w, j + h}, {i, j + h}}
+					if s < 0 {
+						uv[1], uv[3] = uv[3], uv[1]
+					}
+					for c := range uv {
+						f.corners[c][d] = k + (s+1)/2
+						f.corners[c][u] = los[u] + uv[c][0]
+						f.corners[c][v] = los[v] + uv[c][1]
+					}
+					return f
+				})
+			}
+		}
+	}
+
+	for material := range me.faces {
+		me.materials = append(me.materials, material)
+	}
+	sort.Strings(me.materials)
+	return me
+}
+
+// WithMeshPalette sets the colors of the materials, the default is DefaultPalette
+func WithMeshPalette(palette Palette) MeshOption {
+	return func(me *Mesh) { me.palette = palette }
+}
+
+// WithMeshSolids makes each material a closed solid, for 3D printing one part per block type.
+// The faces between blocks of different types are in the mesh, for both blocks, and the
+// faces are not merged so every edge is shared by two faces of the solid.
+func WithMeshSolids() MeshOption {
+	return func(me *Mesh) { me.solids = true }
+}
+
+// merge adds the faces in a slice, mask is nu by nv materials. Rectangles of the same
+// material are grown along u and then along v, quad makes the face of a rectangle.
+func (me *Mesh) merge(mask []string, nu int, nv int, quad func(i, j, w, h int) face) {
+	for j := 0; j < nv; j++ {
+		for i := 0; i < nu; {
+			b := mask[i+j*nu]
+			if b == "" {
+				i++
+				continue
+			}
+			w := 1
+			for !me.solids && i+w < nu && mask[i+w+j*nu] == b {
+				w++
+			}
+			h := 1
+		grow:
+			for !me.solids && j+h < nv {
+				for x := i; x < i+w; x++ {
+					if mask[x+(j+h)*nu] != b {
+						break grow
+					}
+				}
+				h++
+			}
+			for y := j; y < j+h; y++ {
+				for x := i; x < i+w; x++ {
+					mask[x+y*nu] = ""
+				}
+			}
+			me.faces[b] = append(me.faces[b], quad(i, j, w, h))
+			i += w
+		}
+	}
+}
+
+// Materials returns the block names of the materials in the mesh, sorted
+func (me *Mesh) Materials() []string {
+	return me.materials
+}
+
+// Faces returns the number of faces of a material, or of all of them for ""
+func (me *Mesh) Faces(material string) int {
+	if material != "" {
+		return len(me.faces[material])
+	}
+	n := 0
+	for _, faces := range me.faces {
+		n += len(faces)
+	}
+	return n
+}
+
+// Translucent reports whether the blocks behind a block can be seen through it
+func Translucent(block string) bool {
+	name := mcshapes.BlockName(block)
+	return strings.HasSuffix(name, "glass") || strings.HasSuffix(name, "glass_pane") ||
+		name == "minecraft:water" || name == "minecraft:ice"
+}
+
+// materialName is a material name that all the formats accept, e.g. "polished_diorite"
+func materialName(block string) string {
+	return strings.Replace(strings.TrimPrefix(block, "minecraft:"), ":", "_", -1)
+}
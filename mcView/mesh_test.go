@@ -0,0 +1,73 @@
+package mcview
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+)
+
+// Test culling and merging of faces on a wall of stone with a window of glass in it
+func TestMesh(t *testing.T) {
+	cmds := "fill ~0 ~0 ~0 ~4 ~2 ~0 minecraft:stone\n" +
+		"setblock ~2 ~1 ~0 minecraft:glass\n"
+	m, err := mcshapes.ReadModel(strings.NewReader(cmds))
+	if err != nil {
+		t.Fatalf("ReadModel: %v", err)
+	}
+	me := NewMesh(m)
+
+	if got := me.Materials(); len(got) != 2 || got[0] != "minecraft:glass" ||
+		got[1] != "minecraft:stone" {
+		t.Errorf("expected glass and stone materials, got %v", got)
+	}
+	// The glass has its 2 faces to the outside and the stone 4 faces around it, the stone
+	// faces on each side are 4 rectangles around the window, and the ends, top and bottom
+	// are one each.
+	if n := me.Faces("minecraft:glass"); n != 2 {
+		t.Errorf("expected 2 glass faces, got %d", n)
+	}
+	if n := me.Faces("minecraft:stone"); n != 4+2*4+4 {
+		t.Errorf("expected 16 stone faces, got %d", n)
+	}
+
+	var obj, mtl bytes.Buffer
+	if err := me.WriteOBJ(&obj, "wall", "wall.mtl"); err != nil {
+		t.Fatalf("WriteOBJ: %v", err)
+	}
+	if n := strings.Count(obj.String(), "\nf "); n != 18 {
+		t.Errorf("expected 18 faces in the OBJ file, got %d", n)
+	}
+	if err := me.WriteMTL(&mtl); err != nil {
+		t.Fatalf("WriteMTL: %v", err)
+	}
+	if !strings.Contains(mtl.String(), "newmtl glass\n") || !strings.Contains(mtl.String(), "d 0.50") {
+		t.Errorf("expected a translucent glass material, got\n%v", mtl.String())
+	}
+
+	var glb bytes.Buffer
+	if err := me.WriteGLB(&glb, "wall"); err != nil {
+		t.Fatalf("WriteGLB: %v", err)
+	}
+	data := glb.Bytes()
+	var header [5]uint32
+	if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &header); err != nil {
+		t.Fatalf("read GLB header: %v", err)
+	}
+	if header[0] != glbMagic || header[1] != 2 || int(header[2]) != len(data) {
+		t.Fatalf("bad GLB header %x, %d bytes", header, len(data))
+	}
+	var g gltfFile
+	if err := json.Unmarshal(data[20:20+header[3]], &g); err != nil {
+		t.Fatalf("GLB JSON: %v", err)
+	}
+	if len(g.Materials) != 2 || g.Materials[0].AlphaMode != "BLEND" {
+		t.Errorf("expected translucent glass and stone materials, got %+v", g.Materials)
+	}
+	if n := g.Accessors[5].Count; n != 16*6 {
+		t.Errorf("expected %d stone indices, got %d", 16*6, n)
+	}
+}
@@ -0,0 +1,66 @@
+package mcview
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Wavefront OBJ
+//
+// The OBJ file has the faces and the MTL file the materials, the OBJ file names the MTL file
+// with mtllib so both must be in the same directory. Most 3D programs read them, Blender,
+// MeshLab, the slicers of 3D printers, ...
+
+// WriteOBJ writes the mesh as an OBJ file with the object name, mtllib is the name of the MTL
+// file, see WriteMTL.
+func (me *Mesh) WriteOBJ(w io.Writer, name string, mtllib string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# %v, %d faces, 1 unit is 1 block\n", name, me.Faces(""))
+	fmt.Fprintf(bw, "mtllib %v\n", mtllib)
+	fmt.Fprintf(bw, "o %v\n", name)
+	for _, n := range faceNormals {
+		fmt.Fprintf(bw, "vn %d %d %d\n", n[0], n[1], n[2])
+	}
+
+	// The corners are shared by the faces of all materials
+	index := make(map[[3]int]int)
+	for _, material := range me.materials {
+		for _, f := range me.faces[material] {
+			for _, c := range f.corners {
+				if _, ok := index[c]; !ok {
+					index[c] = len(index) + 1
+					fmt.Fprintf(bw, "v %d %d %d\n", c[0], c[1], c[2])
+				}
+			}
+		}
+	}
+
+	for _, material := range me.materials {
+		fmt.Fprintf(bw, "usemtl %v\n", materialName(material))
+		for _, f := range me.faces[material] {
+			n := f.normal + 1
+			fmt.Fprintf(bw, "f %d//%d %d//%d %d//%d %d//%d\n", index[f.corners[0]], n,
+				index[f.corners[1]], n, index[f.corners[2]], n, index[f.corners[3]], n)
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteMTL writes the materials of the mesh as an MTL file, one per block type with the
+// color of the block. Translucent blocks are half transparent.
+func (me *Mesh) WriteMTL(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, material := range me.materials {
+		c := me.palette.Color(material)
+		fmt.Fprintf(bw, "newmtl %v\n", materialName(material))
+		fmt.Fprintf(bw, "Kd %.4f %.4f %.4f\n", float64(c.R)/255, float64(c.G)/255,
+			float64(c.B)/255)
+		fmt.Fprintf(bw, "Ka 0 0 0\nKs 0 0 0\nillum 1\n")
+		if Translucent(material) {
+			fmt.Fprintf(bw, "d %.2f\n", alphaTranslucent)
+		}
+		fmt.Fprintln(bw)
+	}
+	return bw.Flush()
+}
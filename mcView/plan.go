@@ -0,0 +1,330 @@
+package mcview
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"strings"
+
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+)
+
+// Plan
+//
+// A plan draws a model seen from above one layer at a time, a build guide for building it by
+// hand. Each Y layer is a grid with one square per block, north at the top and east to the
+// right, the same as the map in the game. The rows and columns are labeled with the relative
+// coordinates of the functions, ~0 ~0 ~0 is the player who runs the function and is marked
+// with a red square on every layer. A legend lists the blocks in the layer and how many.
+//
+// Air is not drawn, a layer with only air is left out.
+
+// Plan draws the layers of a model
+type Plan struct {
+	model   *mcshapes.Model
+	palette Palette
+	cell    int
+	scale   int
+	lo, hi  mcshapes.XYZ
+}
+
+// PlanOption is an option for NewPlan
+type PlanOption func(*Plan)
+
+// Colors of the parts of the plan that are not blocks
+var (
+	colorBackground = color.RGBA{255, 255, 255, 255}
+	colorGrid       = color.RGBA{220, 220, 220, 255}
+	colorGrid5      = color.RGBA{170, 170, 170, 255}
+	colorText       = color.RGBA{0, 0, 0, 255}
+	colorPlayer     = color.RGBA{220, 0, 0, 255}
+)
+
+// Space around and between the parts of an image, in pixels
+const margin = 10
+
+// NewPlan creates a plan of a model
+func NewPlan(m *mcshapes.Model, opts ...PlanOption) *Plan {
+	p := &Plan{
+		model:   m,
+		palette: DefaultPalette(),
+		cell:    16,
+		scale:   2,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	// The grid always has the player in it
+	p.lo, p.hi = m.Bounds()
+	p.lo.X, p.lo.Z = imin(p.lo.X, 0), imin(p.lo.Z, 0)
+	p.hi.X, p.hi.Z = imax(p.hi.X, 0), imax(p.hi.Z, 0)
+	return p
+}
+
+// WithPalette sets the colors of the blocks, the default is DefaultPalette
+func WithPalette(palette Palette) PlanOption {
+	return func(p *Plan) { p.palette = palette }
+}
+
+// WithCellSize sets the size in pixels of one block, default 16
+func WithCellSize(cell int) PlanOption {
+	return func(p *Plan) { p.cell = cell }
+}
+
+// Ys returns the relative Y of the layers with blocks other than air, bottom up.
+func (p *Plan) Ys() []int {
+	seen := make(map[int]bool)
+	for _, xyz := range p.model.Positions() {
+		if !mcshapes.IsAir(p.model.Block(xyz)) {
+			seen[xyz.Y] = true
+		}
+	}
+	ys := make([]int, 0, len(seen))
+	for y := range seen {
+		ys = append(ys, y)
+	}
+	sort.Ints(ys)
+	return ys
+}
+
+// Layer draws the layer at relative Y y, with its legend.
+func (p *Plan) Layer(y int) *image.RGBA {
+	gw, gh := p.gridSize()
+	legend := p.legend([]int{y})
+	lw, lh := p.legendSize(legend)
+
+	w := gw + margin + lw + margin
+	h := imax(gh, lh+p.titleHeight()) + margin
+	img := newImage(w, h)
+	p.drawLayer(img, margin, margin, y)
+	p.drawLegend(img, gw+margin, margin+p.titleHeight(), legend)
+	return img
+}
+
+// Sheet draws all the layers on one page, bottom layer first, in rows of columns layers.
+// There is one legend for all the layers at the bottom of the page.
+func (p *Plan) Sheet(columns int) *image.RGBA {
+	ys := p.Ys()
+	if columns <= 0 {
+		columns = 1
+	}
+	rows := (len(ys) + columns - 1) / columns
+	if len(ys) < columns {
+		columns = imax(len(ys), 1)
+	}
+
+	gw, gh := p.gridSize()
+	legend := p.legend(ys)
+	lw, lh := p.legendSize(legend)
+
+	w := imax(columns*gw, lw+margin) + margin
+	h := rows*gh + lh + 2*margin
+	img := newImage(w, h)
+	for i, y := range ys {
+		p.drawLayer(img, margin+(i%columns)*gw, margin+(i/columns)*gh, y)
+	}
+	p.drawLegend(img, margin, margin+rows*gh, legend)
+	return img
+}
+
+// legendEntry is one block in a legend and the number of them
+type legendEntry struct {
+	block string
+	count int
+}
+
+// legend returns the blocks in the layers ys, most first
+func (p *Plan) legend(ys []int) []legendEntry {
+	in := make(map[int]bool)
+	for _, y := range ys {
+		in[y] = true
+	}
+	counts := make(map[string]int)
+	for _, xyz := range p.model.Positions() {
+		b := p.model.Block(xyz)
+		if in[xyz.Y] && !mcshapes.IsAir(b) {
+			counts[mcshapes.BlockName(b)]++
+		}
+	}
+	entries := make([]legendEntry, 0, len(counts))
+	for b, n := range counts {
+		entries = append(entries, legendEntry{block: b, count: n})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].count != entries[j].count {
+			return entries[i].count > entries[j].count
+		}
+		return entries[i].block < entries[j].block
+	})
+	return entries
+}
+
+// legendText is the text of a legend entry, e.g. "polished_diorite 72"
+func legendText(e legendEntry) string {
+	return fmt.Sprintf("%v %d", strings.TrimPrefix(e.block, "minecraft:"), e.count)
+}
+
+// lineHeight is the height of a line of text with the space under it
+func (p *Plan) lineHeight() int {
+	return TextHeight(p.scale) + 2*p.scale + 2
+}
+
+// titleHeight is the height of the title above a layer
+func (p *Plan) titleHeight() int {
+	return p.lineHeight() + p.scale
+}
+
+// legendSize returns the width and height of a legend
+func (p *Plan) legendSize(legend []legendEntry) (int, int) {
+	w := 0
+	for _, e := range legend {
+		w = imax(w, TextWidth(legendText(e), p.scale))
+	}
+	swatch := TextHeight(p.scale)
+	return swatch + margin + w, len(legend) * p.lineHeight()
+}
+
+// drawLegend draws a color square and the name and count of each block
+func (p *Plan) drawLegend(img *image.RGBA, x int, y int, legend []legendEntry) {
+	swatch := TextHeight(p.scale)
+	for i, e := range legend {
+		ly := y + i*p.lineHeight()
+		fillRect(img, x, ly, swatch, swatch, colorText)
+		fillRect(img, x+1, ly+1, swatch-2, swatch-2, p.palette.Color(e.block))
+		DrawText(img, x+swatch+margin, ly, legendText(e), colorText, p.scale)
+	}
+}
+
+// labelWidth is the width of the Z labels left of the grid
+func (p *Plan) labelWidth() int {
+	w := 0
+	for _, z := range p.labels(p.lo.Z, p.hi.Z) {
+		w = imax(w, TextWidth(fmt.Sprintf("~%d", z), p.scale))
+	}
+	return w + margin
+}
+
+// gridSize returns the width and height of a layer with its title and labels, and the
+// margin after it
+func (p *Plan) gridSize() (int, int) {
+	nx, nz := p.hi.X-p.lo.X+1, p.hi.Z-p.lo.Z+1
+	return p.labelWidth() + nx*p.cell + margin,
+		p.titleHeight() + p.lineHeight() + nz*p.cell + margin
+}
+
+// labels returns the coordinates from lo to hi that are labeled, every 5 and 0
+func (p *Plan) labels(lo int, hi int) []int {
+	// Labels must not run into each other
+	step := 5
+	for step*p.cell < TextWidth("~-000", p.scale)+p.scale {
+		step += 5
+	}
+	var labels []int
+	for v := lo; v <= hi; v++ {
+		if v%step == 0 {
+			labels = append(labels, v)
+		}
+	}
+	return labels
+}
+
+// drawLayer draws the title, labels and grid of the layer y with the top left corner at x0, y0
+func (p *Plan) drawLayer(img *image.RGBA, x0 int, y0 int, y int) {
+	n := 0
+	for _, e := range p.legend([]int{y}) {
+		n += e.count
+	}
+	DrawText(img, x0, y0, fmt.Sprintf("Y ~%d  %d blocks", y, n), colorText, p.scale)
+
+	// Top left corner of the grid
+	gx := x0 + p.labelWidth()
+	gy := y0 + p.titleHeight() + p.lineHeight()
+	nx, nz := p.hi.X-p.lo.X+1, p.hi.Z-p.lo.Z+1
+
+	for _, x := range p.labels(p.lo.X, p.hi.X) {
+		s := fmt.Sprintf("~%d", x)
+		cx := gx + (x-p.lo.X)*p.cell + p.cell/2 - TextWidth(s, p.scale)/2
+		DrawText(img, cx, gy-p.lineHeight(), s, colorText, p.scale)
+	}
+	for _, z := range p.labels(p.lo.Z, p.hi.Z) {
+		s := fmt.Sprintf("~%d", z)
+		cy := gy + (z-p.lo.Z)*p.cell + p.cell/2 - TextHeight(p.scale)/2
+		DrawText(img, gx-margin-TextWidth(s, p.scale), cy, s, colorText, p.scale)
+	}
+
+	// Grid lines, darker every 5 blocks from the player
+	for i := 0; i <= nx; i++ {
+		c := colorGrid
+		if (p.lo.X+i)%5 == 0 {
+			c = colorGrid5
+		}
+		fillRect(img, gx+i*p.cell, gy, 1, nz*p.cell+1, c)
+	}
+	for i := 0; i <= nz; i++ {
+		c := colorGrid
+		if (p.lo.Z+i)%5 == 0 {
+			c = colorGrid5
+		}
+		fillRect(img, gx, gy+i*p.cell, nx*p.cell+1, 1, c)
+	}
+
+	// The blocks, with a darker edge so blocks of the same color can be counted
+	for z := p.lo.Z; z <= p.hi.Z; z++ {
+		for x := p.lo.X; x <= p.hi.X; x++ {
+			b := p.model.Block(mcshapes.XYZ{X: x, Y: y, Z: z})
+			if b == "" || mcshapes.IsAir(b) {
+				continue
+			}
+			c := p.palette.Color(b)
+			px, py := gx+(x-p.lo.X)*p.cell, gy+(z-p.lo.Z)*p.cell
+			fillRect(img, px, py, p.cell+1, p.cell+1, shade(c, 0.6))
+			fillRect(img, px+1, py+1, p.cell-1, p.cell-1, c)
+		}
+	}
+
+	// The player
+	px, py := gx+(0-p.lo.X)*p.cell, gy+(0-p.lo.Z)*p.cell
+	for i := 0; i < 2; i++ {
+		fillRect(img, px+i, py+i, p.cell+1-2*i, 1, colorPlayer)
+		fillRect(img, px+i, py+p.cell-i, p.cell+1-2*i, 1, colorPlayer)
+		fillRect(img, px+i, py+i, 1, p.cell+1-2*i, colorPlayer)
+		fillRect(img, px+p.cell-i, py+i, 1, p.cell+1-2*i, colorPlayer)
+	}
+}
+
+// newImage creates an image with the background color
+func newImage(w int, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	fillRect(img, 0, 0, w, h, colorBackground)
+	return img
+}
+
+// shade returns a color made darker, or lighter, by a factor
+func shade(c color.RGBA, f float64) color.RGBA {
+	s := func(v uint8) uint8 {
+		x := float64(v) * f
+		if x > 255 {
+			return 255
+		}
+		return uint8(x)
+	}
+	return color.RGBA{R: s(c.R), G: s(c.G), B: s(c.B), A: c.A}
+}
+
+// imin returns the smaller of two ints
+func imin(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// imax returns the larger of two ints
+func imax(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,88 @@
+package mcview
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+)
+
+// Test drawing the layers of a small wall in front of the player
+func TestPlan(t *testing.T) {
+	cmds := "fill ~0 ~0 ~-2 ~4 ~1 ~-2 minecraft:sandstone\n" +
+		"fill ~1 ~1 ~-2 ~3 ~1 ~-2 minecraft:glass\n" +
+		"fill ~0 ~2 ~-2 ~4 ~2 ~-2 minecraft:air\n"
+	m, err := mcshapes.ReadModel(strings.NewReader(cmds))
+	if err != nil {
+		t.Fatalf("ReadModel: %v", err)
+	}
+	p := NewPlan(m, WithCellSize(10))
+	ys := p.Ys()
+	if len(ys) != 2 || ys[0] != 0 || ys[1] != 1 {
+		t.Fatalf("expected layers 0 and 1, the air layer left out, got %v", ys)
+	}
+
+	legend := p.legend([]int{1})
+	if len(legend) != 2 || legend[0].block != "minecraft:glass" || legend[0].count != 3 {
+		t.Errorf("expected 3 glass and 2 sandstone in layer 1, got %v", legend)
+	}
+
+	// The middle of the block at ~2 ~1 ~-2 is glass
+	img := p.Layer(1)
+	gx := margin + p.labelWidth()
+	gy := margin + p.titleHeight() + p.lineHeight()
+	x, z := gx+(2-p.lo.X)*p.cell+p.cell/2, gy+(-2-p.lo.Z)*p.cell+p.cell/2
+	if c := img.RGBAAt(x, z); c != p.palette.Color("minecraft:glass") {
+		t.Errorf("expected glass at ~2 ~1 ~-2, got %v", c)
+	}
+
+	sheet := p.Sheet(3)
+	if sheet.Bounds().Dx() < 2*(p.hi.X-p.lo.X+1)*p.cell {
+		t.Errorf("expected both layers side by side on the sheet, got %v", sheet.Bounds())
+	}
+}
+
+// Test the colors of blocks that are not in the palette
+func TestPaletteColor(t *testing.T) {
+	p := DefaultPalette()
+	if p.Color("minecraft:stone 4") != p["minecraft:polished_diorite"] {
+		t.Errorf("expected the old name of polished diorite to have its color")
+	}
+	if p.Color("minecraft:lime_concrete") != dyeColors["lime"] {
+		t.Errorf("expected lime concrete to be lime")
+	}
+	if p.Color("minecraft:dark_oak_fence") != woodColors["dark_oak"] {
+		t.Errorf("expected a dark oak fence to be dark oak")
+	}
+	if p.Color("mod:thing") != p.Color("mod:thing[a=b]") {
+		t.Errorf("expected the same color with and without block states")
+	}
+}
+
+// Test the text layers of a wall of stone and stone bricks
+func TestPlanText(t *testing.T) {
+	cmds := "fill ~0 ~0 ~-2 ~3 ~0 ~-2 minecraft:stone\n" +
+		"setblock ~4 ~0 ~-2 minecraft:stone_bricks\n" +
+		"setblock ~4 ~1 ~-2 minecraft:glass\n"
+	m, err := mcshapes.ReadModel(strings.NewReader(cmds))
+	if err != nil {
+		t.Fatalf("ReadModel: %v", err)
+	}
+	p := NewPlan(m)
+	glyphs := p.Glyphs()
+	if glyphs["minecraft:stone"] != 'S' || glyphs["minecraft:stone_bricks"] != 'B' ||
+		glyphs["minecraft:glass"] != 'G' {
+		t.Errorf("expected S, B and G, got %q", glyphs)
+	}
+
+	var buf bytes.Buffer
+	if err := p.WriteText(&buf, 0, false); err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) != 8 || lines[0] != "Y ~0  5 blocks" || lines[2] != "    S S S S B " ||
+		lines[4] != " ~0 @ . . . . " || lines[5] != "  S  stone 4" {
+		t.Errorf("unexpected layer, got\n%v", buf.String())
+	}
+}
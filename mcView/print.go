@@ -0,0 +1,253 @@
+package mcview
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+)
+
+// 3D printing
+//
+// A build is printed from a mesh made with WithMeshSolids, each block type is a closed solid
+// so it can be printed in its own color, the sea lantern shell and the glass inside of a
+// sphere, the letters and the back of a sign. Sizes are in millimeters, scale is the size of
+// one block, e.g. 2 mm makes a 10 block high wall 20 mm high. Printers build up along Z, so
+// the Y up of the game is turned to Z up, north is +Y.
+//    STL   one file per block type, loaded together into the slicer as parts of one object
+//    3MF   one file with all the block types as objects with their own color
+// The solid builds use a lot of filament or resin, Hollow takes out the inside.
+
+// WriteSTL writes the solid of one material as a binary STL file, scale is mm per block.
+func (me *Mesh) WriteSTL(w io.Writer, material string, scale float64) error {
+	faces := me.faces[material]
+	bw := bufio.NewWriter(w)
+	var header [80]byte
+	copy(header[:], fmt.Sprintf("mcFunctionDev %v", materialName(material)))
+	bw.Write(header[:])
+	binary.Write(bw, binary.LittleEndian, uint32(2*len(faces)))
+	for _, f := range faces {
+		n := zUp(faceNormals[f.normal], 1)
+		for _, t := range [2][3]int{{0, 1, 2}, {0, 2, 3}} {
+			tri := []float32{float32(n[0]), float32(n[1]), float32(n[2])}
+			for _, c := range t {
+				for _, v := range zUp(f.corners[c], scale) {
+					tri = append(tri, float32(v))
+				}
+			}
+			binary.Write(bw, binary.LittleEndian, tri)
+			binary.Write(bw, binary.LittleEndian, uint16(0))
+		}
+	}
+	return bw.Flush()
+}
+
+// Write3MF writes all the materials as a 3MF file, one object per material with the color
+// of the block, scale is mm per block.
+func (me *Mesh) Write3MF(w io.Writer, name string, scale float64) error {
+	z := zip.NewWriter(w)
+	files := []struct{ name, body string }{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+ <Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+ <Default Extension="model" ContentType="application/vnd.ms-package.3dmanufacturing-3dmodel+xml"/>
+</Types>
+`},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+ <Relationship Target="/3D/3dmodel.model" Id="rel0" Type="http://schemas.microsoft.com/3dmanufacturing/2013/01/3dmodel"/>
+</Relationships>
+`},
+	}
+	for _, file := range files {
+		fw, err := z.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, file.body); err != nil {
+			return err
+		}
+	}
+
+	fw, err := z.Create("3D/3dmodel.model")
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(fw)
+	fmt.Fprintf(bw, `<?xml version="1.0" encoding="UTF-8"?>
+<model unit="millimeter" xml:lang="en-US" xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02">
+ <metadata name="Title">%v</metadata>
+ <resources>
+  <basematerials id="1">
+`, xmlEscape(name))
+	for _, material := range me.materials {
+		c := me.palette.Color(material)
+		fmt.Fprintf(bw, "   <base name=\"%v\" displaycolor=\"#%02X%02X%02X\"/>\n",
+			xmlEscape(materialName(material)), c.R, c.G, c.B)
+	}
+	fmt.Fprintf(bw, "  </basematerials>\n")
+
+	for i, material := range me.materials {
+		fmt.Fprintf(bw, "  <object id=\"%d\" name=\"%v\" type=\"model\" pid=\"1\" pindex=\"%d\">\n",
+			i+2, xmlEscape(materialName(material)), i)
+		fmt.Fprintf(bw, "   <mesh>\n    <vertices>\n")
+		index := make(map[[3]int]int)
+		var order [][3]int
+		for _, f := range me.faces[material] {
+			for _, c := range f.corners {
+				if _, ok := index[c]; !ok {
+					index[c] = len(order)
+					order = append(order, c)
+				}
+			}
+		}
+		for _, c := range order {
+			v := zUp(c, scale)
+			fmt.Fprintf(bw, "     <vertex x=\"%v\" y=\"%v\" z=\"%v\"/>\n", v[0], v[1], v[2])
+		}
+		fmt.Fprintf(bw, "    </vertices>\n    <triangles>\n")
+		for _, f := range me.faces[material] {
+			for _, t := range [2][3]int{{0, 1, 2}, {0, 2, 3}} {
+				fmt.Fprintf(bw, "     <triangle v1=\"%d\" v2=\"%d\" v3=\"%d\"/>\n",
+					index[f.corners[t[0]]], index[f.corners[t[1]]], index[f.corners[t[2]]])
+			}
+		}
+		fmt.Fprintf(bw, "    </triangles>\n   </mesh>\n  </object>\n")
+	}
+	fmt.Fprintf(bw, " </resources>\n <build>\n")
+	for i := range me.materials {
+		fmt.Fprintf(bw, "  <item objectid=\"%d\"/>\n", i+2)
+	}
+	fmt.Fprintf(bw, " </build>\n</model>\n")
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return z.Close()
+}
+
+// zUp returns a corner or normal of the game, Y up, as printer coordinates, Z up, in mm
+func zUp(c [3]int, scale float64) [3]float64 {
+	return [3]float64{float64(c[0]) * scale, float64(-c[2]) * scale, float64(c[1]) * scale}
+}
+
+// xmlEscape escapes the characters that can not be in an XML attribute
+func xmlEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;").Replace(s)
+}
+
+// Hollow returns a copy of a model with the inside taken out, the blocks that have blocks of
+// the same type all around them for shell blocks in every direction. Each block type is
+// hollowed on its own so the parts printed in different colors keep their walls. With drain a hole is made from the
+// bottom of each space inside straight down, so resin or support material can get out.
+// Air is left out of the copy.
+func Hollow(m *mcshapes.Model, shell int, drain bool) *mcshapes.Model {
+	filled := make(map[mcshapes.XYZ]string)
+	for _, xyz := range m.Positions() {
+		if b := m.Block(xyz); b != "" && !mcshapes.IsAir(b) {
+			filled[xyz] = b
+		}
+	}
+	if shell < 1 {
+		shell = 1
+	}
+
+	inside := func(p mcshapes.XYZ) bool {
+		b := mcshapes.BlockName(filled[p])
+		for dx := -shell; dx <= shell; dx++ {
+			for dy := -shell; dy <= shell; dy++ {
+				for dz := -shell; dz <= shell; dz++ {
+					q := mcshapes.XYZ{X: p.X + dx, Y: p.Y + dy, Z: p.Z + dz}
+					if mcshapes.BlockName(filled[q]) != b {
+						return false
+					}
+				}
+			}
+		}
+		return true
+	}
+	removed := make(map[mcshapes.XYZ]bool)
+	for p := range filled {
+		if inside(p) {
+			removed[p] = true
+		}
+	}
+
+	if drain {
+		// Each space inside, lowest block first, gets one hole
+		cavities := make([]mcshapes.XYZ, 0, len(removed))
+		for p := range removed {
+			cavities = append(cavities, p)
+		}
+		sort.Slice(cavities, func(i, j int) bool {
+			a, b := cavities[i], cavities[j]
+			if a.Y != b.Y {
+				return a.Y < b.Y
+			}
+			if a.X != b.X {
+				return a.X < b.X
+			}
+			return a.Z < b.Z
+		})
+		seen := make(map[mcshapes.XYZ]bool)
+		for _, p := range cavities {
+			if seen[p] {
+				continue
+			}
+			// Mark the whole space, then drill down from its lowest block
+			stack := []mcshapes.XYZ{p}
+			seen[p] = true
+			for len(stack) > 0 {
+				q := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				for _, n := range faceNormals {
+					r := mcshapes.XYZ{X: q.X + n[0], Y: q.Y + n[1], Z: q.Z + n[2]}
+					if removed[r] && !seen[r] {
+						seen[r] = true
+						stack = append(stack, r)
+					}
+				}
+			}
+			for q := (mcshapes.XYZ{X: p.X, Y: p.Y - 1, Z: p.Z}); ; q.Y-- {
+				if _, ok := filled[q]; !ok {
+					break
+				}
+				removed[q] = true
+			}
+		}
+	}
+
+	h := mcshapes.NewModel()
+	for p, b := range filled {
+		if !removed[p] {
+			h.SetBlock(p, b)
+		}
+	}
+	return h
+}
+
+// Volume returns the volume of the solid of a material in cubic mm, scale is mm per block.
+func (me *Mesh) Volume(material string, scale float64) float64 {
+	// Divergence theorem with the field (0, y, 0), only the top and bottom faces count
+	v := 0.0
+	for _, f := range me.faces[material] {
+		if f.normal/2 != 1 {
+			continue
+		}
+		lo, hi := f.corners[0], f.corners[0]
+		for _, c := range f.corners {
+			for i := range c {
+				lo[i] = imin(lo[i], c[i])
+				hi[i] = imax(hi[i], c[i])
+			}
+		}
+		area := float64((hi[0] - lo[0]) * (hi[2] - lo[2]))
+		v += float64(faceNormals[f.normal][1]*f.corners[0][1]) * area
+	}
+	return math.Abs(v) * scale * scale * scale
+}
@@ -0,0 +1,78 @@
+package mcview
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+)
+
+// Test the 3D printing solids, hollowing and files on a cube of stone with a lapis top
+func TestPrint(t *testing.T) {
+	cmds := "fill ~0 ~0 ~0 ~4 ~4 ~4 minecraft:stone\n" +
+		"fill ~0 ~5 ~0 ~4 ~5 ~4 minecraft:lapis_block\n"
+	m, err := mcshapes.ReadModel(strings.NewReader(cmds))
+	if err != nil {
+		t.Fatalf("ReadModel: %v", err)
+	}
+
+	// Each block type is a closed solid, the stone has its top under the lapis
+	me := NewMesh(m, WithMeshSolids())
+	if v := me.Volume("minecraft:stone", 2); v != 125*8 {
+		t.Errorf("expected a stone volume of %d mm3, got %v", 125*8, v)
+	}
+	if v := me.Volume("minecraft:lapis_block", 1); v != 25 {
+		t.Errorf("expected a lapis volume of 25 mm3, got %v", v)
+	}
+
+	// The 3 x 3 x 3 inside of the stone is taken out, the lapis stays, and one block under it
+	// for the drain
+	h := Hollow(m, 1, true)
+	if n := h.Len(); n != 150-27-1 {
+		t.Errorf("expected %d blocks in the hollow cube, got %d", 150-27-1, n)
+	}
+	if b := h.Block(mcshapes.XYZ{X: 1, Y: 0, Z: 1}); b != "" {
+		t.Errorf("expected the drain hole at ~1 ~0 ~1, got %q", b)
+	}
+	hollow := NewMesh(h, WithMeshSolids())
+	if v := hollow.Volume("minecraft:stone", 1); v != 125-27-1 {
+		t.Errorf("expected a hollow stone volume of %d, got %v", 125-27-1, v)
+	}
+
+	var stl bytes.Buffer
+	if err := me.WriteSTL(&stl, "minecraft:lapis_block", 1); err != nil {
+		t.Fatalf("WriteSTL: %v", err)
+	}
+	// 25 top, 25 bottom and 20 side faces, 2 triangles each
+	if n := stl.Len(); n != 84+50*2*70 {
+		t.Errorf("expected %d bytes of STL, got %d", 84+50*2*70, n)
+	}
+
+	var tmf bytes.Buffer
+	if err := me.Write3MF(&tmf, "cube", 1); err != nil {
+		t.Fatalf("Write3MF: %v", err)
+	}
+	z, err := zip.NewReader(bytes.NewReader(tmf.Bytes()), int64(tmf.Len()))
+	if err != nil {
+		t.Fatalf("3MF zip: %v", err)
+	}
+	for _, f := range z.File {
+		if f.Name != "3D/3dmodel.model" {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %v: %v", f.Name, err)
+		}
+		model, _ := io.ReadAll(r)
+		if !strings.Contains(string(model), `<base name="lapis_block" displaycolor="#1E438C"/>`) ||
+			strings.Count(string(model), "<object ") != 2 {
+			t.Errorf("expected lapis and stone objects in the 3MF model")
+		}
+		return
+	}
+	t.Errorf("3MF has no 3D/3dmodel.model")
+}
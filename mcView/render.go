@@ -0,0 +1,320 @@
+package mcview
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+)
+
+// Rendering
+//
+// A view draws a picture of a model, so the output of a generator can be looked at without
+// the game or a 3D viewer. Every block is a cube in the color of the block, see Palette.
+//
+// Cameras
+//    iso, iso_se   isometric, looking down from the south east
+//    iso_sw        isometric from the south west
+//    iso_nw        isometric from the north west
+//    iso_ne        isometric from the north east
+//    top           straight down, north at the top
+//    north         the north side, straight on (looking south)
+//    south         the south side, straight on (looking north)
+//    east          the east side, straight on (looking west)
+//    west          the west side, straight on (looking east)
+// In the isometric views the tops of the blocks are drawn in their color and the two sides
+// darker, so the shape can be seen. In the straight on views blocks further from the camera
+// are drawn darker.
+
+// Cameras is the list of camera names, see NewView
+var Cameras = []string{"iso", "iso_se", "iso_sw", "iso_nw", "iso_ne", "top", "north", "south",
+	"east", "west"}
+
+// View renders models from one camera
+type View struct {
+	palette Palette
+	camera  string
+	size    int
+}
+
+// ViewOption is an option for NewView
+type ViewOption func(*View)
+
+// Shading of the faces of a block in the isometric views
+const (
+	shadeTop   = 1.0
+	shadeRight = 0.8
+	shadeLeft  = 0.62
+)
+
+// NewView creates a view
+func NewView(opts ...ViewOption) *View {
+	v := &View{
+		palette: DefaultPalette(),
+		camera:  "iso",
+		size:    16,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// WithViewPalette sets the colors of the blocks, the default is DefaultPalette
+func WithViewPalette(palette Palette) ViewOption {
+	return func(v *View) { v.palette = palette }
+}
+
+// WithViewCamera sets the camera, one of Cameras, the default is "iso"
+func WithViewCamera(camera string) ViewOption {
+	return func(v *View) { v.camera = camera }
+}
+
+// WithViewBlockSize sets the width in pixels of one block, default 16
+func WithViewBlockSize(size int) ViewOption {
+	return func(v *View) { v.size = size }
+}
+
+// Render draws a model. Air is not drawn.
+func (v *View) Render(m *mcshapes.Model) (*image.RGBA, error) {
+	turn, ok := cameraTurns[v.camera]
+	if !ok {
+		return nil, fmt.Errorf("unknown camera %q", v.camera)
+	}
+
+	// The blocks turned so the camera is at +X +Z (isometric) or +Z (straight on)
+	blocks := make(map[mcshapes.XYZ]string)
+	for _, xyz := range m.Positions() {
+		if b := m.Block(xyz); !mcshapes.IsAir(b) {
+			blocks[turn(xyz)] = b
+		}
+	}
+	if len(blocks) == 0 {
+		return newImage(2*margin, 2*margin), nil
+	}
+
+	switch v.camera {
+	case "top":
+		return v.renderTop(blocks), nil
+	case "north", "south", "east", "west":
+		return v.renderSide(blocks), nil
+	}
+	return v.renderIso(blocks), nil
+}
+
+// Turns of the model for each camera
+var cameraTurns = map[string]func(mcshapes.XYZ) mcshapes.XYZ{
+	"iso":    func(p mcshapes.XYZ) mcshapes.XYZ { return p },
+	"iso_se": func(p mcshapes.XYZ) mcshapes.XYZ { return p },
+	"iso_sw": func(p mcshapes.XYZ) mcshapes.XYZ { return mcshapes.XYZ{X: p.Z, Y: p.Y, Z: -p.X} },
+	"iso_nw": func(p mcshapes.XYZ) mcshapes.XYZ { return mcshapes.XYZ{X: -p.X, Y: p.Y, Z: -p.Z} },
+	"iso_ne": func(p mcshapes.XYZ) mcshapes.XYZ { return mcshapes.XYZ{X: -p.Z, Y: p.Y, Z: p.X} },
+	"top":    func(p mcshapes.XYZ) mcshapes.XYZ { return p },
+	"south":  func(p mcshapes.XYZ) mcshapes.XYZ { return p },
+	"north":  func(p mcshapes.XYZ) mcshapes.XYZ { return mcshapes.XYZ{X: -p.X, Y: p.Y, Z: -p.Z} },
+	"east":   func(p mcshapes.XYZ) mcshapes.XYZ { return mcshapes.XYZ{X: -p.Z, Y: p.Y, Z: p.X} },
+	"west":   func(p mcshapes.XYZ) mcshapes.XYZ { return mcshapes.XYZ{X: p.Z, Y: p.Y, Z: -p.X} },
+}
+
+// bounds returns the lowest and highest corners of the blocks
+func bounds(blocks map[mcshapes.XYZ]string) (mcshapes.XYZ, mcshapes.XYZ) {
+	first := true
+	var lo, hi mcshapes.XYZ
+	for p := range blocks {
+		if first {
+			lo, hi, first = p, p, false
+			continue
+		}
+		lo = mcshapes.XYZ{X: imin(lo.X, p.X), Y: imin(lo.Y, p.Y), Z: imin(lo.Z, p.Z)}
+		hi = mcshapes.XYZ{X: imax(hi.X, p.X), Y: imax(hi.Y, p.Y), Z: imax(hi.Z, p.Z)}
+	}
+	return lo, hi
+}
+
+// renderIso draws the blocks isometric, with the camera at +X +Z looking down. The blocks
+// are drawn back to front so the nearer blocks cover the ones behind them, and the faces
+// against another block are not drawn at all.
+func (v *View) renderIso(blocks map[mcshapes.XYZ]string) *image.RGBA {
+	a := float64(v.size) / 2
+	project := func(x, y, z int) (float64, float64) {
+		return float64(x-z) * a, float64(x+z)*a/2 - float64(y)*a
+	}
+
+	// The image holds the corners of the box around all the blocks
+	lo, hi := bounds(blocks)
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, x := range []int{lo.X, hi.X + 1} {
+		for _, y := range []int{lo.Y, hi.Y + 1} {
+			for _, z := range []int{lo.Z, hi.Z + 1} {
+				sx, sy := project(x, y, z)
+				minX, maxX = math.Min(minX, sx), math.Max(maxX, sx)
+				minY, maxY = math.Min(minY, sy), math.Max(maxY, sy)
+			}
+		}
+	}
+	img := newImage(int(maxX-minX)+2*margin+1, int(maxY-minY)+2*margin+1)
+	ox, oy := float64(margin)-minX, float64(margin)-minY
+	corner := func(x, y, z int) [2]float64 {
+		sx, sy := project(x, y, z)
+		return [2]float64{sx + ox, sy + oy}
+	}
+
+	order := make([]mcshapes.XYZ, 0, len(blocks))
+	for p := range blocks {
+		order = append(order, p)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		pi, pj := order[i], order[j]
+		if di, dj := pi.X+pi.Y+pi.Z, pj.X+pj.Y+pj.Z; di != dj {
+			return di < dj
+		}
+		if pi.Y != pj.Y {
+			return pi.Y < pj.Y
+		}
+		return pi.X < pj.X
+	})
+
+	covered := func(x, y, z int) bool {
+		_, ok := blocks[mcshapes.XYZ{X: x, Y: y, Z: z}]
+		return ok
+	}
+	outline := v.size >= 8
+	for _, p := range order {
+		c := v.palette.Color(blocks[p])
+		x, y, z := p.X, p.Y, p.Z
+		faces := []struct {
+			hidden  bool
+			shade   float64
+			corners [][2]float64
+		}{
+			{covered(x, y+1, z), shadeTop, [][2]float64{corner(x, y+1, z),
+				corner(x+1, y+1, z), corner(x+1, y+1, z+1), corner(x, y+1, z+1)}},
+			{covered(x+1, y, z), shadeRight, [][2]float64{corner(x+1, y, z),
+				corner(x+1, y+1, z), corner(x+1, y+1, z+1), corner(x+1, y, z+1)}},
+			{covered(x, y, z+1), shadeLeft, [][2]float64{corner(x, y, z+1),
+				corner(x+1, y, z+1), corner(x+1, y+1, z+1), corner(x, y+1, z+1)}},
+		}
+		for _, f := range faces {
+			if f.hidden {
+				continue
+			}
+			fillPolygon(img, f.corners, shade(c, f.shade))
+			if outline {
+				drawPolygon(img, f.corners, shade(c, f.shade*0.75))
+			}
+		}
+	}
+	return img
+}
+
+// renderTop draws the blocks seen from straight above, the highest block of each column.
+// Lower blocks are darker.
+func (v *View) renderTop(blocks map[mcshapes.XYZ]string) *image.RGBA {
+	lo, hi := bounds(blocks)
+	img := newImage((hi.X-lo.X+1)*v.size+2*margin, (hi.Z-lo.Z+1)*v.size+2*margin)
+	for z := lo.Z; z <= hi.Z; z++ {
+		for x := lo.X; x <= hi.X; x++ {
+			for y := hi.Y; y >= lo.Y; y-- {
+				b, ok := blocks[mcshapes.XYZ{X: x, Y: y, Z: z}]
+				if !ok {
+					continue
+				}
+				f := depthShade(y-lo.Y, hi.Y-lo.Y)
+				v.drawSquare(img, margin+(x-lo.X)*v.size, margin+(z-lo.Z)*v.size,
+					shade(v.palette.Color(b), f))
+				break
+			}
+		}
+	}
+	return img
+}
+
+// renderSide draws the blocks seen straight on from +Z, the nearest block of each row.
+// Blocks further away are darker.
+func (v *View) renderSide(blocks map[mcshapes.XYZ]string) *image.RGBA {
+	lo, hi := bounds(blocks)
+	img := newImage((hi.X-lo.X+1)*v.size+2*margin, (hi.Y-lo.Y+1)*v.size+2*margin)
+	for y := lo.Y; y <= hi.Y; y++ {
+		for x := lo.X; x <= hi.X; x++ {
+			for z := hi.Z; z >= lo.Z; z-- {
+				b, ok := blocks[mcshapes.XYZ{X: x, Y: y, Z: z}]
+				if !ok {
+					continue
+				}
+				f := depthShade(z-lo.Z, hi.Z-lo.Z)
+				v.drawSquare(img, margin+(x-lo.X)*v.size, margin+(hi.Y-y)*v.size,
+					shade(v.palette.Color(b), f))
+				break
+			}
+		}
+	}
+	return img
+}
+
+// depthShade returns the shade of a block d from the back of a model n deep, 1 at the
+// front down to 0.6 at the back
+func depthShade(d int, n int) float64 {
+	if n == 0 {
+		return 1
+	}
+	return 0.6 + 0.4*float64(d)/float64(n)
+}
+
+// drawSquare draws one block in the straight on views, with a darker edge
+func (v *View) drawSquare(img *image.RGBA, x int, y int, c color.RGBA) {
+	if v.size >= 8 {
+		fillRect(img, x, y, v.size, v.size, shade(c, 0.75))
+		fillRect(img, x+1, y+1, v.size-2, v.size-2, c)
+		return
+	}
+	fillRect(img, x, y, v.size, v.size, c)
+}
+
+// fillPolygon fills a convex polygon, a pixel is filled if its center is inside
+func fillPolygon(img *image.RGBA, corners [][2]float64, c color.RGBA) {
+	top, bottom := math.Inf(1), math.Inf(-1)
+	for _, p := range corners {
+		top, bottom = math.Min(top, p[1]), math.Max(bottom, p[1])
+	}
+	for py := int(math.Floor(top)); py <= int(math.Ceil(bottom)); py++ {
+		// Where the edges cross the middle of the row of pixels
+		cy := float64(py) + 0.5
+		left, right := math.Inf(1), math.Inf(-1)
+		for i, p := range corners {
+			q := corners[(i+1)%len(corners)]
+			if (p[1] <= cy) == (q[1] <= cy) {
+				continue
+			}
+			x := p[0] + (cy-p[1])*(q[0]-p[0])/(q[1]-p[1])
+			left, right = math.Min(left, x), math.Max(right, x)
+		}
+		for px := int(math.Ceil(left - 0.5)); float64(px)+0.5 <= right; px++ {
+			if image.Pt(px, py).In(img.Bounds()) {
+				img.SetRGBA(px, py, c)
+			}
+		}
+	}
+}
+
+// drawPolygon draws the edges of a polygon one pixel wide
+func drawPolygon(img *image.RGBA, corners [][2]float64, c color.RGBA) {
+	for i, p := range corners {
+		q := corners[(i+1)%len(corners)]
+		n := int(math.Max(math.Abs(q[0]-p[0]), math.Abs(q[1]-p[1])))
+		for s := 0; s <= n; s++ {
+			t := 0.0
+			if n > 0 {
+				t = float64(s) / float64(n)
+			}
+			px := int(math.Floor(p[0] + t*(q[0]-p[0])))
+			py := int(math.Floor(p[1] + t*(q[1]-p[1])))
+			if image.Pt(px, py).In(img.Bounds()) {
+				img.SetRGBA(px, py, c)
+			}
+		}
+	}
+}
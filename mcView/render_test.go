@@ -0,0 +1,62 @@
+package mcview
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+)
+
+// Test the cameras on a column of glass on stone, with the stone colored red
+func TestRender(t *testing.T) {
+	cmds := "fill ~0 ~0 ~-2 ~0 ~0 ~-2 minecraft:stone\n" +
+		"fill ~0 ~1 ~-2 ~0 ~1 ~-2 minecraft:glass\n"
+	m, err := mcshapes.ReadModel(strings.NewReader(cmds))
+	if err != nil {
+		t.Fatalf("ReadModel: %v", err)
+	}
+	palette := DefaultPalette()
+	if err := palette.SetColor("minecraft:stone", "#ff0000"); err != nil {
+		t.Fatalf("SetColor: %v", err)
+	}
+	if err := palette.SetColor("minecraft:stone", "red"); err == nil {
+		t.Errorf("expected an error for a color that is not #rrggbb")
+	}
+
+	for _, camera := range Cameras {
+		v := NewView(WithViewCamera(camera), WithViewBlockSize(10), WithViewPalette(palette))
+		img, err := v.Render(m)
+		if err != nil {
+			t.Fatalf("Render %v: %v", camera, err)
+		}
+
+		// Seen from the top there is only glass, from the sides the stone is under it
+		w, h := img.Bounds().Dx(), img.Bounds().Dy()
+		switch camera {
+		case "top":
+			if w != 10+2*margin || h != 10+2*margin {
+				t.Errorf("top: expected one block, got %v", img.Bounds())
+			}
+			if c := img.RGBAAt(w/2, h/2); c != palette.Color("minecraft:glass") {
+				t.Errorf("top: expected glass, got %v", c)
+			}
+		case "north", "south", "east", "west":
+			if w != 10+2*margin || h != 20+2*margin {
+				t.Errorf("%v: expected two blocks, got %v", camera, img.Bounds())
+			}
+			if c := img.RGBAAt(w/2, h-margin-5); c != (color.RGBA{255, 0, 0, 255}) {
+				t.Errorf("%v: expected red stone at the bottom, got %v", camera, c)
+			}
+		default:
+			// The top of the glass is in the middle near the top
+			if c := img.RGBAAt(w/2, margin+3); c != palette.Color("minecraft:glass") {
+				t.Errorf("%v: expected the top of the glass, got %v", camera, c)
+			}
+		}
+	}
+
+	if _, err := NewView(WithViewCamera("below")).Render(m); err == nil {
+		t.Errorf("expected an error for an unknown camera")
+	}
+}
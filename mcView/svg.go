@@ -0,0 +1,320 @@
+package mcview
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+)
+
+// Blueprints
+//
+// A blueprint is a drawing of a model as an SVG file, for build plans in shared documents.
+// SVG stays sharp at any size and the documents show it as it is. Every block is a square in
+// its color, see Palette, with the block and its relative coordinates shown when the mouse
+// is over it.
+//
+// Views
+//    plan          from above, north at the top, the highest block of each column
+//    south         south elevation, the south side seen straight on (looking north)
+//    north         north elevation (looking south)
+//    east          east elevation (looking west)
+//    west          west elevation (looking east)
+//    x=N, y=N, z=N section, only the blocks in the plane at relative x, y or z N, e.g. y=0 is
+//                  the layer the player stands in. x sections are seen from the east, z
+//                  sections from the south and y sections from above.
+// In the plan and the elevations blocks further back are drawn darker. The relative
+// coordinates are along the edges, every 5 blocks, and the size of the blocks in the view is
+// marked with dimension lines. The player, ~0 ~0 ~0, is a red circle.
+
+// Blueprint draws views of a model
+type Blueprint struct {
+	model   *mcshapes.Model
+	palette Palette
+	cell    int
+}
+
+// BlueprintOption is an option for NewBlueprint
+type BlueprintOption func(*Blueprint)
+
+// blueprintView is how a view sees the blocks. Column u and row v of the drawing are the
+// axes u and v of the blocks times their signs, the block nearest the viewer along axis d
+// is drawn, unless the view is a section at d == at.
+type blueprintView struct {
+	title      string
+	u, v, d    int // 0 X, 1 Y, 2 Z
+	us, vs, ds int
+	section    bool
+	at         int
+}
+
+// The views, without the sections
+var blueprintViews = map[string]blueprintView{
+	"plan":  {title: "plan", u: 0, v: 2, d: 1, us: 1, vs: 1, ds: 1},
+	"south": {title: "south elevation", u: 0, v: 1, d: 2, us: 1, vs: -1, ds: 1},
+	"north": {title: "north elevation", u: 0, v: 1, d: 2, us: -1, vs: -1, ds: -1},
+	"east":  {title: "east elevation", u: 2, v: 1, d: 0, us: -1, vs: -1, ds: 1},
+	"west":  {title: "west elevation", u: 2, v: 1, d: 0, us: 1, vs: -1, ds: -1},
+}
+
+// BlueprintViews is the list of views that are not sections, see NewBlueprint
+var BlueprintViews = []string{"plan", "south", "north", "east", "west"}
+
+// Sizes of the parts of a blueprint in pixels
+const (
+	svgFont   = 12
+	svgLine   = 18
+	svgDimGap = 14
+)
+
+// NewBlueprint creates a blueprint of a model
+func NewBlueprint(m *mcshapes.Model, opts ...BlueprintOption) *Blueprint {
+	b := &Blueprint{model: m, palette: DefaultPalette(), cell: 16}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// WithBlueprintPalette sets the colors of the blocks, the default is DefaultPalette
+func WithBlueprintPalette(palette Palette) BlueprintOption {
+	return func(b *Blueprint) { b.palette = palette }
+}
+
+// WithBlueprintCellSize sets the size in pixels of one block, default 16
+func WithBlueprintCellSize(cell int) BlueprintOption {
+	return func(b *Blueprint) { b.cell = cell }
+}
+
+// parseView returns the view for a view name, see Blueprint
+func parseView(name string) (blueprintView, error) {
+	if v, ok := blueprintViews[name]; ok {
+		return v, nil
+	}
+	axis, at, ok := strings.Cut(name, "=")
+	n, err := strconv.Atoi(strings.TrimPrefix(at, "~"))
+	if !ok || err != nil {
+		return blueprintView{}, fmt.Errorf("unknown view %q, one of %v or a section x=N, y=N, z=N",
+			name, strings.Join(BlueprintViews, " "))
+	}
+	var v blueprintView
+	switch axis {
+	case "x":
+		v = blueprintViews["east"]
+	case "y":
+		v = blueprintViews["plan"]
+	case "z":
+		v = blueprintViews["south"]
+	default:
+		return blueprintView{}, fmt.Errorf("unknown section %q, x=N, y=N or z=N", name)
+	}
+	v.title = fmt.Sprintf("section %v = ~%d", axis, n)
+	v.section, v.at = true, n
+	return v, nil
+}
+
+// svgCell is a block drawn in a view
+type svgCell struct {
+	block string
+	xyz   mcshapes.XYZ
+	depth int
+}
+
+// WriteSVG writes one view of the blueprint as an SVG file, name is the title of the drawing.
+func (b *Blueprint) WriteSVG(w io.Writer, name string, view string) error {
+	v, err := parseView(view)
+	if err != nil {
+		return err
+	}
+	get := func(p mcshapes.XYZ, axis int) int {
+		return [3]int{p.X, p.Y, p.Z}[axis]
+	}
+
+	// The nearest block of each column and row of the drawing
+	cells := make(map[[2]int]svgCell)
+	for _, p := range b.model.Positions() {
+		block := b.model.Block(p)
+		if block == "" || mcshapes.IsAir(block) {
+			continue
+		}
+		d := v.ds * get(p, v.d)
+		if v.section && get(p, v.d) != v.at {
+			continue
+		}
+		k := [2]int{v.us * get(p, v.u), v.vs * get(p, v.v)}
+		if c, ok := cells[k]; !ok || d > c.depth {
+			cells[k] = svgCell{block: block, xyz: p, depth: d}
+		}
+	}
+
+	// The drawing always has the player in it, column and row 0
+	ulo, uhi, vlo, vhi := 0, 0, 0, 0
+	blo, bhi := [2]int{}, [2]int{}
+	dlo, dhi := 0, 0
+	first := true
+	for k, c := range cells {
+		ulo, uhi = imin(ulo, k[0]), imax(uhi, k[0])
+		vlo, vhi = imin(vlo, k[1]), imax(vhi, k[1])
+		if first {
+			blo, bhi, dlo, dhi, first = k, k, c.depth, c.depth, false
+		}
+		blo = [2]int{imin(blo[0], k[0]), imin(blo[1], k[1])}
+		bhi = [2]int{imax(bhi[0], k[0]), imax(bhi[1], k[1])}
+		dlo, dhi = imin(dlo, c.depth), imax(dhi, c.depth)
+	}
+
+	// Layout, title, width dimension, column labels, then the grid with the height
+	// dimension and the row labels on the left, then the legend
+	labelW := 0
+	for r := vlo; r <= vhi; r++ {
+		labelW = imax(labelW, len(fmt.Sprintf("~%d", v.vs*r)))
+	}
+	cell := b.cell
+	gx := margin + svgLine + svgDimGap + labelW*svgFont*6/10 + margin
+	gy := margin + 2*svgLine + svgDimGap + svgLine
+	nu, nv := uhi-ulo+1, vhi-vlo+1
+	legend := b.svgLegend(cells)
+	width := imax(gx+nu*cell+margin, margin+30*svgFont*6/10)
+	height := gy + nv*cell + margin + len(legend)*svgLine + margin
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(bw, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" "+
+		"viewBox=\"0 0 %d %d\" font-family=\"monospace\" font-size=\"%d\">\n",
+		width, height, width, height, svgFont)
+	fmt.Fprintf(bw, "<rect width=\"%d\" height=\"%d\" fill=\"#ffffff\"/>\n", width, height)
+	fmt.Fprintf(bw, "<text x=\"%d\" y=\"%d\" font-weight=\"bold\">%v %v</text>\n", margin,
+		margin+svgFont, xmlEscape(name), v.title)
+
+	// Labels every 5 blocks, and a darker grid line there
+	colX := func(u int) int { return gx + (u-ulo)*cell }
+	rowY := func(r int) int { return gy + (r-vlo)*cell }
+	step := 5
+	for step*cell < 5*svgFont*6/10+4 {
+		step += 5
+	}
+	for u := ulo; u <= uhi; u++ {
+		if (v.us*u)%step == 0 {
+			fmt.Fprintf(bw, "<text x=\"%d\" y=\"%d\" text-anchor=\"middle\">~%d</text>\n",
+				colX(u)+cell/2, gy-4, v.us*u)
+		}
+	}
+	for r := vlo; r <= vhi; r++ {
+		if (v.vs*r)%step == 0 {
+			fmt.Fprintf(bw, "<text x=\"%d\" y=\"%d\" text-anchor=\"end\">~%d</text>\n",
+				gx-margin/2, rowY(r)+cell/2+svgFont/2-2, v.vs*r)
+		}
+	}
+	fmt.Fprintf(bw, "<g stroke-width=\"1\">\n")
+	for u := ulo; u <= uhi+1; u++ {
+		c := "#dcdcdc"
+		if (v.us*u)%5 == 0 {
+			c = "#aaaaaa"
+		}
+		fmt.Fprintf(bw, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"%v\"/>\n",
+			colX(u), rowY(vlo), colX(u), rowY(vhi+1), c)
+	}
+	for r := vlo; r <= vhi+1; r++ {
+		c := "#dcdcdc"
+		if (v.vs*r)%5 == 0 {
+			c = "#aaaaaa"
+		}
+		fmt.Fprintf(bw, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"%v\"/>\n",
+			colX(ulo), rowY(r), colX(uhi+1), rowY(r), c)
+	}
+	fmt.Fprintf(bw, "</g>\n")
+
+	// The blocks, drawn in order so the files are the same every time
+	keys := make([][2]int, 0, len(cells))
+	for k := range cells {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][1] != keys[j][1] {
+			return keys[i][1] < keys[j][1]
+		}
+		return keys[i][0] < keys[j][0]
+	})
+	fmt.Fprintf(bw, "<g stroke-width=\"1\">\n")
+	for _, k := range keys {
+		c := cells[k]
+		col := shade(b.palette.Color(c.block), depthShade(c.depth-dlo, dhi-dlo))
+		fmt.Fprintf(bw, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" "+
+			"fill=\"#%02x%02x%02x\" stroke=\"#%02x%02x%02x\">"+
+			"<title>%v ~%d ~%d ~%d</title></rect>\n",
+			colX(k[0]), rowY(k[1]), cell, cell, col.R, col.G, col.B,
+			shade(col, 0.75).R, shade(col, 0.75).G, shade(col, 0.75).B,
+			xmlEscape(strings.TrimPrefix(c.block, "minecraft:")), c.xyz.X, c.xyz.Y, c.xyz.Z)
+	}
+	fmt.Fprintf(bw, "</g>\n")
+
+	// The player, a red circle in column and row 0
+	fmt.Fprintf(bw, "<circle cx=\"%d\" cy=\"%d\" r=\"%d\" fill=\"none\" stroke=\"#dc0000\" "+
+		"stroke-width=\"2\"><title>player ~0 ~0 ~0</title></circle>\n",
+		colX(0)+cell/2, rowY(0)+cell/2, imax(cell/2-1, 2))
+
+	// Dimensions of the blocks, across the top and down the left
+	if len(cells) > 0 {
+		x1, x2 := colX(blo[0]), colX(bhi[0]+1)
+		y := margin + 2*svgLine + svgDimGap/2
+		fmt.Fprintf(bw, "<g stroke=\"#000000\" stroke-width=\"1\">\n")
+		fmt.Fprintf(bw, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\"/>\n", x1, y, x2, y)
+		fmt.Fprintf(bw, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\"/>\n", x1, y-4, x1, y+4)
+		fmt.Fprintf(bw, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\"/>\n", x2, y-4, x2, y+4)
+		y1, y2 := rowY(blo[1]), rowY(bhi[1]+1)
+		x := margin + svgLine + svgDimGap/2
+		fmt.Fprintf(bw, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\"/>\n", x, y1, x, y2)
+		fmt.Fprintf(bw, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\"/>\n", x-4, y1, x+4, y1)
+		fmt.Fprintf(bw, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\"/>\n", x-4, y2, x+4, y2)
+		fmt.Fprintf(bw, "</g>\n")
+		fmt.Fprintf(bw, "<text x=\"%d\" y=\"%d\" text-anchor=\"middle\">%v</text>\n",
+			(x1+x2)/2, y-svgDimGap/2-2, blocks(bhi[0]-blo[0]+1))
+		fmt.Fprintf(bw, "<text x=\"%d\" y=\"%d\" text-anchor=\"middle\" "+
+			"transform=\"rotate(-90 %d %d)\">%v</text>\n", x-svgDimGap/2-2, (y1+y2)/2,
+			x-svgDimGap/2-2, (y1+y2)/2, blocks(bhi[1]-blo[1]+1))
+	}
+
+	// Legend
+	ly := gy + nv*cell + margin
+	for i, e := range legend {
+		c := b.palette.Color(e.block)
+		fmt.Fprintf(bw, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" "+
+			"fill=\"#%02x%02x%02x\" stroke=\"#000000\"/>\n", margin, ly+i*svgLine, svgFont,
+			svgFont, c.R, c.G, c.B)
+		fmt.Fprintf(bw, "<text x=\"%d\" y=\"%d\">%v</text>\n", margin+svgFont+margin,
+			ly+i*svgLine+svgFont-2, xmlEscape(legendText(e)))
+	}
+	fmt.Fprintf(bw, "</svg>\n")
+	return bw.Flush()
+}
+
+// blocks returns a number of blocks as text, "1 block" or "5 blocks"
+func blocks(n int) string {
+	if n == 1 {
+		return "1 block"
+	}
+	return fmt.Sprintf("%d blocks", n)
+}
+
+// svgLegend returns the blocks seen in a view, most first
+func (b *Blueprint) svgLegend(cells map[[2]int]svgCell) []legendEntry {
+	counts := make(map[string]int)
+	for _, c := range cells {
+		counts[mcshapes.BlockName(c.block)]++
+	}
+	entries := make([]legendEntry, 0, len(counts))
+	for block, n := range counts {
+		entries = append(entries, legendEntry{block: block, count: n})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].count != entries[j].count {
+			return entries[i].count > entries[j].count
+		}
+		return entries[i].block < entries[j].block
+	})
+	return entries
+}
@@ -0,0 +1,70 @@
+package mcview
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+)
+
+// Test the views of a wall with a window in front of the player
+func TestBlueprint(t *testing.T) {
+	cmds := "fill ~0 ~0 ~-2 ~4 ~2 ~-2 minecraft:stone\n" +
+		"setblock ~2 ~1 ~-2 minecraft:glass\n" +
+		"fill ~0 ~0 ~-3 ~4 ~0 ~-3 minecraft:oak_planks\n"
+	m, err := mcshapes.ReadModel(strings.NewReader(cmds))
+	if err != nil {
+		t.Fatalf("ReadModel: %v", err)
+	}
+	b := NewBlueprint(m, WithBlueprintCellSize(10))
+
+	// The number of blocks drawn and the size of the blocks along the edges of each view
+	tests := []struct {
+		view   string
+		blocks int
+		dims   []string
+	}{
+		{"plan", 10, []string{">5 blocks<", ">2 blocks<"}},
+		{"south", 15, []string{">5 blocks<", ">3 blocks<"}},
+		{"east", 4, []string{">2 blocks<", ">3 blocks<"}},
+		{"z=-3", 5, []string{">5 blocks<", ">1 block<"}},
+		{"y=1", 5, []string{">5 blocks<", ">1 block<"}},
+		{"x=~2", 4, []string{">2 blocks<", ">3 blocks<"}},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := b.WriteSVG(&buf, "wall", test.view); err != nil {
+			t.Fatalf("%v: %v", test.view, err)
+		}
+		svg := buf.String()
+		if err := xml.Unmarshal(buf.Bytes(), new(interface{})); err != nil {
+			t.Errorf("%v: not XML: %v", test.view, err)
+		}
+		if n := strings.Count(svg, "<title>") - 1; n != test.blocks {
+			t.Errorf("%v: expected %d blocks, got %d", test.view, test.blocks, n)
+		}
+		if !strings.Contains(svg, "<title>player ~0 ~0 ~0</title>") {
+			t.Errorf("%v: expected the player", test.view)
+		}
+		for _, dim := range test.dims {
+			if !strings.Contains(svg, dim) {
+				t.Errorf("%v: expected dimension %v", test.view, dim)
+			}
+		}
+	}
+
+	// The window is seen from the south but not in the plan
+	var buf bytes.Buffer
+	b.WriteSVG(&buf, "wall", "south")
+	if !strings.Contains(buf.String(), "<title>glass ~2 ~1 ~-2</title>") {
+		t.Errorf("south: expected the glass window")
+	}
+
+	for _, view := range []string{"up", "w=3", "x=a"} {
+		if err := b.WriteSVG(&buf, "wall", view); err == nil {
+			t.Errorf("expected an error for view %q", view)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package mcview
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+	"unicode"
+
+	mcshapes "github.com/GreenSeaTurtle/mcFunctionDev/mcShapes"
+)
+
+// Text layers
+//
+// A plan can also be drawn as text for a terminal, one layer at a time. Each block type is a
+// letter from its name, e.g. S for stone and g for glass, the same letter in every layer.
+// Empty places are a dot and the player, ~0 ~0 ~0, is @ when nothing is there. With ANSI
+// colors each block is also on a background of its color. A terminal character is about twice
+// as high as it is wide, so each block is two characters wide.
+
+// Glyphs returns the letter of each block type in the model, the most used blocks first
+// get the first letter of their names.
+func (p *Plan) Glyphs() map[string]rune {
+	glyphs := make(map[string]rune)
+	used := map[rune]bool{'.': true, '@': true}
+	for _, e := range p.legend(p.Ys()) {
+		var candidates []rune
+		for _, word := range strings.Split(strings.TrimPrefix(e.block, "minecraft:"), "_") {
+			if word != "" {
+				candidates = append(candidates, unicode.ToUpper(rune(word[0])))
+			}
+		}
+		for _, r := range strings.TrimPrefix(e.block, "minecraft:") {
+			if unicode.IsLetter(r) {
+				candidates = append(candidates, unicode.ToLower(r), unicode.ToUpper(r))
+			}
+		}
+		candidates = append(candidates, []rune("0123456789#%&*+=?$")...)
+		glyphs[e.block] = '?'
+		for _, r := range candidates {
+			if !used[r] {
+				glyphs[e.block], used[r] = r, true
+				break
+			}
+		}
+	}
+	return glyphs
+}
+
+// WriteText writes the layer at relative Y y as text with a legend, with ANSI colors if
+// ansi is set.
+func (p *Plan) WriteText(w io.Writer, y int, ansi bool) error {
+	glyphs := p.Glyphs()
+	bw := bufio.NewWriter(w)
+
+	n := 0
+	legend := p.legend([]int{y})
+	for _, e := range legend {
+		n += e.count
+	}
+	fmt.Fprintf(bw, "Y ~%d  %d blocks\n", y, n)
+
+	// X labels every 10 blocks, 2 characters a block, after the Z labels
+	zw := 0
+	for z := p.lo.Z; z <= p.hi.Z; z++ {
+		zw = imax(zw, len(fmt.Sprintf("~%d", z)))
+	}
+	header := []rune(strings.Repeat(" ", zw+1+2*(p.hi.X-p.lo.X+1)+4))
+	for x := p.lo.X; x <= p.hi.X; x++ {
+		if x%10 == 0 {
+			copy(header[zw+1+2*(x-p.lo.X):], []rune(fmt.Sprintf("~%d", x)))
+		}
+	}
+	fmt.Fprintln(bw, strings.TrimRight(string(header), " "))
+
+	for z := p.lo.Z; z <= p.hi.Z; z++ {
+		label := ""
+		if z%5 == 0 {
+			label = fmt.Sprintf("~%d", z)
+		}
+		fmt.Fprintf(bw, "%*s ", zw, label)
+		for x := p.lo.X; x <= p.hi.X; x++ {
+			b := p.model.Block(mcshapes.XYZ{X: x, Y: y, Z: z})
+			if b == "" || mcshapes.IsAir(b) {
+				if x == 0 && z == 0 && y == 0 {
+					fmt.Fprint(bw, "@ ")
+				} else {
+					fmt.Fprint(bw, ". ")
+				}
+				continue
+			}
+			name := mcshapes.BlockName(b)
+			if ansi {
+				fmt.Fprint(bw, ansiBlock(p.palette.Color(name), glyphs[name]))
+			} else {
+				fmt.Fprintf(bw, "%c ", glyphs[name])
+			}
+		}
+		fmt.Fprintln(bw)
+	}
+
+	for _, e := range legend {
+		g := fmt.Sprintf("%c ", glyphs[e.block])
+		if ansi {
+			g = ansiBlock(p.palette.Color(e.block), glyphs[e.block])
+		}
+		fmt.Fprintf(bw, "  %v %v\n", g, legendText(e))
+	}
+	return bw.Flush()
+}
+
+// ansiBlock is a glyph on a 24 bit ANSI background color, in black or white to be seen
+func ansiBlock(c color.RGBA, glyph rune) string {
+	fg := "97"
+	if 299*int(c.R)+587*int(c.G)+114*int(c.B) > 128000 {
+		fg = "30"
+	}
+	return fmt.Sprintf("\x1b[48;2;%d;%d;%dm\x1b[%vm%c \x1b[0m", c.R, c.G, c.B, fg, glyph)
+}